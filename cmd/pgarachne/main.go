@@ -6,8 +6,11 @@ import (
 	"log/slog"
 	"os"
 
+	"github.com/yourusername/pgarachne/internal/cli"
 	"github.com/yourusername/pgarachne/internal/config"
 	"github.com/yourusername/pgarachne/internal/daemon"
+	"github.com/yourusername/pgarachne/internal/logging"
+	"github.com/yourusername/pgarachne/internal/selftest"
 	"github.com/yourusername/pgarachne/internal/server"
 )
 
@@ -18,12 +21,22 @@ func main() {
 	configPath := flag.String("config", "", "Path to configuration file. If empty, searches standard locations.")
 	showVersion := flag.Bool("version", false, "Show version and exit")
 	showHelp := flag.Bool("help", false, "Show help message and exit")
-	startDaemon := flag.Bool("start", false, "Start the server in the background")
-	stopDaemon := flag.Bool("stop", false, "Stop the background server")
+	startDaemon := flag.Bool("start", false, "Start the server in the background (Windows: start the service)")
+	stopDaemon := flag.Bool("stop", false, "Stop the background server (Windows: stop the service)")
+	installService := flag.Bool("install", false, "Install as a Windows service")
+	uninstallService := flag.Bool("uninstall", false, "Uninstall the Windows service")
 
 	flag.Parse()
 
 	// Handle Daemon commands first
+	if *installService {
+		daemon.Install()
+	}
+
+	if *uninstallService {
+		daemon.Uninstall()
+	}
+
 	if *stopDaemon {
 		daemon.Stop()
 	}
@@ -54,43 +67,93 @@ func main() {
 	}
 
 	// Re-configure logging based on config
-	var logLevel slog.Level
-	switch cfg.LogLevel {
-	case "DEBUG":
-		logLevel = slog.LevelDebug
-	case "WARN":
-		logLevel = slog.LevelWarn
-	case "ERROR":
-		logLevel = slog.LevelError
-	default:
-		logLevel = slog.LevelInfo
+	if err := logging.Setup(cfg); err != nil {
+		slog.Error("Failed to configure logging", "error", err)
+		os.Exit(1)
 	}
 
-	var logHandler slog.Handler
-	handlerOptions := &slog.HandlerOptions{
-		Level: logLevel,
-	}
+	slog.Info("Configuration loaded successfully", "config_file", *configPath)
 
-	if cfg.LogOutput != "stdout" {
-		file, err := os.OpenFile(cfg.LogOutput, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-		if err != nil {
-			slog.Error("Failed to open log file", "file", cfg.LogOutput, "error", err)
-			os.Exit(1)
+	// Subcommands operate on the loaded configuration instead of running
+	// the server (e.g. `pgarachne selftest mydb [login] [password]`).
+	if args := flag.Args(); len(args) > 0 {
+		switch args[0] {
+		case "call":
+			if err := cli.Call(cfg, args[1:]); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			os.Exit(0)
+		case "gen":
+			if err := cli.Gen(cfg, args[1:]); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			os.Exit(0)
+		case "migrate":
+			if err := cli.Migrate(cfg, args[1:]); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			os.Exit(0)
+		case "check-config":
+			if err := cli.CheckConfig(cfg, args[1:]); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			os.Exit(0)
+		case "init-db":
+			if err := cli.InitDB(cfg, args[1:]); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			os.Exit(0)
+		case "bench":
+			if err := cli.Bench(cfg, args[1:]); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			os.Exit(0)
+		case "token":
+			if err := cli.Token(cfg, args[1:]); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			os.Exit(0)
+		case "selftest":
+			if len(args) < 2 {
+				fmt.Println("Usage: pgarachne selftest <database> [login] [password]")
+				os.Exit(2)
+			}
+			login, password := "", ""
+			if len(args) >= 4 {
+				login, password = args[2], args[3]
+			}
+			if err := selftest.Run(cfg, args[1], login, password); err != nil {
+				slog.Error("Self-test failed", "error", err)
+				os.Exit(1)
+			}
+			os.Exit(0)
+		default:
+			fmt.Printf("Unknown command: %s\n", args[0])
+			os.Exit(2)
 		}
-		// Note: file is valid here, but we don't strictly close it as main exits immediately after,
-		// or server runs until interrupt. In a long running service, this is usually acceptable for the main logger.
-		logHandler = slog.NewJSONHandler(file, handlerOptions)
-	} else {
-		logHandler = slog.NewJSONHandler(os.Stdout, handlerOptions)
 	}
 
-	logger = slog.New(logHandler)
-	slog.SetDefault(logger)
+	// Initialize and run server
+	server.Version = Version
+	srv, err := server.New(cfg)
+	if err != nil {
+		slog.Error("Failed to initialize server", "error", err)
+		os.Exit(1)
+	}
 
-	slog.Info("Configuration loaded successfully", "config_file", *configPath)
+	// When launched by the Windows service manager, the run loop goes
+	// through the SCM control handler instead.
+	if daemon.MaybeRunService(srv.Run) {
+		return
+	}
 
-	// Initialize and run server
-	srv := server.New(cfg)
 	if err := srv.Run(); err != nil {
 		slog.Error("Server failed", "error", err)
 		// Clean up PID file if we are the daemon process is implicit,
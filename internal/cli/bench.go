@@ -0,0 +1,169 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/yourusername/pgarachne/internal/config"
+)
+
+// Bench implements `pgarachne bench <database> <function> [flags]`: a
+// built-in load generator that drives a running instance with concurrent
+// callers for a fixed duration and reports latency percentiles, throughput
+// and an error breakdown — enough to size pools and validate function
+// performance without external tooling.
+func Bench(cfg *config.Config, args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("usage: pgarachne bench <database> <function> [--params '{...}'] [--concurrency 50] [--duration 30s] [--token ...] [--login ... --password ...] [--url ...]")
+	}
+	database, function := args[0], args[1]
+
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	params := fs.String("params", "{}", "Function parameters as a JSON object")
+	concurrency := fs.Int("concurrency", 10, "Number of concurrent callers")
+	duration := fs.Duration("duration", 10*time.Second, "How long to keep calling")
+	token := fs.String("token", os.Getenv("PGARACHNE_TOKEN"), "Bearer token (JWT or API token); defaults to $PGARACHNE_TOKEN")
+	login := fs.String("login", "", "Login name; with --password, obtains a JWT before the run")
+	password := fs.String("password", "", "Password for --login")
+	baseURL := fs.String("url", "", "Base URL of the server (default http://127.0.0.1:<HTTP_PORT>)")
+	if err := fs.Parse(args[2:]); err != nil {
+		return err
+	}
+	if *concurrency < 1 {
+		return fmt.Errorf("--concurrency must be at least 1")
+	}
+
+	var paramsJSON json.RawMessage
+	if err := json.Unmarshal([]byte(*params), &paramsJSON); err != nil {
+		return fmt.Errorf("--params is not valid JSON: %w", err)
+	}
+
+	url := *baseURL
+	if url == "" {
+		url = "http://127.0.0.1:" + cfg.HTTPPort
+	}
+	client := &http.Client{Timeout: 60 * time.Second}
+
+	bearer := *token
+	if bearer == "" && *login != "" {
+		var err error
+		if bearer, err = obtainJWT(client, url, database, *login, *password); err != nil {
+			return err
+		}
+	}
+
+	envelope, _ := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"params":  paramsJSON,
+	})
+	endpoint := url + "/api/" + database + "/" + function
+
+	// Each worker keeps its own tallies; merging afterwards avoids any
+	// contention that would distort the numbers being measured.
+	type workerStats struct {
+		latencies []time.Duration
+		errors    map[string]int
+	}
+	stats := make([]workerStats, *concurrency)
+	deadline := time.Now().Add(*duration)
+
+	fmt.Printf("Benchmarking %s with %d callers for %s...\n", endpoint, *concurrency, *duration)
+
+	var wg sync.WaitGroup
+	for i := 0; i < *concurrency; i++ {
+		wg.Add(1)
+		go func(w *workerStats) {
+			defer wg.Done()
+			w.errors = make(map[string]int)
+			for time.Now().Before(deadline) {
+				started := time.Now()
+				outcome := benchOnce(client, endpoint, bearer, envelope)
+				elapsed := time.Since(started)
+				if outcome == "" {
+					w.latencies = append(w.latencies, elapsed)
+				} else {
+					w.errors[outcome]++
+				}
+			}
+		}(&stats[i])
+	}
+	wg.Wait()
+
+	var latencies []time.Duration
+	errors := make(map[string]int)
+	for _, w := range stats {
+		latencies = append(latencies, w.latencies...)
+		for outcome, count := range w.errors {
+			errors[outcome] += count
+		}
+	}
+	failed := 0
+	for _, count := range errors {
+		failed += count
+	}
+	total := len(latencies) + failed
+
+	fmt.Printf("\nRequests:   %d (%d ok, %d failed)\n", total, len(latencies), failed)
+	fmt.Printf("Throughput: %.1f req/s\n", float64(total)/duration.Seconds())
+
+	if len(latencies) > 0 {
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		percentile := func(p float64) time.Duration {
+			index := int(p * float64(len(latencies)-1))
+			return latencies[index]
+		}
+		fmt.Printf("Latency:    p50=%s p90=%s p95=%s p99=%s max=%s\n",
+			percentile(0.50).Round(time.Microsecond),
+			percentile(0.90).Round(time.Microsecond),
+			percentile(0.95).Round(time.Microsecond),
+			percentile(0.99).Round(time.Microsecond),
+			latencies[len(latencies)-1].Round(time.Microsecond))
+	}
+	if len(errors) > 0 {
+		fmt.Println("Errors:")
+		outcomes := make([]string, 0, len(errors))
+		for outcome := range errors {
+			outcomes = append(outcomes, outcome)
+		}
+		sort.Strings(outcomes)
+		for _, outcome := range outcomes {
+			fmt.Printf("  %6d  %s\n", errors[outcome], outcome)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d requests failed", failed, total)
+	}
+	return nil
+}
+
+// benchOnce performs one call and classifies the outcome: "" for success,
+// otherwise a short error label the breakdown groups by.
+func benchOnce(client *http.Client, endpoint, bearer string, envelope []byte) string {
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(envelope))
+	if err != nil {
+		return err.Error()
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "transport: " + err.Error()
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return "HTTP " + resp.Status
+	}
+	return ""
+}
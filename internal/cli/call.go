@@ -0,0 +1,116 @@
+// Package cli implements the pgarachne operator subcommands that run
+// against a configured or already-running instance (call, token management
+// and the like) instead of starting the server.
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/yourusername/pgarachne/internal/config"
+)
+
+// Call implements `pgarachne call <database> <function> [flags]`: it wraps
+// the parameters in a JSON-RPC envelope, posts them to a running instance
+// and pretty-prints the response — a smoke-test tool that replaces
+// hand-crafted curl invocations.
+func Call(cfg *config.Config, args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("usage: pgarachne call <database> <function> [--params '{...}'] [--token ...] [--login ... --password ...] [--url ...]")
+	}
+	database, function := args[0], args[1]
+
+	fs := flag.NewFlagSet("call", flag.ContinueOnError)
+	params := fs.String("params", "{}", "Function parameters as a JSON object")
+	token := fs.String("token", os.Getenv("PGARACHNE_TOKEN"), "Bearer token (JWT or API token); defaults to $PGARACHNE_TOKEN")
+	login := fs.String("login", "", "Login name; with --password, obtains a JWT before the call")
+	password := fs.String("password", "", "Password for --login")
+	baseURL := fs.String("url", "", "Base URL of the server (default http://127.0.0.1:<HTTP_PORT>)")
+	if err := fs.Parse(args[2:]); err != nil {
+		return err
+	}
+
+	var paramsJSON json.RawMessage
+	if err := json.Unmarshal([]byte(*params), &paramsJSON); err != nil {
+		return fmt.Errorf("--params is not valid JSON: %w", err)
+	}
+
+	url := *baseURL
+	if url == "" {
+		url = "http://127.0.0.1:" + cfg.HTTPPort
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+
+	bearer := *token
+	if bearer == "" && *login != "" {
+		var err error
+		if bearer, err = obtainJWT(client, url, database, *login, *password); err != nil {
+			return err
+		}
+	}
+
+	envelope, _ := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  function,
+		"params":  paramsJSON,
+		"id":      1,
+	})
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/api/%s/%s", url, database, function), bytes.NewReader(envelope))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	var pretty bytes.Buffer
+	if err := json.Indent(&pretty, body, "", "  "); err != nil {
+		// Not JSON (e.g. a proxy error page); print it as-is.
+		fmt.Println(string(body))
+	} else {
+		fmt.Println(pretty.String())
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("server returned %s", resp.Status)
+	}
+	return nil
+}
+
+// obtainJWT logs in through the public endpoint and returns the issued token.
+func obtainJWT(client *http.Client, baseURL, database, login, password string) (string, error) {
+	payload, _ := json.Marshal(map[string]string{"login": login, "password": password})
+	resp, err := client.Post(fmt.Sprintf("%s/api/%s/login", baseURL, database), "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var result struct {
+		Token string `json:"token"`
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("login response: %w", err)
+	}
+	if result.Token == "" {
+		return "", fmt.Errorf("login failed: %s", result.Error)
+	}
+	return result.Token, nil
+}
@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/yourusername/pgarachne/internal/config"
+	"github.com/yourusername/pgarachne/internal/database"
+	sqlassets "github.com/yourusername/pgarachne/sql"
+)
+
+// CheckConfig implements `pgarachne check-config [database...]`: the
+// configuration has already been loaded and validated by the normal startup
+// path, so this verifies the parts that only fail at runtime — database
+// reachability and the installed pgarachne schema version — and prints the
+// effective configuration with secrets masked.
+func CheckConfig(cfg *config.Config, args []string) error {
+	fmt.Println("Effective configuration:")
+	dump, err := json.MarshalIndent(effectiveConfig(cfg), "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(dump))
+
+	failed := false
+	report := func(name string, err error) {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "FAIL %s: %v\n", name, err)
+			failed = true
+			return
+		}
+		fmt.Printf("ok   %s\n", name)
+	}
+
+	fmt.Println("\nChecks:")
+	report("primary reachable", database.CheckPrimary(cfg))
+
+	// The databases named on the command line (falling back to DB_CRITICAL)
+	// additionally get a connection and schema version check.
+	databases := args
+	if len(databases) == 0 {
+		databases = cfg.DBCritical
+	}
+	for _, dbName := range databases {
+		db, err := database.GetConnection(cfg, dbName)
+		if err != nil {
+			report("database "+dbName, err)
+			continue
+		}
+		report("database "+dbName, nil)
+
+		version, err := installedSchemaVersion(db)
+		switch {
+		case err != nil:
+			report("schema "+dbName, err)
+		case version == 0:
+			report("schema "+dbName, fmt.Errorf("pgarachne schema not installed (run: pgarachne init-db %s)", dbName))
+		case version < sqlassets.SchemaVersion:
+			report("schema "+dbName, fmt.Errorf("schema version %d older than bundled %d (run: pgarachne init-db %s --force)", version, sqlassets.SchemaVersion, dbName))
+		default:
+			report(fmt.Sprintf("schema %s (version %d)", dbName, version), nil)
+		}
+	}
+
+	if failed {
+		return fmt.Errorf("configuration check failed")
+	}
+	fmt.Println("\nConfiguration OK.")
+	return nil
+}
+
+// maskSecret hides a secret's value while showing whether it is set.
+func maskSecret(value string) string {
+	if value == "" {
+		return ""
+	}
+	return "(set, masked)"
+}
+
+// effectiveConfig lays the loaded configuration out for display, masking
+// secrets.
+func effectiveConfig(cfg *config.Config) map[string]interface{} {
+	return map[string]interface{}{
+		"config_file":        cfg.SourcePath,
+		"db_host":            cfg.DBHost,
+		"db_hosts":           cfg.DBHosts,
+		"db_replica_hosts":   cfg.DBReplicaHosts,
+		"db_port":            cfg.DBPort,
+		"db_user":            cfg.DBUser,
+		"db_critical":        cfg.DBCritical,
+		"db_optional":        cfg.DBOptional,
+		"http_port":          cfg.HTTPPort,
+		"http_socket":        cfg.HTTPSocket,
+		"http_h2c":           cfg.HTTPH2C,
+		"jwt_secret":         maskSecret(cfg.JWTSecret),
+		"jwt_expiry_hours":   cfg.JWTExpiryHours,
+		"admin_token":        maskSecret(cfg.AdminToken),
+		"allowed_origins":    cfg.AllowedOrigins,
+		"static_files_path":  cfg.StaticFilesPath,
+		"base_url":           cfg.BaseURL,
+		"tls_cert_file":      cfg.TLSCertFile,
+		"tls_client_ca_file": cfg.TLSClientCAFile,
+		"state_storage":      cfg.StateStorage,
+		"audit_log":          cfg.AuditLog,
+		"debug_endpoints":    cfg.DebugEndpoints,
+		"ui_enabled":         cfg.UIEnabled,
+		"log_level":          cfg.LogLevel,
+		"log_output":         cfg.LogOutput,
+	}
+}
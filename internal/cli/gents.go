@@ -0,0 +1,181 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/yourusername/pgarachne/internal/config"
+	"github.com/yourusername/pgarachne/internal/database"
+)
+
+// Gen implements `pgarachne gen ts --database mydb --out client.ts`: it
+// reads pgarachne.capabilities() straight from the database and emits a
+// typed TypeScript client — one async method per exposed SQL function with
+// parameters typed from the --- PARAMS --- schemas, plus a login helper.
+func Gen(cfg *config.Config, args []string) error {
+	if len(args) < 1 || args[0] != "ts" {
+		return fmt.Errorf("usage: pgarachne gen ts --database <name> [--out client.ts]")
+	}
+
+	fs := flag.NewFlagSet("gen ts", flag.ContinueOnError)
+	dbName := fs.String("database", "", "Database whose capabilities to generate a client for")
+	out := fs.String("out", "-", "Output file ('-' for stdout)")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *dbName == "" {
+		return fmt.Errorf("--database is required")
+	}
+
+	db, err := database.GetConnection(cfg, *dbName)
+	if err != nil {
+		return fmt.Errorf("connect to %s: %w", *dbName, err)
+	}
+	var capabilitiesJSON []byte
+	if err := db.QueryRow(`SELECT pgarachne.capabilities('{}'::jsonb)`).Scan(&capabilitiesJSON); err != nil {
+		return fmt.Errorf("read capabilities: %w", err)
+	}
+
+	var methods []capabilityMethod
+	if err := json.Unmarshal(capabilitiesJSON, &methods); err != nil {
+		return fmt.Errorf("parse capabilities: %w", err)
+	}
+
+	source := generateTypeScript(*dbName, methods)
+	if *out == "-" {
+		fmt.Print(source)
+		return nil
+	}
+	if err := os.WriteFile(*out, []byte(source), 0o644); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %s (%d methods).\n", *out, len(methods))
+	return nil
+}
+
+type capabilityMethod struct {
+	Method      string `json:"method"`
+	Description string `json:"description"`
+	Parameters  struct {
+		Properties map[string]struct {
+			Type        string `json:"type"`
+			Description string `json:"description"`
+		} `json:"properties"`
+		Required []string `json:"required"`
+	} `json:"parameters"`
+}
+
+// tsType maps JSON-schema types onto TypeScript.
+func tsType(schemaType string) string {
+	switch schemaType {
+	case "string":
+		return "string"
+	case "number", "integer":
+		return "number"
+	case "boolean":
+		return "boolean"
+	case "array":
+		return "unknown[]"
+	case "object":
+		return "Record<string, unknown>"
+	default:
+		return "unknown"
+	}
+}
+
+// tsIdentifier turns a qualified SQL function name into a method name
+// (api.get_users -> api_get_users).
+func tsIdentifier(method string) string {
+	return strings.NewReplacer(".", "_", "-", "_").Replace(method)
+}
+
+func generateTypeScript(dbName string, methods []capabilityMethod) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "// Generated by pgarachne gen ts for database %q — do not edit.\n\n", dbName)
+	b.WriteString(`export interface JSONRPCError {
+  code?: number;
+  message: string;
+  data?: unknown;
+}
+
+export class PgArachneError extends Error {
+  constructor(public rpcError: JSONRPCError) {
+    super(rpcError.message);
+  }
+}
+
+export class PgArachneClient {
+  constructor(private baseUrl: string, private token?: string) {}
+
+  /** Logs in and stores the issued JWT for subsequent calls. */
+  async login(login: string, password: string): Promise<void> {
+    const res = await fetch(` + "`${this.baseUrl}/api/" + dbName + "/login`" + `, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ login, password }),
+    });
+    const body = await res.json();
+    if (!body.token) throw new PgArachneError({ message: body.error ?? 'login failed' });
+    this.token = body.token;
+  }
+
+  private async rpc<T>(method: string, params: unknown): Promise<T> {
+    const res = await fetch(` + "`${this.baseUrl}/api/" + dbName + "/${method}`" + `, {
+      method: 'POST',
+      headers: {
+        'Content-Type': 'application/json',
+        ...(this.token ? { Authorization: ` + "`Bearer ${this.token}`" + ` } : {}),
+      },
+      body: JSON.stringify({ jsonrpc: '2.0', method, params, id: Date.now() }),
+    });
+    const body = await res.json();
+    if (body.error) throw new PgArachneError(body.error);
+    return body.result as T;
+  }
+`)
+
+	sort.Slice(methods, func(i, j int) bool { return methods[i].Method < methods[j].Method })
+	for _, method := range methods {
+		required := make(map[string]bool, len(method.Parameters.Required))
+		for _, name := range method.Parameters.Required {
+			required[name] = true
+		}
+		names := make([]string, 0, len(method.Parameters.Properties))
+		for name := range method.Parameters.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		var fields []string
+		for _, name := range names {
+			prop := method.Parameters.Properties[name]
+			optional := "?"
+			if required[name] {
+				optional = ""
+			}
+			fields = append(fields, fmt.Sprintf("%s%s: %s", name, optional, tsType(prop.Type)))
+		}
+		paramsType := "Record<string, unknown>"
+		if len(fields) > 0 {
+			paramsType = "{ " + strings.Join(fields, "; ") + " }"
+		}
+		defaultValue := " = {}"
+		if len(method.Parameters.Required) > 0 {
+			defaultValue = ""
+		}
+
+		b.WriteString("\n")
+		if method.Description != "" {
+			fmt.Fprintf(&b, "  /** %s */\n", strings.ReplaceAll(method.Description, "*/", "* /"))
+		}
+		fmt.Fprintf(&b, "  %s(params: %s%s): Promise<unknown> {\n", tsIdentifier(method.Method), paramsType, defaultValue)
+		fmt.Fprintf(&b, "    return this.rpc(%q, params);\n", method.Method)
+		b.WriteString("  }\n")
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
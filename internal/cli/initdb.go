@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+
+	"github.com/yourusername/pgarachne/internal/config"
+	"github.com/yourusername/pgarachne/internal/database"
+	sqlassets "github.com/yourusername/pgarachne/sql"
+)
+
+// InitDB implements `pgarachne init-db <database>`: it installs the bundled
+// pgarachne schema (token tables, verify_api_token, capabilities, ...) into
+// the target database. The DDL is idempotent (IF NOT EXISTS / OR REPLACE)
+// and versioned through pgarachne.schema_info, so rerunning is safe and a
+// current installation is left untouched unless --force is given.
+func InitDB(cfg *config.Config, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: pgarachne init-db <database> [--force]")
+	}
+	dbName := args[0]
+
+	fs := flag.NewFlagSet("init-db", flag.ContinueOnError)
+	force := fs.Bool("force", false, "Reapply the schema even when the installed version is current")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	db, err := database.GetConnection(cfg, dbName)
+	if err != nil {
+		return fmt.Errorf("connect to %s: %w", dbName, err)
+	}
+
+	if installed, err := installedSchemaVersion(db); err != nil {
+		return err
+	} else if installed >= sqlassets.SchemaVersion && !*force {
+		fmt.Printf("Schema version %d already installed in %s, nothing to do.\n", installed, dbName)
+		return nil
+	}
+
+	// The bundled DDL is a single idempotent script; lib/pq executes it as
+	// one simple-protocol batch.
+	if _, err := db.Exec(sqlassets.Schema); err != nil {
+		return fmt.Errorf("apply schema: %w", err)
+	}
+
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS pgarachne.schema_info (
+			version INT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`); err != nil {
+		return fmt.Errorf("record schema version: %w", err)
+	}
+	if _, err := db.Exec(`INSERT INTO pgarachne.schema_info (version) VALUES ($1)`, sqlassets.SchemaVersion); err != nil {
+		return fmt.Errorf("record schema version: %w", err)
+	}
+
+	fmt.Printf("Installed pgarachne schema version %d into %s.\n", sqlassets.SchemaVersion, dbName)
+	fmt.Println("Grant EXECUTE on the pgarachne functions to your API roles to finish setup.")
+	return nil
+}
+
+// installedSchemaVersion returns the highest recorded schema version, or 0
+// when pgarachne has never been installed.
+func installedSchemaVersion(db *sql.DB) (int, error) {
+	var exists bool
+	if err := db.QueryRow(
+		`SELECT EXISTS (SELECT 1 FROM information_schema.tables WHERE table_schema = 'pgarachne' AND table_name = 'schema_info')`,
+	).Scan(&exists); err != nil {
+		return 0, fmt.Errorf("check schema_info: %w", err)
+	}
+	if !exists {
+		return 0, nil
+	}
+	var version int
+	if err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM pgarachne.schema_info`).Scan(&version); err != nil {
+		return 0, fmt.Errorf("read schema version: %w", err)
+	}
+	return version, nil
+}
@@ -0,0 +1,213 @@
+package cli
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/yourusername/pgarachne/internal/config"
+	"github.com/yourusername/pgarachne/internal/database"
+)
+
+// Migrate implements `pgarachne migrate <up|down|status> <database>`: a
+// migration runner for the application schemas whose functions the server
+// exposes. Migrations live in MIGRATIONS_PATH/<database>/ as
+// NNNN_name.up.sql / NNNN_name.down.sql pairs and are tracked per database
+// in pgarachne.schema_migrations. Each migration runs in its own
+// transaction.
+func Migrate(cfg *config.Config, args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("usage: pgarachne migrate <up|down|status> <database> [--path dir] [--steps n]")
+	}
+	action, dbName := args[0], args[1]
+
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	path := fs.String("path", cfg.MigrationsPath, "Migrations directory (contains one subdirectory per database)")
+	steps := fs.Int("steps", 1, "Number of migrations to roll back with 'down'")
+	if err := fs.Parse(args[2:]); err != nil {
+		return err
+	}
+	if *path == "" {
+		return fmt.Errorf("no migrations directory: set MIGRATIONS_PATH or pass --path")
+	}
+
+	migrations, err := loadMigrations(filepath.Join(*path, dbName))
+	if err != nil {
+		return err
+	}
+
+	db, err := database.GetConnection(cfg, dbName)
+	if err != nil {
+		return fmt.Errorf("connect to %s: %w", dbName, err)
+	}
+	if _, err := db.Exec(`
+		CREATE SCHEMA IF NOT EXISTS pgarachne;
+		CREATE TABLE IF NOT EXISTS pgarachne.schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)`); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return err
+	}
+
+	switch action {
+	case "up":
+		return migrateUp(db, migrations, applied)
+	case "down":
+		return migrateDown(db, migrations, applied, *steps)
+	case "status":
+		for _, m := range migrations {
+			state := "pending"
+			if applied[m.version] {
+				state = "applied"
+			}
+			fmt.Printf("%-8s %04d %s\n", state, m.version, m.name)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate action '%s' (expected up, down or status)", action)
+	}
+}
+
+type migration struct {
+	version  int64
+	name     string
+	upFile   string
+	downFile string // optional
+}
+
+// loadMigrations reads NNNN_name.up.sql files from dir, ordered by version.
+func loadMigrations(dir string) ([]migration, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("migrations directory: %w", err)
+	}
+	var migrations []migration
+	for _, entry := range entries {
+		fileName := entry.Name()
+		if !strings.HasSuffix(fileName, ".up.sql") {
+			continue
+		}
+		base := strings.TrimSuffix(fileName, ".up.sql")
+		versionStr, name, found := strings.Cut(base, "_")
+		if !found {
+			return nil, fmt.Errorf("migration file %s: expected NNNN_name.up.sql", fileName)
+		}
+		version, err := strconv.ParseInt(versionStr, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration file %s: version prefix is not a number", fileName)
+		}
+		m := migration{version: version, name: name, upFile: filepath.Join(dir, fileName)}
+		if downFile := filepath.Join(dir, base+".down.sql"); fileExists(downFile) {
+			m.downFile = downFile
+		}
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].version < migrations[j].version })
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].version == migrations[i-1].version {
+			return nil, fmt.Errorf("duplicate migration version %d", migrations[i].version)
+		}
+	}
+	return migrations, nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func appliedMigrations(db *sql.DB) (map[int64]bool, error) {
+	rows, err := db.Query(`SELECT version FROM pgarachne.schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+	applied := make(map[int64]bool)
+	for rows.Next() {
+		var version int64
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		applied[version] = true
+	}
+	return applied, rows.Err()
+}
+
+func migrateUp(db *sql.DB, migrations []migration, applied map[int64]bool) error {
+	ran := 0
+	for _, m := range migrations {
+		if applied[m.version] {
+			continue
+		}
+		contents, err := os.ReadFile(m.upFile)
+		if err != nil {
+			return err
+		}
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(string(contents)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %04d_%s: %w", m.version, m.name, err)
+		}
+		if _, err := tx.Exec(`INSERT INTO pgarachne.schema_migrations (version, name) VALUES ($1, $2)`, m.version, m.name); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %04d_%s: %w", m.version, m.name, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("migration %04d_%s: %w", m.version, m.name, err)
+		}
+		fmt.Printf("applied %04d %s\n", m.version, m.name)
+		ran++
+	}
+	if ran == 0 {
+		fmt.Println("No pending migrations.")
+	}
+	return nil
+}
+
+func migrateDown(db *sql.DB, migrations []migration, applied map[int64]bool, steps int) error {
+	// Roll back the newest applied migrations first.
+	for i := len(migrations) - 1; i >= 0 && steps > 0; i-- {
+		m := migrations[i]
+		if !applied[m.version] {
+			continue
+		}
+		if m.downFile == "" {
+			return fmt.Errorf("migration %04d_%s has no .down.sql", m.version, m.name)
+		}
+		contents, err := os.ReadFile(m.downFile)
+		if err != nil {
+			return err
+		}
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(string(contents)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("rollback %04d_%s: %w", m.version, m.name, err)
+		}
+		if _, err := tx.Exec(`DELETE FROM pgarachne.schema_migrations WHERE version = $1`, m.version); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("rollback %04d_%s: %w", m.version, m.name, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("rollback %04d_%s: %w", m.version, m.name, err)
+		}
+		fmt.Printf("rolled back %04d %s\n", m.version, m.name)
+		steps--
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yourusername/pgarachne/internal/auth"
+	"github.com/yourusername/pgarachne/internal/config"
+	"github.com/yourusername/pgarachne/internal/database"
+)
+
+// Token implements `pgarachne token create <database> [flags]`: it
+// generates a random API token, prints the raw value once and stores only
+// the SHA-256 hash in pgarachne.api_tokens — the same hashing
+// auth.HashToken applies when verifying. With --print-sql the INSERT is
+// printed instead of executed, for environments where the operator has no
+// direct connection.
+func Token(cfg *config.Config, args []string) error {
+	if len(args) < 2 || args[0] != "create" {
+		return fmt.Errorf("usage: pgarachne token create <database> [--role ...] [--expires 90d] [--description ...] [--print-sql]")
+	}
+	dbName := args[1]
+
+	fs := flag.NewFlagSet("token create", flag.ContinueOnError)
+	role := fs.String("role", "", "Database role the token authenticates as (default: DB_USER)")
+	expires := fs.String("expires", "", "Validity period, e.g. 90d or 12h (default: no expiry)")
+	description := fs.String("description", "created by pgarachne token", "Description stored with the token")
+	printSQL := fs.Bool("print-sql", false, "Print the INSERT statement instead of executing it")
+	if err := fs.Parse(args[2:]); err != nil {
+		return err
+	}
+	if *role == "" {
+		*role = cfg.DBUser
+	}
+
+	var validTo *time.Time
+	if *expires != "" {
+		duration, err := parseExpiry(*expires)
+		if err != nil {
+			return err
+		}
+		t := time.Now().Add(duration)
+		validTo = &t
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return err
+	}
+	rawToken := hex.EncodeToString(raw)
+	tokenHash := auth.HashToken(rawToken)
+
+	if *printSQL {
+		validToSQL := "NULL"
+		if validTo != nil {
+			validToSQL = "'" + validTo.UTC().Format(time.RFC3339) + "'"
+		}
+		fmt.Printf("INSERT INTO pgarachne.api_tokens (role, token_hash, description, valid_to)\nVALUES ('%s', '%s', '%s', %s);\n",
+			strings.ReplaceAll(*role, "'", "''"), tokenHash, strings.ReplaceAll(*description, "'", "''"), validToSQL)
+		fmt.Printf("\nToken (shown once, store it now): %s\n", rawToken)
+		return nil
+	}
+
+	db, err := database.GetConnection(cfg, dbName)
+	if err != nil {
+		return fmt.Errorf("connect to %s: %w", dbName, err)
+	}
+	if _, err := db.Exec(
+		`INSERT INTO pgarachne.api_tokens (role, token_hash, description, valid_to) VALUES ($1, $2, $3, $4)`,
+		*role, tokenHash, *description, validTo,
+	); err != nil {
+		return fmt.Errorf("insert token: %w", err)
+	}
+
+	fmt.Printf("Token created for role %q", *role)
+	if validTo != nil {
+		fmt.Printf(", valid until %s", validTo.Format(time.RFC3339))
+	}
+	fmt.Printf(".\nToken (shown once, store it now): %s\n", rawToken)
+	return nil
+}
+
+// parseExpiry understands time.ParseDuration syntax plus a "d" suffix for
+// days, the natural unit for token lifetimes.
+func parseExpiry(value string) (time.Duration, error) {
+	if strings.HasSuffix(value, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(value, "d"))
+		if err != nil || days <= 0 {
+			return 0, fmt.Errorf("invalid --expires value: '%s'", value)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		return 0, fmt.Errorf("invalid --expires value: '%s'", value)
+	}
+	return duration, nil
+}
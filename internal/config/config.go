@@ -2,47 +2,385 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	DBHost          string
-	DBPort          int
-	DBUser          string
-	HTTPPort        string
-	JWTSecret       string
-	JWTExpiryHours  int
-	AllowedOrigins  []string
-	StaticFilesPath string
-	LogLevel        string
-	LogOutput       string
+	DBHost         string
+	DBHosts        []string
+	DBPort         int
+	DBUser         string
+	DBReplicaHosts []string
+
+	// TLS towards Postgres: DB_SSLMODE defaults to "disable" for local
+	// setups; managed providers typically need "require" or "verify-full"
+	// plus the certificate files.
+	DBSSLMode     string
+	DBSSLRootCert string
+	DBSSLCert     string
+	DBSSLKey      string
+
+	// Extra lib/pq connection parameters appended to every generated
+	// connection string (sslmode, connect_timeout, options, ...); the
+	// per-database map overrides the global value.
+	DBDSN      string
+	DBDSNPerDB map[string]string
+
+	// Databases that may be addressed via /api/:database. Empty means no
+	// restriction (any catalog the credentials can reach).
+	Databases []string
+
+	// Databases checked during startup: critical ones fail startup when
+	// unreachable, optional ones only log a warning.
+	DBCritical     []string
+	DBOptional     []string
+	HTTPPort       string
+	HTTPSocket     string
+	HTTPSocketMode os.FileMode
+	HTTPH2C        bool
+
+	HTTPReadHeaderTimeout time.Duration
+	HTTPWriteTimeout      time.Duration
+	HTTPIdleTimeout       time.Duration
+
+	// ShutdownTimeout bounds how long each subsystem — most notably the
+	// HTTP listener draining in-flight requests — may take to stop.
+	ShutdownTimeout    time.Duration
+	JWTSecret          string
+	JWTExpiryHours     int
+	LoginFunction      string
+	AnonRole           string
+	ImpersonationRoles []string
+	PasswordFunction   string
+	SignupFunction     string
+	SignupRole         string
+	SignupRateLimit    int
+	CaptchaProvider    string
+	CaptchaSecret      string
+	PowDifficulty      int
+
+	// CIDR access rules: global nets plus per-database overrides keyed by
+	// database name. An empty allowlist admits everyone not denied.
+	IPAllow        []*net.IPNet
+	IPDeny         []*net.IPNet
+	IPAllowDB      map[string][]*net.IPNet
+	IPDenyDB       map[string][]*net.IPNet
+	AllowedOrigins []string
+
+	// Browser hardening: SECURITY_HEADERS turns the response headers on,
+	// CSP sets the policy verbatim, HSTS_MAX_AGE scopes the HSTS header
+	// (TLS only) and HTTPS_REDIRECT_PORT runs the plain-HTTP bouncer.
+	SecurityHeaders   bool
+	CSP               string
+	HSTSMaxAge        time.Duration
+	HTTPSRedirectPort string
+
+	// TrustedProxies are the only peers whose X-Forwarded-For/X-Real-IP are
+	// believed (IPs or CIDRs). Empty means no proxy is trusted and the
+	// socket peer address is the client IP.
+	TrustedProxies []string
+
+	// Per-database CORS overrides for multi-tenant hosts; databases without
+	// an entry use the global policy.
+	CORSOriginsDB       map[string][]string
+	CORSCredentialsDB   map[string]bool
+	CORSExposeHeadersDB map[string][]string
+	StaticFilesPath     string
+	BaseURL             string
+	// BasePath mounts everything under a URL prefix ("/pgarachne"); empty
+	// serves from the root as before.
+	BasePath        string
+	StaticJSONFiles map[string]string
+	TLSCertFile     string
+	TLSKeyFile      string
+
+	TLSClientCAFile      string
+	TLSClientRoleMapFile string
+
+	StateStorage         string
+	StateStorageDatabase string
+	// IdempotencyTTL is how long a stored Idempotency-Key response can be
+	// replayed (IDEMPOTENCY_TTL seconds, default 24h).
+	IdempotencyTTL time.Duration
+
+	// StaticCacheMaxAge is the Cache-Control max-age for non-hashed static
+	// files (STATIC_CACHE_MAX_AGE seconds, default 1h).
+	StaticCacheMaxAge time.Duration
+	// Static fallback hardening: listings and dotfiles are refused and
+	// symlinks pointing outside the root are not followed unless the
+	// operator opts in; STATIC_EXTENSIONS restricts what is served at all.
+	StaticDirListing     bool
+	StaticServeDotfiles  bool
+	StaticFollowSymlinks bool
+	StaticExtensions     []string
+	// TemplatesPath holds the html/template files for the _html endpoints.
+	TemplatesPath string
+
+	// Object storage offload for binary payloads (STORAGE_BACKEND=s3).
+	StorageBackend   string
+	S3Endpoint       string
+	S3Bucket         string
+	S3Region         string
+	S3AccessKey      string
+	S3SecretKey      string
+	S3ForcePathStyle bool
+	S3PresignTTL     time.Duration
+
+	// Access log: "json" (structured slog entries, the default), "combined"
+	// (Apache combined lines) or "off"; excluded paths are never logged.
+	AccessLogFormat  string
+	AccessLogExclude []string
+
+	// Error tracking (SENTRY_DSN); SENTRY_RELEASE defaults to the binary's
+	// version and SENTRY_ENVIRONMENT tags events with the deployment.
+	SentryDSN         string
+	SentryRelease     string
+	SentryEnvironment string
+
+	AuditLog        string
+	AuditRedactKeys []string
+
+	// SecretsRefresh re-runs the configuration load this often so rotated
+	// remote secrets (SECRETS_PROVIDER) are picked up; zero disables it.
+	SecretsRefresh time.Duration
+
+	// Tenant routing: the database is resolved from an X-Tenant-style
+	// header or the host's subdomain instead of the URL path; the tenant
+	// maps to a database via the map (overrides) or the pattern.
+	TenantHeader     string
+	TenantHostSuffix string
+	TenantDBPattern  string
+	TenantDBMap      map[string]string
+
+	// API versioning: APIVersions maps URL version segments to schemas
+	// ("v1=api,v2=api_v2"), so /api/v2/db/fn runs with search_path=api_v2.
+	// APIVersionsDB overrides the mapping per database
+	// ("sales=v1:api|v2:sales_v2").
+	APIVersions   map[string]string
+	APIVersionsDB map[string]map[string]string
+
+	// DefaultSchema pins search_path for unversioned calls
+	// (DEFAULT_SCHEMA=api), so unqualified function names resolve in a
+	// designated schema instead of the connecting user's default;
+	// DefaultSchemaDB overrides it per database. Empty leaves search_path
+	// untouched.
+	DefaultSchema   string
+	DefaultSchemaDB map[string]string
+
+	// LeaderElection restricts webhook dispatch and schedulers to one
+	// elected instance per cluster (advisory locks); off by default so
+	// single-instance deployments behave as before.
+	LeaderElection bool
+
+	// RoleLimits enforces the per-role rate and concurrency limits stored
+	// in each database's pgarachne.limits table; roles without a row stay
+	// unrestricted.
+	RoleLimits bool
+
+	// UsageAccounting tallies calls, rows and execution time per role into
+	// pgarachne.usage, serves GET /api/{database}/_usage, and enforces the
+	// monthly_calls quotas from pgarachne.limits.
+	UsageAccounting bool
+
+	// DevMode relaxes the API for local development: pretty JSON, verbose
+	// SQL errors, body logging, any-origin CORS and no state read caching.
+	DevMode bool
+
+	// Mock mode serves function results from JSON fixture files instead of
+	// Postgres, optionally delayed to simulate call latency.
+	MockFixturesPath string
+	MockLatency      time.Duration
+
+	DebugEndpoints   bool
+	AdminToken       string
+	UIEnabled        bool
+	MigrationsPath   string
+	WebhookDatabases []string
+	// WebhookAllowPrivate lets webhook deliveries reach loopback, private
+	// and link-local addresses; off by default, since self-service URLs
+	// would otherwise be an SSRF vector into the internal network.
+	WebhookAllowPrivate bool
+	ScheduleDatabases   []string
+
+	// Concurrency limiting for function calls: at most MaxConcurrentCalls
+	// run per database (per-database overrides win), excess callers queue
+	// up to CallQueueTimeout before getting 503 + Retry-After. Zero means
+	// unlimited.
+	MaxConcurrentCalls   int
+	MaxConcurrentCallsDB map[string]int
+	CallQueueTimeout     time.Duration
+
+	// Per-client concurrency: at most MaxCallsPerClient requests in flight
+	// per token (or IP, for unauthenticated identities), with per-database
+	// overrides. Zero means unlimited.
+	MaxCallsPerClient   int
+	MaxCallsPerClientDB map[string]int
+
+	JSONMaxDepth            int
+	JSONMaxBodyBytes        int
+	JSONRejectUnknownFields bool
+	JSONRejectDuplicateKeys bool
+	LogLevel                string
+	LogOutput               string
+	LogMaxSizeMB            int
+	LogMaxBackups           int
+	LogMaxAgeDays           int
+	LogCompress             bool
+	// LogRedactKeys are blanked in every log record and in audit entries;
+	// LOG_DEBUG_SAMPLE=n keeps 1 in n DEBUG records (0/1 keep all).
+	LogRedactKeys     []string
+	LogDebugSample    int
+	SlowCallThreshold time.Duration
+
+	// SourcePath is the config file the values came from ("" when running
+	// on environment variables only); Reload re-reads it on SIGHUP.
+	SourcePath string
+}
+
+// envBool reads a boolean environment variable; unset or unparsable values
+// are treated as false.
+func envBool(name string) bool {
+	v, err := strconv.ParseBool(os.Getenv(name))
+	return err == nil && v
+}
+
+// envSeconds reads a duration environment variable expressed in whole
+// seconds, returning the default when unset.
+func envSeconds(name string, defaultSeconds int) (time.Duration, error) {
+	str := os.Getenv(name)
+	if str == "" {
+		return time.Duration(defaultSeconds) * time.Second, nil
+	}
+	seconds, err := strconv.Atoi(str)
+	if err != nil || seconds < 0 {
+		return 0, fmt.Errorf("invalid %s value: '%s', must be a non-negative integer (seconds)", name, str)
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
+// parseCIDR parses a CIDR or bare IP into a net.
+func parseCIDR(value string) (*net.IPNet, error) {
+	if !strings.Contains(value, "/") {
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid IP '%s'", value)
+		}
+		bitLen := 32
+		if ip.To4() == nil {
+			bitLen = 128
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bitLen, bitLen)}, nil
+	}
+	_, ipNet, err := net.ParseCIDR(value)
+	return ipNet, err
+}
+
+// parseCIDRRules splits a rule list into global nets and per-database nets
+// ("db=cidr" entries).
+func parseCIDRRules(value string) ([]*net.IPNet, map[string][]*net.IPNet, error) {
+	var global []*net.IPNet
+	perDB := make(map[string][]*net.IPNet)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		dbName := ""
+		if name, cidr, found := strings.Cut(entry, "="); found {
+			dbName, entry = strings.TrimSpace(name), strings.TrimSpace(cidr)
+		}
+		ipNet, err := parseCIDR(entry)
+		if err != nil {
+			return nil, nil, err
+		}
+		if dbName == "" {
+			global = append(global, ipNet)
+		} else {
+			perDB[dbName] = append(perDB[dbName], ipNet)
+		}
+	}
+	return global, perDB, nil
+}
+
+// parseDBMap parses "db=value,db2=value" lists used by the per-database
+// override settings.
+func parseDBMap(value string) map[string]string {
+	entries := make(map[string]string)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if name, v, found := strings.Cut(entry, "="); found {
+			entries[strings.TrimSpace(name)] = strings.TrimSpace(v)
+		}
+	}
+	return entries
+}
+
+// splitPipeList splits "a|b|c" values inside per-database settings.
+func splitPipeList(value string) []string {
+	var entries []string
+	for _, entry := range strings.Split(value, "|") {
+		if trimmed := strings.TrimSpace(entry); trimmed != "" {
+			entries = append(entries, trimmed)
+		}
+	}
+	return entries
 }
 
 // Search paths for configuration
 // 1. Explicitly provided path (flag)
-// 2. Current directory: ./pgarachne.env
+// 2. Current directory: ./pgarachne.env (or .toml/.yaml/.yml)
 // 3. User config: $XDG_CONFIG_HOME/pgarachne/pgarachne.env (or ~/.config/...)
 // 4. System config: /etc/pgarachne/pgarachne.env
 
 func Load(configPath string) (*Config, error) {
+	return load(configPath, false)
+}
+
+// Reload re-reads the config file (overriding values already present in the
+// process environment, which plain Load does not) and re-parses everything.
+// Used for SIGHUP-style hot reloads.
+func Reload(configPath string) (*Config, error) {
+	return load(configPath, true)
+}
+
+func load(configPath string, overload bool) (*Config, error) {
 	loadedFile := ""
 
+	// godotenv.Load leaves existing environment values alone; on reload we
+	// need the file to win so edits actually apply. Structured (TOML/YAML)
+	// files go through loadStructuredFile, which follows the same rule.
+	loadFile := func(path string) error {
+		if structuredConfigPath(path) {
+			return loadStructuredFile(path, overload)
+		}
+		if overload {
+			return godotenv.Overload(path)
+		}
+		return godotenv.Load(path)
+	}
+
 	if configPath != "" {
 		// 1. Explicit path
-		if err := godotenv.Load(configPath); err != nil {
+		if err := loadFile(configPath); err != nil {
 			return nil, fmt.Errorf("failed to load config file '%s': %w", configPath, err)
 		}
 		loadedFile = configPath
 	} else {
-		// Automatic search
-		searchPaths := []string{
-			"pgarachne.env", // Current dir
-		}
+		// Automatic search. The env file keeps precedence over the
+		// structured variants so existing deployments are unaffected.
+		names := []string{"pgarachne.env", "pgarachne.toml", "pgarachne.yaml", "pgarachne.yml"}
+		searchPaths := append([]string{}, names...) // Current dir
 
 		// User Config
 		configHome := os.Getenv("XDG_CONFIG_HOME")
@@ -53,16 +391,20 @@ func Load(configPath string) (*Config, error) {
 			}
 		}
 		if configHome != "" {
-			searchPaths = append(searchPaths, filepath.Join(configHome, "pgarachne", "pgarachne.env"))
+			for _, name := range names {
+				searchPaths = append(searchPaths, filepath.Join(configHome, "pgarachne", name))
+			}
 		}
 
 		// System Config
-		searchPaths = append(searchPaths, "/etc/pgarachne/pgarachne.env")
+		for _, name := range names {
+			searchPaths = append(searchPaths, filepath.Join("/etc/pgarachne", name))
+		}
 
 		// Try to load first existing
 		for _, path := range searchPaths {
 			if _, err := os.Stat(path); err == nil {
-				if err := godotenv.Load(path); err == nil {
+				if err := loadFile(path); err == nil {
 					loadedFile = path
 					break
 				}
@@ -76,10 +418,56 @@ func Load(configPath string) (*Config, error) {
 		fmt.Println("No configuration file found in standard locations. Using environment variables only.")
 	}
 
-	cfg := &Config{}
+	// *_FILE secret variants (mounted Docker/Kubernetes secrets) are
+	// resolved after file loading so a config file may also name them.
+	if err := resolveSecretFiles(); err != nil {
+		return nil, err
+	}
+	// <KEY>_SECRET references are fetched through SECRETS_PROVIDER.
+	if err := resolveRemoteSecrets(); err != nil {
+		return nil, err
+	}
+
+	cfg := &Config{SourcePath: loadedFile}
 
-	cfg.DBHost = os.Getenv("DB_HOST")
+	// DB_HOST may be a comma-separated list for primary failover
+	// (e.g. "pg1,pg2:5433"). The first entry is kept in DBHost for
+	// backwards compatibility.
+	dbHostStr := os.Getenv("DB_HOST")
+	if dbHostStr != "" {
+		hosts := strings.Split(dbHostStr, ",")
+		cfg.DBHosts = make([]string, 0, len(hosts))
+		for _, host := range hosts {
+			trimmedHost := strings.TrimSpace(host)
+			if trimmedHost != "" {
+				cfg.DBHosts = append(cfg.DBHosts, trimmedHost)
+			}
+		}
+	}
+	if len(cfg.DBHosts) > 0 {
+		cfg.DBHost = cfg.DBHosts[0]
+	}
 	cfg.DBUser = os.Getenv("DB_USER")
+
+	cfg.DBSSLMode = os.Getenv("DB_SSLMODE")
+	if cfg.DBSSLMode == "" {
+		cfg.DBSSLMode = "disable"
+	}
+	switch cfg.DBSSLMode {
+	case "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+	default:
+		return nil, fmt.Errorf("invalid DB_SSLMODE value: '%s'", cfg.DBSSLMode)
+	}
+	cfg.DBSSLRootCert = os.Getenv("DB_SSLROOTCERT")
+	cfg.DBSSLCert = os.Getenv("DB_SSLCERT")
+	cfg.DBSSLKey = os.Getenv("DB_SSLKEY")
+
+	// DB_DSN carries additional connection parameters verbatim (e.g.
+	// "sslmode=require connect_timeout=5 target_session_attrs=read-write");
+	// DB_DSN_DB overrides it per database ("sales=sslmode=verify-full").
+	cfg.DBDSN = os.Getenv("DB_DSN")
+	cfg.DBDSNPerDB = parseDBMap(os.Getenv("DB_DSN_DB"))
+
 	cfg.HTTPPort = os.Getenv("HTTP_PORT")
 	cfg.JWTSecret = os.Getenv("JWT_SECRET")
 
@@ -93,6 +481,72 @@ func Load(configPath string) (*Config, error) {
 		cfg.LogOutput = "stdout"
 	}
 
+	// Log rotation (only meaningful when LOG_OUTPUT is a file).
+	// LOG_MAX_SIZE_MB=0 disables size-based rotation; SIGUSR1 reopening for
+	// external logrotate always works.
+	envInt := func(name string, defaultValue int) (int, error) {
+		str := os.Getenv(name)
+		if str == "" {
+			return defaultValue, nil
+		}
+		value, err := strconv.Atoi(str)
+		if err != nil || value < 0 {
+			return 0, fmt.Errorf("invalid %s value: '%s', must be a non-negative integer", name, str)
+		}
+		return value, nil
+	}
+	var logErr error
+	if cfg.LogMaxSizeMB, logErr = envInt("LOG_MAX_SIZE_MB", 100); logErr != nil {
+		return nil, logErr
+	}
+	if cfg.LogMaxBackups, logErr = envInt("LOG_MAX_BACKUPS", 10); logErr != nil {
+		return nil, logErr
+	}
+	if cfg.LogMaxAgeDays, logErr = envInt("LOG_MAX_AGE_DAYS", 0); logErr != nil {
+		return nil, logErr
+	}
+	cfg.LogCompress = envBool("LOG_COMPRESS")
+
+	// Slow call logging: function calls above the threshold are logged at
+	// WARN and counted in pgarachne_slow_calls_total. 0 disables it.
+	slowMS, slowErr := envInt("SLOW_CALL_THRESHOLD_MS", 0)
+	if slowErr != nil {
+		return nil, slowErr
+	}
+	cfg.SlowCallThreshold = time.Duration(slowMS) * time.Millisecond
+
+	// Concurrency limits: MAX_CONCURRENT_CALLS applies per database, with
+	// MAX_CONCURRENT_CALLS_DB ("db=n") overrides; saturated callers queue
+	// for at most CALL_QUEUE_TIMEOUT.
+	if cfg.MaxConcurrentCalls, logErr = envInt("MAX_CONCURRENT_CALLS", 0); logErr != nil {
+		return nil, logErr
+	}
+	cfg.MaxConcurrentCallsDB = make(map[string]int)
+	for dbName, v := range parseDBMap(os.Getenv("MAX_CONCURRENT_CALLS_DB")) {
+		n, convErr := strconv.Atoi(v)
+		if convErr != nil || n < 0 {
+			return nil, fmt.Errorf("invalid MAX_CONCURRENT_CALLS_DB value for '%s': '%s'", dbName, v)
+		}
+		cfg.MaxConcurrentCallsDB[dbName] = n
+	}
+	if cfg.CallQueueTimeout, slowErr = envSeconds("CALL_QUEUE_TIMEOUT", 2); slowErr != nil {
+		return nil, slowErr
+	}
+
+	// Per-client caps: MAX_CALLS_PER_CLIENT bounds one token's (or IP's)
+	// in-flight requests, with MAX_CALLS_PER_CLIENT_DB ("db=n") overrides.
+	if cfg.MaxCallsPerClient, logErr = envInt("MAX_CALLS_PER_CLIENT", 0); logErr != nil {
+		return nil, logErr
+	}
+	cfg.MaxCallsPerClientDB = make(map[string]int)
+	for dbName, v := range parseDBMap(os.Getenv("MAX_CALLS_PER_CLIENT_DB")) {
+		n, convErr := strconv.Atoi(v)
+		if convErr != nil || n < 0 {
+			return nil, fmt.Errorf("invalid MAX_CALLS_PER_CLIENT_DB value for '%s': '%s'", dbName, v)
+		}
+		cfg.MaxCallsPerClientDB[dbName] = n
+	}
+
 	dbPortStr := os.Getenv("DB_PORT")
 	if dbPortStr != "" {
 		port, err := strconv.Atoi(dbPortStr)
@@ -102,6 +556,53 @@ func Load(configPath string) (*Config, error) {
 		cfg.DBPort = port
 	}
 
+	// Optional SQL function called during login to add custom claims to the
+	// issued JWT (e.g. LOGIN_FUNCTION=pgarachne.login).
+	cfg.LoginFunction = os.Getenv("LOGIN_FUNCTION")
+
+	// Role for unauthenticated requests (PostgREST-style anonymous access);
+	// empty keeps the API fully authenticated.
+	cfg.AnonRole = os.Getenv("ANON_ROLE")
+
+	// Optional SQL function for delegated password changes; empty means a
+	// direct ALTER ROLE on the user's own verified connection.
+	cfg.PasswordFunction = os.Getenv("PASSWORD_FUNCTION")
+
+	// Public signup: a designated function (e.g. pgarachne.signup) invoked
+	// as a restricted role, rate-limited per client IP.
+	cfg.SignupFunction = os.Getenv("SIGNUP_FUNCTION")
+	cfg.SignupRole = os.Getenv("SIGNUP_ROLE")
+	if cfg.SignupRateLimit, logErr = envInt("SIGNUP_RATE_LIMIT", 5); logErr != nil {
+		return nil, logErr
+	}
+
+	// Bot protection on the public endpoints: turnstile, hcaptcha or pow.
+	cfg.CaptchaProvider = os.Getenv("CAPTCHA_PROVIDER")
+	cfg.CaptchaSecret = os.Getenv("CAPTCHA_SECRET")
+	switch cfg.CaptchaProvider {
+	case "", "pow":
+	case "turnstile", "hcaptcha":
+		if cfg.CaptchaSecret == "" {
+			return nil, fmt.Errorf("CAPTCHA_PROVIDER=%s requires CAPTCHA_SECRET", cfg.CaptchaProvider)
+		}
+	default:
+		return nil, fmt.Errorf("invalid CAPTCHA_PROVIDER value: '%s' (supported: turnstile, hcaptcha, pow)", cfg.CaptchaProvider)
+	}
+	if cfg.PowDifficulty, logErr = envInt("POW_DIFFICULTY", 20); logErr != nil {
+		return nil, logErr
+	}
+
+	// CIDR access rules. Entries are either global ("10.0.0.0/8") or
+	// per-database ("admin=192.168.1.0/24"); bare IPs get a full-length mask.
+	// IP_ALLOW=10.0.0.0/8,admin=192.0.2.0/24  IP_DENY=203.0.113.0/24
+	var ipErr error
+	if cfg.IPAllow, cfg.IPAllowDB, ipErr = parseCIDRRules(os.Getenv("IP_ALLOW")); ipErr != nil {
+		return nil, fmt.Errorf("IP_ALLOW: %w", ipErr)
+	}
+	if cfg.IPDeny, cfg.IPDenyDB, ipErr = parseCIDRRules(os.Getenv("IP_DENY")); ipErr != nil {
+		return nil, fmt.Errorf("IP_DENY: %w", ipErr)
+	}
+
 	jwtExpiryStr := os.Getenv("JWT_EXPIRY_HOURS")
 	if jwtExpiryStr != "" {
 		hours, err := strconv.Atoi(jwtExpiryStr)
@@ -113,10 +614,43 @@ func Load(configPath string) (*Config, error) {
 		cfg.JWTExpiryHours = 8 // Default
 	}
 
-	if cfg.HTTPPort == "" {
+	// Unix domain socket listener. When HTTP_SOCKET is set and HTTP_PORT is
+	// not, the TCP listener is disabled entirely (socket-only deployment
+	// behind nginx/caddy on the same host).
+	cfg.HTTPSocket = os.Getenv("HTTP_SOCKET")
+	cfg.HTTPSocketMode = 0660
+	socketModeStr := os.Getenv("HTTP_SOCKET_MODE")
+	if socketModeStr != "" {
+		mode, err := strconv.ParseUint(socketModeStr, 8, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid HTTP_SOCKET_MODE value: '%s', must be octal (e.g. 0660)", socketModeStr)
+		}
+		cfg.HTTPSocketMode = os.FileMode(mode)
+	}
+
+	if cfg.HTTPPort == "" && cfg.HTTPSocket == "" {
 		cfg.HTTPPort = "8080"
 	}
 
+	// Server timeouts (seconds). Zero disables a timeout, but the defaults
+	// protect against slowloris-style clients out of the box.
+	var err error
+	if cfg.HTTPReadHeaderTimeout, err = envSeconds("HTTP_READ_HEADER_TIMEOUT", 10); err != nil {
+		return nil, err
+	}
+	if cfg.HTTPWriteTimeout, err = envSeconds("HTTP_WRITE_TIMEOUT", 60); err != nil {
+		return nil, err
+	}
+	if cfg.ShutdownTimeout, err = envSeconds("SHUTDOWN_TIMEOUT", 5); err != nil {
+		return nil, err
+	}
+	if cfg.HTTPIdleTimeout, err = envSeconds("HTTP_IDLE_TIMEOUT", 120); err != nil {
+		return nil, err
+	}
+
+	// Plaintext HTTP/2 (h2c) for load balancers that speak HTTP/2 without TLS.
+	cfg.HTTPH2C = envBool("HTTP_H2C")
+
 	allowedOriginsStr := os.Getenv("ALLOWED_ORIGINS")
 	if allowedOriginsStr != "" {
 		origins := strings.Split(allowedOriginsStr, ",")
@@ -132,6 +666,224 @@ func Load(configPath string) (*Config, error) {
 		cfg.AllowedOrigins = []string{"*"}
 	}
 
+	// Per-database CORS overrides, entries "db=value,db2=value" with "|"
+	// separating multiple origins/headers:
+	// CORS_ORIGINS_DB=sales=https://app.example.com|https://admin.example.com
+	// CORS_CREDENTIALS_DB=sales=false  CORS_EXPOSE_HEADERS_DB=sales=ETag
+	cfg.CORSOriginsDB = make(map[string][]string)
+	for dbName, value := range parseDBMap(os.Getenv("CORS_ORIGINS_DB")) {
+		cfg.CORSOriginsDB[dbName] = splitPipeList(value)
+	}
+	cfg.CORSCredentialsDB = make(map[string]bool)
+	for dbName, value := range parseDBMap(os.Getenv("CORS_CREDENTIALS_DB")) {
+		enabled, parseErr := strconv.ParseBool(value)
+		if parseErr != nil {
+			return nil, fmt.Errorf("invalid CORS_CREDENTIALS_DB value for '%s': '%s'", dbName, value)
+		}
+		cfg.CORSCredentialsDB[dbName] = enabled
+	}
+	cfg.CORSExposeHeadersDB = make(map[string][]string)
+	for dbName, value := range parseDBMap(os.Getenv("CORS_EXPOSE_HEADERS_DB")) {
+		cfg.CORSExposeHeadersDB[dbName] = splitPipeList(value)
+	}
+
+	// Static JSON endpoints (agent/integration manifests):
+	// STATIC_JSON_ENDPOINTS=/.well-known/ai-plugin.json=/etc/pgarachne/ai-plugin.json,/manifest.json=./manifest.json
+	cfg.BaseURL = strings.TrimSuffix(os.Getenv("BASE_URL"), "/")
+
+	// BASE_PATH mounts the whole API under a URL prefix for path-based
+	// ingress routing; normalized to "/prefix" form.
+	if basePath := strings.Trim(os.Getenv("BASE_PATH"), "/"); basePath != "" {
+		cfg.BasePath = "/" + basePath
+	}
+
+	staticJSONStr := os.Getenv("STATIC_JSON_ENDPOINTS")
+	if staticJSONStr != "" {
+		cfg.StaticJSONFiles = make(map[string]string)
+		for _, entry := range strings.Split(staticJSONStr, ",") {
+			entry = strings.TrimSpace(entry)
+			if entry == "" {
+				continue
+			}
+			parts := strings.SplitN(entry, "=", 2)
+			if len(parts) != 2 || !strings.HasPrefix(parts[0], "/") {
+				return nil, fmt.Errorf("invalid STATIC_JSON_ENDPOINTS entry '%s', expected /url/path=/file/path", entry)
+			}
+			filePath := strings.TrimSpace(parts[1])
+			if _, err := os.Stat(filePath); err != nil {
+				return nil, fmt.Errorf("STATIC_JSON_ENDPOINTS file not readable for '%s': %w", parts[0], err)
+			}
+			cfg.StaticJSONFiles[strings.TrimSpace(parts[0])] = filePath
+		}
+	}
+
+	splitList := func(value string) []string {
+		var entries []string
+		for _, entry := range strings.Split(value, ",") {
+			if trimmed := strings.TrimSpace(entry); trimmed != "" {
+				entries = append(entries, trimmed)
+			}
+		}
+		return entries
+	}
+	// DATABASES restricts which catalogs the API will touch at all; without
+	// it any authenticated caller could have pools opened to e.g. postgres
+	// or template1.
+	cfg.Databases = splitList(os.Getenv("DATABASES"))
+
+	cfg.DBCritical = splitList(os.Getenv("DB_CRITICAL"))
+	cfg.DBOptional = splitList(os.Getenv("DB_OPTIONAL"))
+
+	// Tenant routing. The pattern must consume the tenant name exactly once.
+	cfg.TenantHeader = os.Getenv("TENANT_HEADER")
+	cfg.TenantHostSuffix = os.Getenv("TENANT_HOST_SUFFIX")
+	cfg.TenantDBPattern = os.Getenv("TENANT_DB_PATTERN")
+	if cfg.TenantDBPattern != "" && strings.Count(cfg.TenantDBPattern, "%s") != 1 {
+		return nil, fmt.Errorf("invalid TENANT_DB_PATTERN value: '%s', must contain exactly one %%s", cfg.TenantDBPattern)
+	}
+	cfg.TenantDBMap = parseDBMap(os.Getenv("TENANT_DB_MAP"))
+
+	// API version -> schema mappings; both versions and schemas are
+	// validated here so the call path can trust them.
+	versionPattern := regexp.MustCompile(`^v[0-9]+$`)
+	schemaPattern := regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+	cfg.APIVersions = make(map[string]string)
+	for version, schema := range parseDBMap(os.Getenv("API_VERSIONS")) {
+		if !versionPattern.MatchString(version) || !schemaPattern.MatchString(schema) {
+			return nil, fmt.Errorf("invalid API_VERSIONS entry: '%s=%s'", version, schema)
+		}
+		cfg.APIVersions[version] = schema
+	}
+	cfg.APIVersionsDB = make(map[string]map[string]string)
+	for dbName, value := range parseDBMap(os.Getenv("API_VERSIONS_DB")) {
+		versions := make(map[string]string)
+		for _, entry := range splitPipeList(value) {
+			version, schema, found := strings.Cut(entry, ":")
+			if !found || !versionPattern.MatchString(version) || !schemaPattern.MatchString(schema) {
+				return nil, fmt.Errorf("invalid API_VERSIONS_DB entry for '%s': '%s'", dbName, entry)
+			}
+			versions[version] = schema
+		}
+		cfg.APIVersionsDB[dbName] = versions
+	}
+
+	// Default schema for unversioned calls, with per-database overrides.
+	cfg.DefaultSchema = os.Getenv("DEFAULT_SCHEMA")
+	if cfg.DefaultSchema != "" && !schemaPattern.MatchString(cfg.DefaultSchema) {
+		return nil, fmt.Errorf("invalid DEFAULT_SCHEMA value: '%s'", cfg.DefaultSchema)
+	}
+	cfg.DefaultSchemaDB = make(map[string]string)
+	for dbName, schema := range parseDBMap(os.Getenv("DEFAULT_SCHEMA_DB")) {
+		if !schemaPattern.MatchString(schema) {
+			return nil, fmt.Errorf("invalid DEFAULT_SCHEMA_DB value for '%s': '%s'", dbName, schema)
+		}
+		cfg.DefaultSchemaDB[dbName] = schema
+	}
+
+	cfg.LeaderElection = envBool("LEADER_ELECTION")
+
+	cfg.RoleLimits = envBool("ROLE_LIMITS")
+
+	cfg.UsageAccounting = envBool("USAGE_ACCOUNTING")
+
+	cfg.DevMode = envBool("DEV_MODE")
+
+	// Mock mode for frontend development without Postgres.
+	cfg.MockFixturesPath = os.Getenv("MOCK_FIXTURES_PATH")
+	if cfg.MockFixturesPath != "" {
+		if info, statErr := os.Stat(cfg.MockFixturesPath); statErr != nil || !info.IsDir() {
+			return nil, fmt.Errorf("MOCK_FIXTURES_PATH is not a directory: '%s'", cfg.MockFixturesPath)
+		}
+	}
+	mockLatencyMS, mockErr := envInt("MOCK_LATENCY_MS", 0)
+	if mockErr != nil {
+		return nil, mockErr
+	}
+	cfg.MockLatency = time.Duration(mockLatencyMS) * time.Millisecond
+
+	// Browser hardening headers and the HTTP-to-HTTPS redirect listener.
+	cfg.SecurityHeaders = envBool("SECURITY_HEADERS")
+	cfg.CSP = os.Getenv("CSP")
+	if cfg.HSTSMaxAge, err = envSeconds("HSTS_MAX_AGE", 365*24*60*60); err != nil {
+		return nil, err
+	}
+	cfg.HTTPSRedirectPort = os.Getenv("HTTPS_REDIRECT_PORT")
+
+	// Proxies allowed to supply the real client IP via X-Forwarded-For.
+	// Validated here so a typo fails startup, not silently trusts nobody.
+	cfg.TrustedProxies = splitList(os.Getenv("TRUSTED_PROXIES"))
+	for _, proxy := range cfg.TrustedProxies {
+		if _, err := parseCIDR(proxy); err != nil {
+			return nil, fmt.Errorf("TRUSTED_PROXIES: %w", err)
+		}
+	}
+
+	// Runtime debugging: /debug/pprof/* and /debug/vars are only mounted
+	// when DEBUG_ENDPOINTS is set, and always require the admin token.
+	cfg.DebugEndpoints = envBool("DEBUG_ENDPOINTS")
+	cfg.AdminToken = os.Getenv("ADMIN_TOKEN")
+	if cfg.DebugEndpoints && cfg.AdminToken == "" {
+		return nil, fmt.Errorf("DEBUG_ENDPOINTS requires ADMIN_TOKEN")
+	}
+
+	// Application schema migrations for `pgarachne migrate`: one
+	// subdirectory per database under this path.
+	cfg.MigrationsPath = os.Getenv("MIGRATIONS_PATH")
+
+	// The embedded console at /ui is on by default; UI_ENABLED=false hides it.
+	cfg.UIEnabled = true
+	if uiStr := os.Getenv("UI_ENABLED"); uiStr != "" {
+		enabled, parseErr := strconv.ParseBool(uiStr)
+		if parseErr != nil {
+			return nil, fmt.Errorf("invalid UI_ENABLED value: '%s'", uiStr)
+		}
+		cfg.UIEnabled = enabled
+	}
+
+	// Databases whose webhook subscriptions get an outgoing dispatcher
+	// (LISTEN -> signed POST).
+	cfg.WebhookDatabases = splitList(os.Getenv("WEBHOOK_DATABASES"))
+	cfg.WebhookAllowPrivate = envBool("WEBHOOK_ALLOW_PRIVATE")
+
+	// Databases whose pgarachne.schedules get the built-in cron runner.
+	cfg.ScheduleDatabases = splitList(os.Getenv("SCHEDULE_DATABASES"))
+
+	// Roles allowed to use the X-Impersonate-Role header; membership in the
+	// target role is still verified against pg_has_role per request.
+	cfg.ImpersonationRoles = splitList(os.Getenv("IMPERSONATION_ROLES"))
+
+	// Audit trail: "db" records through pgarachne.audit() in the target
+	// database, any other non-empty value is a JSON-lines file path.
+	cfg.AuditLog = os.Getenv("AUDIT_LOG")
+	cfg.AuditRedactKeys = splitList(os.Getenv("AUDIT_REDACT_KEYS"))
+
+	// Log redaction and DEBUG sampling. The redaction keys apply to every
+	// slog record and are merged into the audit redaction set.
+	if redactKeysStr := os.Getenv("LOG_REDACT_KEYS"); redactKeysStr != "" {
+		cfg.LogRedactKeys = splitList(redactKeysStr)
+	} else {
+		cfg.LogRedactKeys = []string{"password", "token", "secret", "card_number"}
+	}
+	if cfg.LogDebugSample, err = envInt("LOG_DEBUG_SAMPLE", 1); err != nil {
+		return nil, err
+	}
+
+	if cfg.SecretsRefresh, err = envSeconds("SECRETS_REFRESH", 0); err != nil {
+		return nil, err
+	}
+
+	replicaHostsStr := os.Getenv("DB_REPLICA_HOSTS")
+	if replicaHostsStr != "" {
+		hosts := strings.Split(replicaHostsStr, ",")
+		cfg.DBReplicaHosts = make([]string, 0, len(hosts))
+		for _, host := range hosts {
+			trimmedHost := strings.TrimSpace(host)
+			if trimmedHost != "" {
+				cfg.DBReplicaHosts = append(cfg.DBReplicaHosts, trimmedHost)
+			}
+		}
+	}
+
 	staticPath := os.Getenv("STATIC_FILES_PATH")
 	if staticPath != "" {
 		absPath, err := filepath.Abs(staticPath)
@@ -150,6 +902,118 @@ func Load(configPath string) (*Config, error) {
 		}
 		cfg.StaticFilesPath = absPath
 	}
+	if cfg.StaticCacheMaxAge, err = envSeconds("STATIC_CACHE_MAX_AGE", 3600); err != nil {
+		return nil, err
+	}
+	cfg.StaticDirListing = envBool("STATIC_DIR_LISTING")
+	cfg.StaticServeDotfiles = envBool("STATIC_SERVE_DOTFILES")
+	cfg.StaticFollowSymlinks = envBool("STATIC_FOLLOW_SYMLINKS")
+	cfg.StaticExtensions = splitList(os.Getenv("STATIC_EXTENSIONS"))
+
+	// Server-side HTML rendering from SQL results.
+	cfg.TemplatesPath = os.Getenv("TEMPLATES_PATH")
+	if cfg.TemplatesPath != "" {
+		if info, statErr := os.Stat(cfg.TemplatesPath); statErr != nil || !info.IsDir() {
+			return nil, fmt.Errorf("TEMPLATES_PATH is not a directory: '%s'", cfg.TemplatesPath)
+		}
+	}
+
+	// Shared state backend for rate limiting / idempotency / revocation.
+	cfg.StateStorage = os.Getenv("STATE_STORAGE")
+	cfg.StateStorageDatabase = os.Getenv("STATE_STORAGE_DATABASE")
+	if cfg.StateStorage == "postgres" && cfg.StateStorageDatabase == "" {
+		return nil, fmt.Errorf("STATE_STORAGE=postgres requires STATE_STORAGE_DATABASE")
+	}
+	if cfg.IdempotencyTTL, err = envSeconds("IDEMPOTENCY_TTL", 24*60*60); err != nil {
+		return nil, err
+	}
+
+	// Object storage offload. Credentials fall back to the standard AWS
+	// environment so one set of keys can serve secrets and storage.
+	cfg.StorageBackend = os.Getenv("STORAGE_BACKEND")
+	cfg.S3Endpoint = os.Getenv("S3_ENDPOINT")
+	cfg.S3Bucket = os.Getenv("S3_BUCKET")
+	cfg.S3Region = os.Getenv("S3_REGION")
+	if cfg.S3Region == "" {
+		cfg.S3Region = os.Getenv("AWS_REGION")
+	}
+	cfg.S3AccessKey = os.Getenv("S3_ACCESS_KEY")
+	if cfg.S3AccessKey == "" {
+		cfg.S3AccessKey = os.Getenv("AWS_ACCESS_KEY_ID")
+	}
+	cfg.S3SecretKey = os.Getenv("S3_SECRET_KEY")
+	if cfg.S3SecretKey == "" {
+		cfg.S3SecretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+	}
+	cfg.S3ForcePathStyle = envBool("S3_FORCE_PATH_STYLE")
+	if cfg.S3PresignTTL, err = envSeconds("S3_PRESIGN_TTL", 900); err != nil {
+		return nil, err
+	}
+
+	// Access logging.
+	cfg.AccessLogFormat = os.Getenv("ACCESS_LOG_FORMAT")
+	if cfg.AccessLogFormat == "" {
+		cfg.AccessLogFormat = "json"
+	}
+	if cfg.AccessLogFormat != "json" && cfg.AccessLogFormat != "combined" && cfg.AccessLogFormat != "off" {
+		return nil, fmt.Errorf("invalid ACCESS_LOG_FORMAT value: '%s', must be 'json', 'combined' or 'off'", cfg.AccessLogFormat)
+	}
+	cfg.AccessLogExclude = splitList(os.Getenv("ACCESS_LOG_EXCLUDE"))
+
+	// Error tracking. An empty DSN disables it entirely.
+	cfg.SentryDSN = os.Getenv("SENTRY_DSN")
+	cfg.SentryRelease = os.Getenv("SENTRY_RELEASE")
+	cfg.SentryEnvironment = os.Getenv("SENTRY_ENVIRONMENT")
+
+	// JSON parser hardening. JSON_MAX_DEPTH=0 disables the depth check.
+	jsonMaxDepthStr := os.Getenv("JSON_MAX_DEPTH")
+	if jsonMaxDepthStr != "" {
+		depth, err := strconv.Atoi(jsonMaxDepthStr)
+		if err != nil || depth < 0 {
+			return nil, fmt.Errorf("invalid JSON_MAX_DEPTH value: '%s', must be a non-negative integer", jsonMaxDepthStr)
+		}
+		cfg.JSONMaxDepth = depth
+	}
+	// JSON_MAX_BODY_BYTES bounds the request body before any decoding —
+	// including the msgpack/CBOR conversion — runs. Zero disables the check.
+	if cfg.JSONMaxBodyBytes, logErr = envInt("JSON_MAX_BODY_BYTES", 10*1024*1024); logErr != nil {
+		return nil, logErr
+	}
+	cfg.JSONRejectUnknownFields = envBool("JSON_REJECT_UNKNOWN_FIELDS")
+	cfg.JSONRejectDuplicateKeys = envBool("JSON_REJECT_DUPLICATE_KEYS")
+
+	cfg.TLSCertFile = os.Getenv("TLS_CERT_FILE")
+	cfg.TLSKeyFile = os.Getenv("TLS_KEY_FILE")
+	if (cfg.TLSCertFile == "") != (cfg.TLSKeyFile == "") {
+		return nil, fmt.Errorf("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if cfg.TLSCertFile != "" {
+		if _, err := os.Stat(cfg.TLSCertFile); err != nil {
+			return nil, fmt.Errorf("TLS_CERT_FILE not readable: %w", err)
+		}
+		if _, err := os.Stat(cfg.TLSKeyFile); err != nil {
+			return nil, fmt.Errorf("TLS_KEY_FILE not readable: %w", err)
+		}
+	}
+
+	// mTLS client authentication: a CA bundle for verifying machine clients
+	// and a certificate-CN-to-role mapping file. Both hot-reload at runtime.
+	cfg.TLSClientCAFile = os.Getenv("TLS_CLIENT_CA_FILE")
+	cfg.TLSClientRoleMapFile = os.Getenv("TLS_CLIENT_ROLE_MAP_FILE")
+	if cfg.TLSClientCAFile != "" {
+		if cfg.TLSCertFile == "" {
+			return nil, fmt.Errorf("TLS_CLIENT_CA_FILE requires TLS_CERT_FILE/TLS_KEY_FILE (mTLS needs native TLS termination)")
+		}
+		if cfg.TLSClientRoleMapFile == "" {
+			return nil, fmt.Errorf("TLS_CLIENT_CA_FILE requires TLS_CLIENT_ROLE_MAP_FILE")
+		}
+		if _, err := os.Stat(cfg.TLSClientCAFile); err != nil {
+			return nil, fmt.Errorf("TLS_CLIENT_CA_FILE not readable: %w", err)
+		}
+		if _, err := os.Stat(cfg.TLSClientRoleMapFile); err != nil {
+			return nil, fmt.Errorf("TLS_CLIENT_ROLE_MAP_FILE not readable: %w", err)
+		}
+	}
 
 	if cfg.DBHost == "" || cfg.DBUser == "" || cfg.DBPort == 0 {
 		return nil, fmt.Errorf("missing required database environment variables: DB_HOST, DB_USER, DB_PORT")
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/yourusername/pgarachne/internal/secrets"
+)
+
+// Secrets from files: every key below accepts a <KEY>_FILE variant naming a
+// file whose contents become the value. Container orchestrators mount
+// secrets as files; this keeps them out of the environment and of anything
+// that dumps it. Files are re-read on reload, so rotated secrets are picked
+// up by SIGHUP. PGPASSWORD is included because lib/pq reads it directly.
+var secretFileKeys = []string{
+	"JWT_SECRET",
+	"PGPASSWORD",
+	"ADMIN_TOKEN",
+	"CAPTCHA_SECRET",
+}
+
+// resolvedSecrets remembers which keys were populated from a file or a
+// secret manager, so a reload can overwrite them without tripping the
+// both-forms-set check below.
+var resolvedSecrets = make(map[string]bool)
+
+// resolveSecretFiles loads each configured <KEY>_FILE into <KEY>. Setting
+// both forms is rejected rather than silently picking one.
+func resolveSecretFiles() error {
+	for _, key := range secretFileKeys {
+		path := os.Getenv(key + "_FILE")
+		if path == "" {
+			continue
+		}
+		if os.Getenv(key) != "" && !resolvedSecrets[key] {
+			return fmt.Errorf("both %s and %s_FILE are set; use one", key, key)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("reading %s_FILE: %w", key, err)
+		}
+		// Mounted secrets commonly end with a newline; strip it so it
+		// doesn't end up inside the secret itself.
+		if err := os.Setenv(key, strings.TrimRight(string(data), "\r\n")); err != nil {
+			return err
+		}
+		resolvedSecrets[key] = true
+	}
+	return nil
+}
+
+// resolveRemoteSecrets fetches every <KEY>_SECRET reference through the
+// provider named by SECRETS_PROVIDER (vault, aws or gcp). Runs on every load,
+// so a SIGHUP — or the SECRETS_REFRESH timer — re-fetches rotated values.
+func resolveRemoteSecrets() error {
+	providerName := os.Getenv("SECRETS_PROVIDER")
+	if providerName == "" {
+		return nil
+	}
+	provider, err := secrets.New(providerName)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	for _, key := range secretFileKeys {
+		ref := os.Getenv(key + "_SECRET")
+		if ref == "" {
+			continue
+		}
+		if os.Getenv(key) != "" && !resolvedSecrets[key] {
+			return fmt.Errorf("both %s and %s_SECRET are set; use one", key, key)
+		}
+		value, err := provider.Fetch(ctx, ref)
+		if err != nil {
+			return fmt.Errorf("fetching %s from %s: %w", key, providerName, err)
+		}
+		if err := os.Setenv(key, value); err != nil {
+			return err
+		}
+		resolvedSecrets[key] = true
+	}
+	return nil
+}
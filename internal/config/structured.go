@@ -0,0 +1,254 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pelletier/go-toml/v2"
+)
+
+// Structured configuration: pgarachne.toml / pgarachne.yaml as an alternative
+// to the flat env file. The file is parsed into fileConfig and flattened onto
+// the same environment keys the loader below reads, so both formats share one
+// parsing and validation path and cannot drift apart. Values already present
+// in the process environment win (matching godotenv.Load semantics).
+
+type fileConfig struct {
+	Server    serverSection     `toml:"server" yaml:"server"`
+	TLS       tlsSection        `toml:"tls" yaml:"tls"`
+	Auth      authSection       `toml:"auth" yaml:"auth"`
+	Logging   loggingSection    `toml:"logging" yaml:"logging"`
+	Limits    limitsSection     `toml:"limits" yaml:"limits"`
+	Databases []databaseSection `toml:"databases" yaml:"databases"`
+}
+
+type serverSection struct {
+	Port              string   `toml:"port" yaml:"port"`
+	Socket            string   `toml:"socket" yaml:"socket"`
+	SocketMode        string   `toml:"socket_mode" yaml:"socket_mode"`
+	H2C               *bool    `toml:"h2c" yaml:"h2c"`
+	ReadHeaderTimeout *int     `toml:"read_header_timeout" yaml:"read_header_timeout"`
+	WriteTimeout      *int     `toml:"write_timeout" yaml:"write_timeout"`
+	IdleTimeout       *int     `toml:"idle_timeout" yaml:"idle_timeout"`
+	BaseURL           string   `toml:"base_url" yaml:"base_url"`
+	BasePath          string   `toml:"base_path" yaml:"base_path"`
+	StaticFilesPath   string   `toml:"static_files_path" yaml:"static_files_path"`
+	AllowedOrigins    []string `toml:"allowed_origins" yaml:"allowed_origins"`
+	UIEnabled         *bool    `toml:"ui_enabled" yaml:"ui_enabled"`
+}
+
+type tlsSection struct {
+	CertFile          string `toml:"cert_file" yaml:"cert_file"`
+	KeyFile           string `toml:"key_file" yaml:"key_file"`
+	ClientCAFile      string `toml:"client_ca_file" yaml:"client_ca_file"`
+	ClientRoleMapFile string `toml:"client_role_map_file" yaml:"client_role_map_file"`
+}
+
+type authSection struct {
+	JWTSecret          string   `toml:"jwt_secret" yaml:"jwt_secret"`
+	JWTExpiryHours     *int     `toml:"jwt_expiry_hours" yaml:"jwt_expiry_hours"`
+	LoginFunction      string   `toml:"login_function" yaml:"login_function"`
+	PasswordFunction   string   `toml:"password_function" yaml:"password_function"`
+	AnonRole           string   `toml:"anon_role" yaml:"anon_role"`
+	ImpersonationRoles []string `toml:"impersonation_roles" yaml:"impersonation_roles"`
+	SignupFunction     string   `toml:"signup_function" yaml:"signup_function"`
+	SignupRole         string   `toml:"signup_role" yaml:"signup_role"`
+	CaptchaProvider    string   `toml:"captcha_provider" yaml:"captcha_provider"`
+	CaptchaSecret      string   `toml:"captcha_secret" yaml:"captcha_secret"`
+	AdminToken         string   `toml:"admin_token" yaml:"admin_token"`
+}
+
+type loggingSection struct {
+	Level      string `toml:"level" yaml:"level"`
+	Output     string `toml:"output" yaml:"output"`
+	MaxSizeMB  *int   `toml:"max_size_mb" yaml:"max_size_mb"`
+	MaxBackups *int   `toml:"max_backups" yaml:"max_backups"`
+	MaxAgeDays *int   `toml:"max_age_days" yaml:"max_age_days"`
+	Compress   *bool  `toml:"compress" yaml:"compress"`
+	AuditLog   string `toml:"audit_log" yaml:"audit_log"`
+}
+
+type limitsSection struct {
+	SignupRateLimit     *int     `toml:"signup_rate_limit" yaml:"signup_rate_limit"`
+	PowDifficulty       *int     `toml:"pow_difficulty" yaml:"pow_difficulty"`
+	SlowCallThresholdMS *int     `toml:"slow_call_threshold_ms" yaml:"slow_call_threshold_ms"`
+	JSONMaxDepth        *int     `toml:"json_max_depth" yaml:"json_max_depth"`
+	IPAllow             []string `toml:"ip_allow" yaml:"ip_allow"`
+	IPDeny              []string `toml:"ip_deny" yaml:"ip_deny"`
+}
+
+// databaseSection carries per-database overrides; each maps onto an entry of
+// the corresponding "db=value" env key.
+type databaseSection struct {
+	Name              string   `toml:"name" yaml:"name"`
+	DSN               string   `toml:"dsn" yaml:"dsn"`
+	Critical          *bool    `toml:"critical" yaml:"critical"`
+	Webhooks          *bool    `toml:"webhooks" yaml:"webhooks"`
+	Schedules         *bool    `toml:"schedules" yaml:"schedules"`
+	CORSOrigins       []string `toml:"cors_origins" yaml:"cors_origins"`
+	CORSCredentials   *bool    `toml:"cors_credentials" yaml:"cors_credentials"`
+	CORSExposeHeaders []string `toml:"cors_expose_headers" yaml:"cors_expose_headers"`
+	IPAllow           []string `toml:"ip_allow" yaml:"ip_allow"`
+	IPDeny            []string `toml:"ip_deny" yaml:"ip_deny"`
+}
+
+// structuredConfigPath reports whether path should be parsed as a structured
+// config file rather than a godotenv file.
+func structuredConfigPath(path string) bool {
+	switch filepath.Ext(path) {
+	case ".toml", ".yaml", ".yml":
+		return true
+	}
+	return false
+}
+
+// loadStructuredFile parses a TOML or YAML config file and exports its values
+// into the process environment. Unless overload is set, keys already present
+// in the environment are left alone.
+func loadStructuredFile(path string, overload bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var fc fileConfig
+	if filepath.Ext(path) == ".toml" {
+		err = toml.Unmarshal(data, &fc)
+	} else {
+		err = yaml.Unmarshal(data, &fc)
+	}
+	if err != nil {
+		return fmt.Errorf("parsing '%s': %w", path, err)
+	}
+	for key, value := range fc.flatten() {
+		if !overload {
+			if _, exists := os.LookupEnv(key); exists {
+				continue
+			}
+		}
+		if err := os.Setenv(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flatten maps the nested sections onto the flat env keys. Only values
+// actually present in the file produce a key.
+func (fc *fileConfig) flatten() map[string]string {
+	env := make(map[string]string)
+	set := func(key, value string) {
+		if value != "" {
+			env[key] = value
+		}
+	}
+	setBool := func(key string, value *bool) {
+		if value != nil {
+			env[key] = strconv.FormatBool(*value)
+		}
+	}
+	setInt := func(key string, value *int) {
+		if value != nil {
+			env[key] = strconv.Itoa(*value)
+		}
+	}
+
+	set("HTTP_PORT", fc.Server.Port)
+	set("HTTP_SOCKET", fc.Server.Socket)
+	set("HTTP_SOCKET_MODE", fc.Server.SocketMode)
+	setBool("HTTP_H2C", fc.Server.H2C)
+	setInt("HTTP_READ_HEADER_TIMEOUT", fc.Server.ReadHeaderTimeout)
+	setInt("HTTP_WRITE_TIMEOUT", fc.Server.WriteTimeout)
+	setInt("HTTP_IDLE_TIMEOUT", fc.Server.IdleTimeout)
+	set("BASE_URL", fc.Server.BaseURL)
+	set("BASE_PATH", fc.Server.BasePath)
+	set("STATIC_FILES_PATH", fc.Server.StaticFilesPath)
+	set("ALLOWED_ORIGINS", strings.Join(fc.Server.AllowedOrigins, ","))
+	setBool("UI_ENABLED", fc.Server.UIEnabled)
+
+	set("TLS_CERT_FILE", fc.TLS.CertFile)
+	set("TLS_KEY_FILE", fc.TLS.KeyFile)
+	set("TLS_CLIENT_CA_FILE", fc.TLS.ClientCAFile)
+	set("TLS_CLIENT_ROLE_MAP_FILE", fc.TLS.ClientRoleMapFile)
+
+	set("JWT_SECRET", fc.Auth.JWTSecret)
+	setInt("JWT_EXPIRY_HOURS", fc.Auth.JWTExpiryHours)
+	set("LOGIN_FUNCTION", fc.Auth.LoginFunction)
+	set("PASSWORD_FUNCTION", fc.Auth.PasswordFunction)
+	set("ANON_ROLE", fc.Auth.AnonRole)
+	set("IMPERSONATION_ROLES", strings.Join(fc.Auth.ImpersonationRoles, ","))
+	set("SIGNUP_FUNCTION", fc.Auth.SignupFunction)
+	set("SIGNUP_ROLE", fc.Auth.SignupRole)
+	set("CAPTCHA_PROVIDER", fc.Auth.CaptchaProvider)
+	set("CAPTCHA_SECRET", fc.Auth.CaptchaSecret)
+	set("ADMIN_TOKEN", fc.Auth.AdminToken)
+
+	set("LOG_LEVEL", fc.Logging.Level)
+	set("LOG_OUTPUT", fc.Logging.Output)
+	setInt("LOG_MAX_SIZE_MB", fc.Logging.MaxSizeMB)
+	setInt("LOG_MAX_BACKUPS", fc.Logging.MaxBackups)
+	setInt("LOG_MAX_AGE_DAYS", fc.Logging.MaxAgeDays)
+	setBool("LOG_COMPRESS", fc.Logging.Compress)
+	set("AUDIT_LOG", fc.Logging.AuditLog)
+
+	setInt("SIGNUP_RATE_LIMIT", fc.Limits.SignupRateLimit)
+	setInt("POW_DIFFICULTY", fc.Limits.PowDifficulty)
+	setInt("SLOW_CALL_THRESHOLD_MS", fc.Limits.SlowCallThresholdMS)
+	setInt("JSON_MAX_DEPTH", fc.Limits.JSONMaxDepth)
+
+	// Global and per-database CIDR rules share IP_ALLOW / IP_DENY; the
+	// per-database ones use the "db=cidr" entry form parseCIDRRules accepts.
+	ipAllow := append([]string{}, fc.Limits.IPAllow...)
+	ipDeny := append([]string{}, fc.Limits.IPDeny...)
+
+	var names, dsns, critical, webhooks, schedules []string
+	var corsOrigins, corsCredentials, corsExpose []string
+	for _, db := range fc.Databases {
+		if db.Name == "" {
+			continue
+		}
+		names = append(names, db.Name)
+		if db.DSN != "" {
+			dsns = append(dsns, db.Name+"="+db.DSN)
+		}
+		if db.Critical != nil && *db.Critical {
+			critical = append(critical, db.Name)
+		}
+		if db.Webhooks != nil && *db.Webhooks {
+			webhooks = append(webhooks, db.Name)
+		}
+		if db.Schedules != nil && *db.Schedules {
+			schedules = append(schedules, db.Name)
+		}
+		if len(db.CORSOrigins) > 0 {
+			corsOrigins = append(corsOrigins, db.Name+"="+strings.Join(db.CORSOrigins, "|"))
+		}
+		if db.CORSCredentials != nil {
+			corsCredentials = append(corsCredentials, db.Name+"="+strconv.FormatBool(*db.CORSCredentials))
+		}
+		if len(db.CORSExposeHeaders) > 0 {
+			corsExpose = append(corsExpose, db.Name+"="+strings.Join(db.CORSExposeHeaders, "|"))
+		}
+		for _, cidr := range db.IPAllow {
+			ipAllow = append(ipAllow, db.Name+"="+cidr)
+		}
+		for _, cidr := range db.IPDeny {
+			ipDeny = append(ipDeny, db.Name+"="+cidr)
+		}
+	}
+	set("DATABASES", strings.Join(names, ","))
+	set("DB_DSN_DB", strings.Join(dsns, ","))
+	set("IP_ALLOW", strings.Join(ipAllow, ","))
+	set("IP_DENY", strings.Join(ipDeny, ","))
+	set("DB_CRITICAL", strings.Join(critical, ","))
+	set("WEBHOOK_DATABASES", strings.Join(webhooks, ","))
+	set("SCHEDULE_DATABASES", strings.Join(schedules, ","))
+	set("CORS_ORIGINS_DB", strings.Join(corsOrigins, ","))
+	set("CORS_CREDENTIALS_DB", strings.Join(corsCredentials, ","))
+	set("CORS_EXPOSE_HEADERS_DB", strings.Join(corsExpose, ","))
+
+	return env
+}
@@ -13,6 +13,21 @@ import (
 
 const PidFile = "/tmp/pgarachne.pid"
 
+// Install and Uninstall manage the Windows service registration; on Unix
+// systems service management belongs to systemd (see the sd_notify support).
+func Install() {
+	fmt.Println("Service installation is only supported on Windows. Use a systemd unit on this platform.")
+	os.Exit(1)
+}
+
+func Uninstall() {
+	fmt.Println("Service installation is only supported on Windows. Use a systemd unit on this platform.")
+	os.Exit(1)
+}
+
+// MaybeRunService is a no-op outside Windows.
+func MaybeRunService(func() error) bool { return false }
+
 // Start launches the current executable in the background.
 // It removes the "-start" flag from arguments to prevent recursive spawning.
 func Start() {
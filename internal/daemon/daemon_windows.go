@@ -5,16 +5,214 @@ package daemon
 import (
 	"fmt"
 	"os"
+	"time"
+
+	"github.com/yourusername/pgarachne/internal/lifecycle"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/eventlog"
+	"golang.org/x/sys/windows/svc/mgr"
 )
 
-// Start is a stub for Windows.
+// On Windows, PgArachne runs as a proper service under the SCM instead of
+// the Unix PID-file daemonizer: -install/-uninstall manage the registration,
+// -start/-stop control the registered service, and log output additionally
+// goes to the Windows event log.
+
+const serviceName = "PgArachne"
+
+// Install registers the binary (with its current arguments minus the
+// -install flag) as an auto-start Windows service and creates the event log
+// source.
+func Install() {
+	exePath, err := os.Executable()
+	if err != nil {
+		fmt.Printf("Failed to determine executable path: %v\n", err)
+		os.Exit(1)
+	}
+
+	m, err := mgr.Connect()
+	if err != nil {
+		fmt.Printf("Failed to connect to service manager: %v\n", err)
+		os.Exit(1)
+	}
+	defer m.Disconnect()
+
+	if s, err := m.OpenService(serviceName); err == nil {
+		s.Close()
+		fmt.Printf("Service %s is already installed.\n", serviceName)
+		os.Exit(1)
+	}
+
+	var args []string
+	for _, arg := range os.Args[1:] {
+		if arg != "-install" && arg != "--install" {
+			args = append(args, arg)
+		}
+	}
+
+	s, err := m.CreateService(serviceName, exePath, mgr.Config{
+		DisplayName: "PgArachne API Server",
+		Description: "Exposes PostgreSQL functions as a JSON-RPC HTTP API.",
+		StartType:   mgr.StartAutomatic,
+	}, args...)
+	if err != nil {
+		fmt.Printf("Failed to install service: %v\n", err)
+		os.Exit(1)
+	}
+	defer s.Close()
+
+	if err := eventlog.InstallAsEventCreate(serviceName, eventlog.Error|eventlog.Warning|eventlog.Info); err != nil {
+		fmt.Printf("Service installed, but event log source setup failed: %v\n", err)
+	} else {
+		fmt.Printf("Service %s installed.\n", serviceName)
+	}
+	os.Exit(0)
+}
+
+// Uninstall removes the service registration and event log source.
+func Uninstall() {
+	m, err := mgr.Connect()
+	if err != nil {
+		fmt.Printf("Failed to connect to service manager: %v\n", err)
+		os.Exit(1)
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(serviceName)
+	if err != nil {
+		fmt.Printf("Service %s is not installed.\n", serviceName)
+		os.Exit(1)
+	}
+	defer s.Close()
+
+	if err := s.Delete(); err != nil {
+		fmt.Printf("Failed to uninstall service: %v\n", err)
+		os.Exit(1)
+	}
+	eventlog.Remove(serviceName)
+	fmt.Printf("Service %s uninstalled.\n", serviceName)
+	os.Exit(0)
+}
+
+// Start starts the installed Windows service.
 func Start() {
-	fmt.Println("Daemon mode is not supported on Windows.")
-	os.Exit(1)
+	m, err := mgr.Connect()
+	if err != nil {
+		fmt.Printf("Failed to connect to service manager: %v\n", err)
+		os.Exit(1)
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(serviceName)
+	if err != nil {
+		fmt.Printf("Service %s is not installed (run with -install first).\n", serviceName)
+		os.Exit(1)
+	}
+	defer s.Close()
+
+	if err := s.Start(); err != nil {
+		fmt.Printf("Failed to start service: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Service %s started.\n", serviceName)
+	os.Exit(0)
 }
 
-// Stop is a stub for Windows.
+// Stop stops the installed Windows service, waiting for it to wind down.
 func Stop() {
-	fmt.Println("Daemon mode is not supported on Windows.")
-	os.Exit(1)
+	m, err := mgr.Connect()
+	if err != nil {
+		fmt.Printf("Failed to connect to service manager: %v\n", err)
+		os.Exit(1)
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(serviceName)
+	if err != nil {
+		fmt.Printf("Service %s is not installed.\n", serviceName)
+		os.Exit(1)
+	}
+	defer s.Close()
+
+	status, err := s.Control(svc.Stop)
+	if err != nil {
+		fmt.Printf("Failed to stop service: %v\n", err)
+		os.Exit(1)
+	}
+	for deadline := time.Now().Add(30 * time.Second); status.State != svc.Stopped; {
+		if time.Now().After(deadline) {
+			fmt.Println("Timed out waiting for service to stop.")
+			os.Exit(1)
+		}
+		time.Sleep(300 * time.Millisecond)
+		if status, err = s.Query(); err != nil {
+			fmt.Printf("Failed to query service status: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	fmt.Printf("Service %s stopped.\n", serviceName)
+	os.Exit(0)
+}
+
+// serviceHandler adapts the server run loop to the SCM control protocol.
+type serviceHandler struct {
+	run func() error
+	log *eventlog.Log
+}
+
+func (h *serviceHandler) Execute(args []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.StartPending}
+
+	done := make(chan error, 1)
+	go func() { done <- h.run() }()
+
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+	h.log.Info(1, "PgArachne service started")
+
+	for {
+		select {
+		case err := <-done:
+			if err != nil {
+				h.log.Error(1, fmt.Sprintf("PgArachne service failed: %v", err))
+				status <- svc.Status{State: svc.Stopped}
+				return true, 1
+			}
+			status <- svc.Status{State: svc.Stopped}
+			return false, 0
+		case request := <-requests:
+			switch request.Cmd {
+			case svc.Interrogate:
+				status <- request.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				status <- svc.Status{State: svc.StopPending}
+				lifecycle.RequestGlobalShutdown("service control stop")
+				if err := <-done; err != nil {
+					h.log.Error(1, fmt.Sprintf("PgArachne shutdown error: %v", err))
+				}
+				h.log.Info(1, "PgArachne service stopped")
+				status <- svc.Status{State: svc.Stopped}
+				return false, 0
+			}
+		}
+	}
+}
+
+// MaybeRunService runs the server under SCM control when the process was
+// launched by the service manager. It returns true in that case; interactive
+// invocations return false and proceed normally.
+func MaybeRunService(run func() error) bool {
+	isService, err := svc.IsWindowsService()
+	if err != nil || !isService {
+		return false
+	}
+
+	log, err := eventlog.Open(serviceName)
+	if err != nil {
+		// Without the event log we still run; errors go to the config log.
+		log = &eventlog.Log{}
+	}
+	defer log.Close()
+
+	svc.Run(serviceName, &serviceHandler{run: run, log: log})
+	return true
 }
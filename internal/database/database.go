@@ -4,6 +4,9 @@ import (
 	"database/sql"
 	"fmt"
 	"log/slog"
+	"net"
+	"strconv"
+	"strings"
 	"sync"
 
 	_ "github.com/lib/pq"
@@ -13,44 +16,242 @@ import (
 var (
 	dbConnections = make(map[string]*sql.DB)
 	dbMutex       = &sync.RWMutex{}
+
+	// Last host that accepted writes; tried first on reconnect and used by
+	// code (e.g. login) that needs a direct primary address.
+	primaryMutex sync.RWMutex
+	primaryHost  string
 )
 
+// hostPort splits an optional ":port" suffix off a host entry, falling back
+// to the configured DB_PORT. IPv6 literals work bare ("::1") or with a port
+// in bracket syntax ("[::1]:5432").
+func hostPort(host string, defaultPort int) (string, int) {
+	hostName, portStr, err := net.SplitHostPort(host)
+	if err != nil {
+		// No port, or a bare IPv6 literal whose colons are not a port
+		// separator — either way the entry is the host itself.
+		return strings.Trim(host, "[]"), defaultPort
+	}
+	p, err := strconv.Atoi(portStr)
+	if err != nil {
+		return strings.Trim(host, "[]"), defaultPort
+	}
+	return hostName, p
+}
+
+// ConnString assembles the lib/pq connection string for dbName on the given
+// host. defaults are placed before the DB_DSN / DB_DSN_DB parameters, and
+// since lib/pq lets later keywords win, the configured DSN can override any
+// of them — sslmode (DB_SSLMODE, "disable" without it, as before),
+// connect_timeout, options, target_session_attrs and so on.
+func ConnString(cfg *config.Config, dbName, host string, port int, defaults ...string) string {
+	sslMode := cfg.DBSSLMode
+	if sslMode == "" {
+		// Hand-built configs (tests) skip the Load defaulting.
+		sslMode = "disable"
+	}
+	parts := []string{fmt.Sprintf("host=%s port=%d user=%s dbname=%s sslmode=%s", host, port, cfg.DBUser, dbName, sslMode)}
+	if cfg.DBSSLRootCert != "" {
+		parts = append(parts, "sslrootcert="+cfg.DBSSLRootCert)
+	}
+	if cfg.DBSSLCert != "" {
+		parts = append(parts, "sslcert="+cfg.DBSSLCert)
+	}
+	if cfg.DBSSLKey != "" {
+		parts = append(parts, "sslkey="+cfg.DBSSLKey)
+	}
+	parts = append(parts, defaults...)
+	if dsn, ok := cfg.DBDSNPerDB[dbName]; ok {
+		parts = append(parts, dsn)
+	} else if cfg.DBDSN != "" {
+		parts = append(parts, cfg.DBDSN)
+	}
+	return strings.Join(parts, " ")
+}
+
+// PrimaryAddr returns the host and port of the current primary: the last host
+// that accepted a connection as a writable primary, or the first configured
+// host before any connection has been made.
+func PrimaryAddr(cfg *config.Config) (string, int) {
+	primaryMutex.RLock()
+	host := primaryHost
+	primaryMutex.RUnlock()
+	if host == "" {
+		host = cfg.DBHost
+	}
+	return hostPort(host, cfg.DBPort)
+}
+
+// closeAllPools closes every cached pool (primary and replica) and forgets
+// the tracked primary host.
+func closeAllPools() {
+	dbMutex.Lock()
+	for name, db := range dbConnections {
+		db.Close()
+		delete(dbConnections, name)
+	}
+	dbMutex.Unlock()
+
+	replicaMutex.Lock()
+	for name, db := range replicaConnections {
+		db.Close()
+		delete(replicaConnections, name)
+	}
+	replicaMutex.Unlock()
+
+	primaryMutex.Lock()
+	primaryHost = ""
+	primaryMutex.Unlock()
+}
+
+// ResetPools closes every cached pool so subsequent requests reconnect with
+// the current configuration. Called after a config reload changes
+// connection-affecting settings.
+func ResetPools() {
+	closeAllPools()
+	slog.Info("Connection pools reset")
+}
+
+// CloseAll closes every cached pool for good. Called at the end of shutdown,
+// after the listeners have drained their in-flight requests.
+func CloseAll() {
+	closeAllPools()
+	slog.Info("Connection pools closed")
+}
+
+// PoolStats returns driver statistics for every cached pool, keyed by
+// database name (replica pools are prefixed "replica:"). Used by the admin
+// API.
+func PoolStats() map[string]sql.DBStats {
+	stats := make(map[string]sql.DBStats)
+	dbMutex.RLock()
+	for name, db := range dbConnections {
+		stats[name] = db.Stats()
+	}
+	dbMutex.RUnlock()
+	replicaMutex.RLock()
+	for name, db := range replicaConnections {
+		stats["replica:"+name] = db.Stats()
+	}
+	replicaMutex.RUnlock()
+	return stats
+}
+
+// ClosePool drops the cached pool for one database so the next request
+// reconnects from scratch. Returns false when no such pool exists.
+func ClosePool(dbName string) bool {
+	dbMutex.Lock()
+	defer dbMutex.Unlock()
+	db, ok := dbConnections[dbName]
+	if !ok {
+		return false
+	}
+	db.Close()
+	delete(dbConnections, dbName)
+	slog.Info("Connection pool dropped", "database", dbName)
+	return true
+}
+
+// CheckPrimary verifies that the primary accepts connections by pinging the
+// maintenance database. Used for readiness reporting before any API-driven
+// pool exists.
+func CheckPrimary(cfg *config.Config) error {
+	host, port := PrimaryAddr(cfg)
+	connStr := ConnString(cfg, "postgres", host, port, "connect_timeout=3")
+	db, err := sql.Open("postgres", connStr)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.Ping()
+}
+
 // GetConnection returns a specialized connection to a specific database (catalog).
-// It maintains a pool of connections.
+// It maintains a pool of connections. With multiple hosts in DB_HOST, it fails
+// over target_session_attrs-style: each host is tried in order and only one
+// that is not in recovery (i.e. a writable primary) is accepted, so the pool
+// follows the primary across a switchover without a restart.
 func GetConnection(cfg *config.Config, dbName string) (*sql.DB, error) {
+	// Cached pools are returned without a per-request ping; the background
+	// health checker (healthcheck.go) evicts broken ones, and the next
+	// request reconnects — following the primary across a switchover.
 	dbMutex.RLock()
 	db, ok := dbConnections[dbName]
 	dbMutex.RUnlock()
 	if ok {
-		if err := db.Ping(); err == nil {
-			return db, nil
-		}
+		return db, nil
 	}
 
 	dbMutex.Lock()
 	defer dbMutex.Unlock()
 
 	// Double check after lock
-	db, ok = dbConnections[dbName]
-	if ok {
-		if err := db.Ping(); err == nil {
-			return db, nil
-		}
+	if db, ok = dbConnections[dbName]; ok {
+		return db, nil
 	}
 
-	connStr := fmt.Sprintf("host=%s port=%d user=%s dbname=%s sslmode=disable", cfg.DBHost, cfg.DBPort, cfg.DBUser, dbName)
-	slog.Info("Creating new connection pool", "database", dbName)
+	hosts := cfg.DBHosts
+	if len(hosts) == 0 {
+		hosts = []string{cfg.DBHost}
+	}
 
-	newDB, err := sql.Open("postgres", connStr)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open DB connection for %s: %w", dbName, err)
+	// Prefer the host that was most recently confirmed as primary.
+	primaryMutex.RLock()
+	if primaryHost != "" {
+		ordered := []string{primaryHost}
+		for _, h := range hosts {
+			if h != primaryHost {
+				ordered = append(ordered, h)
+			}
+		}
+		hosts = ordered
 	}
+	primaryMutex.RUnlock()
+
+	var lastErr error
+	for _, host := range hosts {
+		hostName, port := hostPort(host, cfg.DBPort)
+		connStr := ConnString(cfg, dbName, hostName, port)
+		slog.Info("Creating new connection pool", "host", host, "database", dbName)
+
+		newDB, err := sql.Open("postgres", connStr)
+		if err != nil {
+			lastErr = fmt.Errorf("failed to open DB connection for %s: %w", dbName, err)
+			continue
+		}
+
+		if err = newDB.Ping(); err != nil {
+			newDB.Close()
+			lastErr = fmt.Errorf("DB ping failed for %s on %s: %w", dbName, host, err)
+			continue
+		}
+
+		// With a single host there is nothing to fail over to, so skip the
+		// recovery check and accept whatever we connected to.
+		if len(hosts) > 1 {
+			var inRecovery bool
+			if err := newDB.QueryRow("SELECT pg_is_in_recovery()").Scan(&inRecovery); err != nil {
+				newDB.Close()
+				lastErr = fmt.Errorf("recovery check failed for %s on %s: %w", dbName, host, err)
+				continue
+			}
+			if inRecovery {
+				newDB.Close()
+				lastErr = fmt.Errorf("host %s is in recovery (standby), not a writable primary", host)
+				slog.Info("Skipping standby host", "host", host, "database", dbName)
+				continue
+			}
+		}
+
+		primaryMutex.Lock()
+		primaryHost = host
+		primaryMutex.Unlock()
 
-	if err = newDB.Ping(); err != nil {
-		return nil, fmt.Errorf("DB ping failed for %s: %w", dbName, err)
+		dbConnections[dbName] = newDB
+		slog.Info("Successfully connected to database", "host", host, "database", dbName)
+		return newDB, nil
 	}
 
-	dbConnections[dbName] = newDB
-	slog.Info("Successfully connected to database", "database", dbName)
-	return newDB, nil
+	return nil, fmt.Errorf("no usable primary host for %s: %w", dbName, lastErr)
 }
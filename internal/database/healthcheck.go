@@ -0,0 +1,90 @@
+package database
+
+import (
+	"context"
+	"log/slog"
+	"math/rand"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Background pool health checking: instead of pinging on every request —
+// which used to add a round-trip to every API call — a single goroutine
+// probes each cached pool on a jittered interval, evicts broken pools (the
+// next request rebuilds them, following the primary across a failover) and
+// exports the outcome as a metric.
+
+const healthCheckInterval = 15 * time.Second
+
+var poolHealthy = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "pgarachne_db_pool_healthy",
+	Help: "Whether the last background health check of the pool succeeded (replica pools are prefixed 'replica:').",
+}, []string{"database"})
+
+// StartHealthChecker launches the checker goroutine and returns a function
+// that stops it.
+func StartHealthChecker() (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		for {
+			// Jitter (±20%) so multiple instances don't probe in lockstep.
+			interval := healthCheckInterval/5*4 + time.Duration(rand.Int63n(int64(healthCheckInterval/5*2)))
+			select {
+			case <-done:
+				return
+			case <-time.After(interval):
+				checkPools()
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// checkPools pings every cached pool and evicts the ones that fail.
+func checkPools() {
+	type pool struct {
+		name    string
+		db      interface{ PingContext(context.Context) error }
+		replica bool
+	}
+	var pools []pool
+
+	dbMutex.RLock()
+	for name, db := range dbConnections {
+		pools = append(pools, pool{name: name, db: db})
+	}
+	dbMutex.RUnlock()
+	replicaMutex.RLock()
+	for name, db := range replicaConnections {
+		pools = append(pools, pool{name: name, db: db, replica: true})
+	}
+	replicaMutex.RUnlock()
+
+	for _, p := range pools {
+		label := p.name
+		if p.replica {
+			label = "replica:" + p.name
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		err := p.db.PingContext(ctx)
+		cancel()
+		if err == nil {
+			poolHealthy.WithLabelValues(label).Set(1)
+			continue
+		}
+		poolHealthy.WithLabelValues(label).Set(0)
+		slog.Warn("Pool health check failed, evicting pool", "database", label, "error", err)
+		if p.replica {
+			replicaMutex.Lock()
+			if db, ok := replicaConnections[p.name]; ok {
+				db.Close()
+				delete(replicaConnections, p.name)
+			}
+			replicaMutex.Unlock()
+		} else {
+			ClosePool(p.name)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package database
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Prometheus export of the driver's sql.DBStats per pool. A collector (rather
+// than periodically-set gauges) reads the stats at scrape time, so the
+// numbers are exact for the scrape and dropped pools disappear from the
+// output. The admin API exposes the same data as JSON under /admin/pools.
+
+var (
+	poolOpenDesc = prometheus.NewDesc("pgarachne_db_pool_open_connections",
+		"Open connections in the pool (in use plus idle).", []string{"database"}, nil)
+	poolInUseDesc = prometheus.NewDesc("pgarachne_db_pool_in_use_connections",
+		"Connections currently in use.", []string{"database"}, nil)
+	poolIdleDesc = prometheus.NewDesc("pgarachne_db_pool_idle_connections",
+		"Idle connections in the pool.", []string{"database"}, nil)
+	poolMaxDesc = prometheus.NewDesc("pgarachne_db_pool_max_open_connections",
+		"Configured maximum number of open connections.", []string{"database"}, nil)
+	poolWaitCountDesc = prometheus.NewDesc("pgarachne_db_pool_wait_count_total",
+		"Total number of times a request waited for a connection.", []string{"database"}, nil)
+	poolWaitDurationDesc = prometheus.NewDesc("pgarachne_db_pool_wait_duration_seconds_total",
+		"Total time spent waiting for a connection.", []string{"database"}, nil)
+)
+
+type poolStatsCollector struct{}
+
+func (poolStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- poolOpenDesc
+	ch <- poolInUseDesc
+	ch <- poolIdleDesc
+	ch <- poolMaxDesc
+	ch <- poolWaitCountDesc
+	ch <- poolWaitDurationDesc
+}
+
+func (poolStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	for name, stats := range PoolStats() {
+		ch <- prometheus.MustNewConstMetric(poolOpenDesc, prometheus.GaugeValue, float64(stats.OpenConnections), name)
+		ch <- prometheus.MustNewConstMetric(poolInUseDesc, prometheus.GaugeValue, float64(stats.InUse), name)
+		ch <- prometheus.MustNewConstMetric(poolIdleDesc, prometheus.GaugeValue, float64(stats.Idle), name)
+		ch <- prometheus.MustNewConstMetric(poolMaxDesc, prometheus.GaugeValue, float64(stats.MaxOpenConnections), name)
+		ch <- prometheus.MustNewConstMetric(poolWaitCountDesc, prometheus.CounterValue, float64(stats.WaitCount), name)
+		ch <- prometheus.MustNewConstMetric(poolWaitDurationDesc, prometheus.CounterValue, stats.WaitDuration.Seconds(), name)
+	}
+}
+
+func init() {
+	prometheus.MustRegister(poolStatsCollector{})
+}
@@ -0,0 +1,83 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+
+	"github.com/yourusername/pgarachne/internal/config"
+)
+
+var (
+	replicaConnections = make(map[string]*sql.DB)
+	replicaMutex       = &sync.RWMutex{}
+	replicaCounter     uint64
+)
+
+// GetReplicaConnection returns a connection to one of the configured read
+// replicas (DB_REPLICA_HOSTS) for a specific database, rotating between hosts
+// round-robin. If no replica is reachable, it falls back to the primary so
+// read-only calls keep working during replica outages.
+func GetReplicaConnection(cfg *config.Config, dbName string) (*sql.DB, error) {
+	if len(cfg.DBReplicaHosts) == 0 {
+		return GetConnection(cfg, dbName)
+	}
+
+	// Round-robin starting point; we try each host once before giving up.
+	start := atomic.AddUint64(&replicaCounter, 1)
+	for i := 0; i < len(cfg.DBReplicaHosts); i++ {
+		host := cfg.DBReplicaHosts[(start+uint64(i))%uint64(len(cfg.DBReplicaHosts))]
+		db, err := getReplicaPool(cfg, host, dbName)
+		if err != nil {
+			slog.Warn("Replica unavailable, trying next", "host", host, "database", dbName, "error", err)
+			continue
+		}
+		return db, nil
+	}
+
+	slog.Warn("All replicas unavailable, falling back to primary", "database", dbName)
+	return GetConnection(cfg, dbName)
+}
+
+func getReplicaPool(cfg *config.Config, host, dbName string) (*sql.DB, error) {
+	key := host + "/" + dbName
+
+	// As with the primary pools, cached replicas skip the per-request ping;
+	// the background health checker evicts broken ones.
+	replicaMutex.RLock()
+	db, ok := replicaConnections[key]
+	replicaMutex.RUnlock()
+	if ok {
+		return db, nil
+	}
+
+	replicaMutex.Lock()
+	defer replicaMutex.Unlock()
+
+	// Double check after lock
+	if db, ok = replicaConnections[key]; ok {
+		return db, nil
+	}
+
+	// Host entries may carry an explicit port ("host:port"); otherwise the
+	// primary DB_PORT applies.
+	hostName, port := hostPort(host, cfg.DBPort)
+
+	connStr := ConnString(cfg, dbName, hostName, port)
+	slog.Info("Creating new replica connection pool", "host", host, "database", dbName)
+
+	newDB, err := sql.Open("postgres", connStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open replica connection for %s: %w", key, err)
+	}
+
+	if err = newDB.Ping(); err != nil {
+		newDB.Close()
+		return nil, fmt.Errorf("replica ping failed for %s: %w", key, err)
+	}
+
+	replicaConnections[key] = newDB
+	return newDB, nil
+}
@@ -0,0 +1,120 @@
+// Package leader provides advisory-lock-based leader election so background
+// subsystems — webhook dispatchers, schedulers — run on exactly one instance
+// of a multi-instance deployment while the others stand by. The leader holds
+// a session-level pg_advisory_lock on a dedicated connection; losing the
+// connection releases the lock and lets a standby take over.
+package leader
+
+import (
+	"context"
+	"database/sql"
+	"hash/fnv"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// retryInterval is how often a standby retries the lock and how often the
+// leader verifies its connection is still alive.
+const retryInterval = 15 * time.Second
+
+// Elector competes for one named leadership slot.
+type Elector struct {
+	db     *sql.DB
+	name   string
+	key    int64
+	leader atomic.Bool
+	done   chan struct{}
+
+	// mu guards conn, which Stop releases from the caller's goroutine
+	// while the Run goroutine may be mid-attempt on it.
+	mu   sync.Mutex
+	conn *sql.Conn
+}
+
+// New builds an elector for the named subsystem; the advisory lock key is
+// derived from the name, so every instance competing under the same name
+// contends for the same lock.
+func New(db *sql.DB, name string) *Elector {
+	h := fnv.New64a()
+	h.Write([]byte("pgarachne/leader/" + name))
+	return &Elector{
+		db:   db,
+		name: name,
+		key:  int64(h.Sum64()),
+		done: make(chan struct{}),
+	}
+}
+
+// Run competes for the lock until Stop; call it in a goroutine.
+func (e *Elector) Run() {
+	ticker := time.NewTicker(retryInterval)
+	defer ticker.Stop()
+	e.attempt()
+	for {
+		select {
+		case <-e.done:
+			return
+		case <-ticker.C:
+			e.attempt()
+		}
+	}
+}
+
+// attempt tries to become leader, or checks that an existing leadership
+// still holds (the lock lives and dies with its session).
+func (e *Elector) attempt() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.leader.Load() {
+		if err := e.conn.PingContext(ctx); err != nil {
+			slog.Warn("Leadership lost with the connection", "name", e.name, "error", err)
+			e.resignLocked()
+		}
+		return
+	}
+
+	conn, err := e.db.Conn(ctx)
+	if err != nil {
+		return
+	}
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, e.key).Scan(&acquired); err != nil || !acquired {
+		conn.Close()
+		return
+	}
+	e.conn = conn
+	e.leader.Store(true)
+	slog.Info("Acquired leadership", "name", e.name)
+}
+
+// resign drops leadership; closing the connection releases the session lock.
+func (e *Elector) resign() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.resignLocked()
+}
+
+func (e *Elector) resignLocked() {
+	e.leader.Store(false)
+	if e.conn != nil {
+		e.conn.Close()
+		e.conn = nil
+	}
+}
+
+// IsLeader reports whether this instance currently holds the lock.
+func (e *Elector) IsLeader() bool {
+	return e.leader.Load()
+}
+
+// Stop ends the election loop and releases the lock if held.
+func (e *Elector) Stop() {
+	close(e.done)
+	e.resign()
+}
@@ -0,0 +1,153 @@
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Hook describes a managed subsystem (HTTP listener, worker, pool, ...).
+// Start must not block: long-running work belongs in a goroutine, which can
+// report a fatal error back via Manager.Fail. Stop is called during shutdown
+// with a context carrying the per-subsystem timeout.
+type Hook struct {
+	Name  string
+	Start func() error
+	Stop  func(ctx context.Context) error
+}
+
+// Running managers register themselves so external control surfaces (e.g.
+// the Windows service handler) can request shutdown without holding a
+// reference to the manager.
+var (
+	managersMu sync.Mutex
+	managers   []*Manager
+)
+
+// RequestGlobalShutdown asks every running manager to shut down cleanly.
+func RequestGlobalShutdown(reason string) {
+	managersMu.Lock()
+	defer managersMu.Unlock()
+	for _, m := range managers {
+		m.RequestShutdown(reason)
+	}
+}
+
+// Manager starts registered subsystems in order and stops them in reverse
+// order on shutdown. Shutdown is triggered by SIGINT/SIGTERM or by any
+// subsystem reporting a fatal error through Fail.
+type Manager struct {
+	hooks       []Hook
+	stopTimeout time.Duration
+	failed      chan error
+	shutdown    chan string
+}
+
+func NewManager(stopTimeout time.Duration) *Manager {
+	return &Manager{
+		stopTimeout: stopTimeout,
+		failed:      make(chan error, 1),
+		shutdown:    make(chan string, 1),
+	}
+}
+
+// RequestShutdown triggers a clean shutdown as if a termination signal had
+// been received (used e.g. by the zero-downtime upgrade handler after the
+// replacement process has taken over).
+func (m *Manager) RequestShutdown(reason string) {
+	select {
+	case m.shutdown <- reason:
+	default:
+	}
+}
+
+// Register adds a subsystem. Registration order is start order; shutdown runs
+// in the reverse order so dependents stop before their dependencies.
+func (m *Manager) Register(hook Hook) {
+	m.hooks = append(m.hooks, hook)
+}
+
+// Fail signals a fatal subsystem error, triggering shutdown of everything
+// else. Safe to call from any goroutine; only the first failure is kept.
+func (m *Manager) Fail(name string, err error) {
+	select {
+	case m.failed <- fmt.Errorf("subsystem %s failed: %w", name, err):
+	default:
+	}
+}
+
+// Run starts all subsystems, blocks until a shutdown trigger, then stops them.
+// It returns the subsystem failure (if that caused the shutdown) or the first
+// error encountered while stopping.
+func (m *Manager) Run() error {
+	managersMu.Lock()
+	managers = append(managers, m)
+	managersMu.Unlock()
+	defer func() {
+		managersMu.Lock()
+		for i, registered := range managers {
+			if registered == m {
+				managers = append(managers[:i], managers[i+1:]...)
+				break
+			}
+		}
+		managersMu.Unlock()
+	}()
+
+	started := 0
+	for _, hook := range m.hooks {
+		slog.Debug("Starting subsystem", "name", hook.Name)
+		if err := hook.Start(); err != nil {
+			m.stop(started)
+			return fmt.Errorf("failed to start subsystem %s: %w", hook.Name, err)
+		}
+		started++
+	}
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+
+	var runErr error
+	select {
+	case sig := <-quit:
+		slog.Info("Shutting down server...", "signal", sig.String())
+	case reason := <-m.shutdown:
+		slog.Info("Shutting down server...", "reason", reason)
+	case err := <-m.failed:
+		slog.Error("Shutting down after subsystem failure", "error", err)
+		runErr = err
+	}
+
+	if stopErr := m.stop(started); runErr == nil {
+		runErr = stopErr
+	}
+	return runErr
+}
+
+// stop shuts down the first n registered subsystems in reverse order, giving
+// each one stopTimeout to finish. It keeps going past individual failures so
+// every subsystem gets a chance to clean up.
+func (m *Manager) stop(n int) error {
+	var firstErr error
+	for i := n - 1; i >= 0; i-- {
+		hook := m.hooks[i]
+		if hook.Stop == nil {
+			continue
+		}
+		slog.Debug("Stopping subsystem", "name", hook.Name)
+		ctx, cancel := context.WithTimeout(context.Background(), m.stopTimeout)
+		if err := hook.Stop(ctx); err != nil {
+			slog.Error("Subsystem shutdown failed", "name", hook.Name, "error", err)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("failed to stop subsystem %s: %w", hook.Name, err)
+			}
+		}
+		cancel()
+	}
+	return firstErr
+}
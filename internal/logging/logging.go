@@ -0,0 +1,65 @@
+// Package logging owns the process-wide slog setup so the level (and later
+// the output) can be adjusted at runtime, e.g. during a SIGHUP config reload.
+package logging
+
+import (
+	"log/slog"
+	"os"
+
+	"github.com/yourusername/pgarachne/internal/config"
+)
+
+// level backs every handler built here, so SetLevel takes effect immediately
+// across the whole process.
+var level slog.LevelVar
+
+// parseLevel maps the LOG_LEVEL config values onto slog levels.
+func parseLevel(levelStr string) slog.Level {
+	switch levelStr {
+	case "DEBUG":
+		return slog.LevelDebug
+	case "WARN":
+		return slog.LevelWarn
+	case "ERROR":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// Setup builds the configured handler (stdout or file) and installs it as
+// the process default.
+func Setup(cfg *config.Config) error {
+	level.Set(parseLevel(cfg.LogLevel))
+	SetRedactKeys(cfg.LogRedactKeys)
+
+	handlerOptions := &slog.HandlerOptions{Level: &level, ReplaceAttr: redactAttr}
+
+	var handler slog.Handler
+	if cfg.LogOutput != "stdout" {
+		w, err := newRotatingWriter(cfg.LogOutput, cfg.LogMaxSizeMB, cfg.LogMaxBackups, cfg.LogMaxAgeDays, cfg.LogCompress)
+		if err != nil {
+			return err
+		}
+		activeWriter = w
+		watchReopenSignal()
+		handler = slog.NewJSONHandler(w, handlerOptions)
+	} else {
+		handler = slog.NewJSONHandler(os.Stdout, handlerOptions)
+	}
+
+	if cfg.LogDebugSample > 1 {
+		handler = &samplingHandler{Handler: handler, sampleRate: uint64(cfg.LogDebugSample)}
+	}
+
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+// activeWriter is the rotating file writer, when file logging is configured.
+var activeWriter *rotatingWriter
+
+// SetLevel changes the level of the running handler without rebuilding it.
+func SetLevel(levelStr string) {
+	level.Set(parseLevel(levelStr))
+}
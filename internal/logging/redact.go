@@ -0,0 +1,112 @@
+// Sensitive data redaction: parameter fields named in LOG_REDACT_KEYS are
+// blanked in every slog record before it reaches a handler, and string
+// values that look like payment card numbers are masked wherever they
+// appear. This makes DEBUG logging safe to enable in production — login
+// bodies and tokens never land in a log file in the clear.
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"sync/atomic"
+)
+
+// redactKeys is the lowercase set of field names to blank; installed once
+// at Setup and shared with the audit log.
+var redactKeys map[string]struct{}
+
+// cardPattern matches 13-19 digit runs with optional space/dash grouping —
+// the shape of a payment card number (PAN).
+var cardPattern = regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`)
+
+// SetRedactKeys installs the redaction key set.
+func SetRedactKeys(keys []string) {
+	set := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		set[key] = struct{}{}
+	}
+	redactKeys = set
+}
+
+// Redacted reports whether a field with this name must be blanked.
+func Redacted(key string) bool {
+	_, hidden := redactKeys[key]
+	return hidden
+}
+
+// MaskCardNumbers replaces card-number-shaped digit runs, keeping the last
+// four digits for correlation.
+func MaskCardNumbers(value string) string {
+	return cardPattern.ReplaceAllStringFunc(value, func(match string) string {
+		return "[card-**" + match[len(match)-4:] + "]"
+	})
+}
+
+// RedactValue walks a decoded JSON value and returns a redacted copy:
+// configured keys are blanked and card numbers in strings are masked. The
+// input is never modified.
+func RedactValue(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(typed))
+		for key, nested := range typed {
+			if Redacted(key) {
+				redacted[key] = "[redacted]"
+			} else {
+				redacted[key] = RedactValue(nested)
+			}
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(typed))
+		for i, nested := range typed {
+			redacted[i] = RedactValue(nested)
+		}
+		return redacted
+	case string:
+		return MaskCardNumbers(typed)
+	default:
+		return value
+	}
+}
+
+// redactAttr is the slog ReplaceAttr hook applying the same rules to every
+// log record.
+func redactAttr(_ []string, a slog.Attr) slog.Attr {
+	if Redacted(a.Key) {
+		a.Value = slog.StringValue("[redacted]")
+		return a
+	}
+	switch a.Value.Kind() {
+	case slog.KindString:
+		a.Value = slog.StringValue(MaskCardNumbers(a.Value.String()))
+	case slog.KindAny:
+		a.Value = slog.AnyValue(RedactValue(a.Value.Any()))
+	}
+	return a
+}
+
+// samplingHandler passes 1 in every sampleRate DEBUG records through to the
+// wrapped handler, so high-volume DEBUG logging keeps a representative
+// trace without flooding the output. Other levels are never sampled.
+type samplingHandler struct {
+	slog.Handler
+	sampleRate uint64
+	counter    atomic.Uint64
+}
+
+func (h *samplingHandler) Handle(ctx context.Context, record slog.Record) error {
+	if record.Level <= slog.LevelDebug && h.counter.Add(1)%h.sampleRate != 1 {
+		return nil
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *samplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &samplingHandler{Handler: h.Handler.WithAttrs(attrs), sampleRate: h.sampleRate}
+}
+
+func (h *samplingHandler) WithGroup(name string) slog.Handler {
+	return &samplingHandler{Handler: h.Handler.WithGroup(name), sampleRate: h.sampleRate}
+}
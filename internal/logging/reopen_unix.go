@@ -0,0 +1,34 @@
+//go:build !windows
+
+package logging
+
+import (
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+var watchOnce sync.Once
+
+// watchReopenSignal reopens the log file on SIGUSR1, the convention external
+// logrotate configurations expect.
+func watchReopenSignal() {
+	watchOnce.Do(func() {
+		reopen := make(chan os.Signal, 1)
+		signal.Notify(reopen, syscall.SIGUSR1)
+		go func() {
+			for range reopen {
+				if activeWriter == nil {
+					continue
+				}
+				if err := activeWriter.Reopen(); err != nil {
+					slog.Error("Failed to reopen log file", "error", err)
+					continue
+				}
+				slog.Info("Log file reopened")
+			}
+		}()
+	})
+}
@@ -0,0 +1,6 @@
+//go:build windows
+
+package logging
+
+// SIGUSR1 does not exist on Windows; size-based rotation still applies.
+func watchReopenSignal() {}
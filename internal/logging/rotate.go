@@ -0,0 +1,171 @@
+package logging
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rotatingWriter writes to a log file and rotates it when it exceeds the
+// configured size: the current file is renamed with a timestamp suffix,
+// optionally gzipped, and old backups are pruned by count and age. Reopen
+// supports external logrotate setups (SIGUSR1).
+type rotatingWriter struct {
+	path       string
+	maxBytes   int64
+	maxBackups int
+	maxAgeDays int
+	compress   bool
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+func newRotatingWriter(path string, maxSizeMB, maxBackups, maxAgeDays int, compress bool) (*rotatingWriter, error) {
+	w := &rotatingWriter{
+		path:       path,
+		maxBytes:   int64(maxSizeMB) * 1024 * 1024,
+		maxBackups: maxBackups,
+		maxAgeDays: maxAgeDays,
+		compress:   compress,
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *rotatingWriter) open() error {
+	file, err := os.OpenFile(w.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file %s: %w", w.path, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	w.file = file
+	w.size = info.Size()
+	return nil
+}
+
+func (w *rotatingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.maxBytes > 0 && w.size+int64(len(p)) > w.maxBytes {
+		if err := w.rotate(); err != nil {
+			// Rotation failing must not lose log lines; keep writing to the
+			// oversized file and report the problem once on stderr.
+			fmt.Fprintf(os.Stderr, "log rotation failed: %v\n", err)
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// rotate renames the active file aside and reopens a fresh one. Callers must
+// hold w.mu.
+func (w *rotatingWriter) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	backupPath := fmt.Sprintf("%s.%s", w.path, time.Now().Format("20060102-150405"))
+	if err := os.Rename(w.path, backupPath); err != nil {
+		// Reopen the original regardless, so logging continues.
+		w.open()
+		return err
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+
+	// Compression and pruning happen off the logging hot path.
+	go func() {
+		if w.compress {
+			if err := gzipFile(backupPath); err != nil {
+				slog.Warn("Failed to compress rotated log", "file", backupPath, "error", err)
+			}
+		}
+		w.prune()
+	}()
+	return nil
+}
+
+// Reopen closes and reopens the log file under its configured name, for
+// logrotate-style external rotation.
+func (w *rotatingWriter) Reopen() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	return w.open()
+}
+
+func gzipFile(path string) error {
+	source, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	target, err := os.Create(path + ".gz")
+	if err != nil {
+		return err
+	}
+	defer target.Close()
+
+	zw := gzip.NewWriter(target)
+	if _, err := io.Copy(zw, source); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// prune applies the count and age retention limits to rotated backups.
+func (w *rotatingWriter) prune() {
+	matches, err := filepath.Glob(w.path + ".*")
+	if err != nil {
+		return
+	}
+
+	// Oldest first; the timestamp suffix sorts chronologically.
+	var backups []string
+	for _, match := range matches {
+		if strings.HasSuffix(match, ".gz") || len(match) > len(w.path)+1 {
+			backups = append(backups, match)
+		}
+	}
+	sort.Strings(backups)
+
+	if w.maxBackups > 0 && len(backups) > w.maxBackups {
+		for _, path := range backups[:len(backups)-w.maxBackups] {
+			os.Remove(path)
+		}
+		backups = backups[len(backups)-w.maxBackups:]
+	}
+
+	if w.maxAgeDays > 0 {
+		cutoff := time.Now().AddDate(0, 0, -w.maxAgeDays)
+		for _, path := range backups {
+			if info, err := os.Stat(path); err == nil && info.ModTime().Before(cutoff) {
+				os.Remove(path)
+			}
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Minimal five-field cron parser (minute hour day-of-month month
+// day-of-week) supporting "*", "*/n", ranges "a-b", and lists "a,b-c".
+// Matching is minute-granular; that is all the scheduler ticks at.
+
+type cronField struct {
+	values map[int]bool
+	// star marks an unrestricted field ("*", "*/n"); day-of-month and
+	// day-of-week matching depends on which of the two is restricted.
+	star bool
+}
+
+// CronSchedule is a parsed cron expression.
+type CronSchedule struct {
+	minute, hour, dayOfMonth, month, dayOfWeek cronField
+}
+
+// ParseCron parses a standard five-field cron expression.
+func ParseCron(expr string) (*CronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression '%s': expected 5 fields, got %d", expr, len(fields))
+	}
+	// Day-of-week allows 7 as an alias for Sunday, per crontab convention.
+	bounds := [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+	parsed := make([]cronField, 5)
+	for i, field := range fields {
+		f, err := parseCronField(field, bounds[i][0], bounds[i][1])
+		if err != nil {
+			return nil, fmt.Errorf("cron expression '%s': %w", expr, err)
+		}
+		parsed[i] = f
+	}
+	if parsed[4].values[7] {
+		parsed[4].values[0] = true
+		delete(parsed[4].values, 7)
+	}
+	return &CronSchedule{
+		minute:     parsed[0],
+		hour:       parsed[1],
+		dayOfMonth: parsed[2],
+		month:      parsed[3],
+		dayOfWeek:  parsed[4],
+	}, nil
+}
+
+func parseCronField(field string, min, max int) (cronField, error) {
+	star := strings.HasPrefix(field, "*")
+	values := make(map[int]bool)
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if base, stepStr, found := strings.Cut(part, "/"); found {
+			s, err := strconv.Atoi(stepStr)
+			if err != nil || s <= 0 {
+				return cronField{}, fmt.Errorf("invalid step in '%s'", part)
+			}
+			step = s
+			part = base
+		}
+		lo, hi := min, max
+		if part != "*" {
+			if loStr, hiStr, isRange := strings.Cut(part, "-"); isRange {
+				var err1, err2 error
+				lo, err1 = strconv.Atoi(loStr)
+				hi, err2 = strconv.Atoi(hiStr)
+				if err1 != nil || err2 != nil {
+					return cronField{}, fmt.Errorf("invalid range '%s'", part)
+				}
+			} else {
+				v, err := strconv.Atoi(part)
+				if err != nil {
+					return cronField{}, fmt.Errorf("invalid value '%s'", part)
+				}
+				lo, hi = v, v
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return cronField{}, fmt.Errorf("value out of range in '%s' (allowed %d-%d)", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			values[v] = true
+		}
+	}
+	return cronField{values: values, star: star}, nil
+}
+
+// Matches reports whether the schedule fires in the minute containing t.
+func (s *CronSchedule) Matches(t time.Time) bool {
+	day := s.dayOfMonth.values[t.Day()] && s.dayOfWeek.values[int(t.Weekday())]
+	if !s.dayOfMonth.star && !s.dayOfWeek.star {
+		// Standard cron: when both day fields are restricted, the entry
+		// fires whenever either one matches ("0 0 1 * 1" runs on the 1st
+		// and on Mondays).
+		day = s.dayOfMonth.values[t.Day()] || s.dayOfWeek.values[int(t.Weekday())]
+	}
+	return s.minute.values[t.Minute()] &&
+		s.hour.values[t.Hour()] &&
+		s.month.values[int(t.Month())] &&
+		day
+}
@@ -0,0 +1,83 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronErrors(t *testing.T) {
+	tests := []string{
+		"* * * *",       // too few fields
+		"* * * * * *",   // too many fields
+		"60 * * * *",    // minute out of range
+		"* 24 * * *",    // hour out of range
+		"* * 0 * *",     // day-of-month out of range
+		"* * * 13 *",    // month out of range
+		"* * * * abc",   // not a number
+		"5-1 * * * *",   // inverted range
+		"*/0 * * * *",   // zero step
+		"*/x * * * *",   // invalid step
+		"1-abc * * * *", // invalid range bound
+		"1,,2 * * * *",  // empty list entry
+		"",              // empty expression
+	}
+	for _, expr := range tests {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("ParseCron(%q) accepted an invalid expression", expr)
+		}
+	}
+}
+
+func TestCronMatches(t *testing.T) {
+	// 2024-03-05 10:30 UTC is a Tuesday.
+	at := func(minute, hour int) time.Time {
+		return time.Date(2024, 3, 5, hour, minute, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		expr string
+		time time.Time
+		want bool
+	}{
+		{"* * * * *", at(30, 10), true},
+		{"30 10 * * *", at(30, 10), true},
+		{"30 10 * * *", at(31, 10), false},
+		{"30 10 * * *", at(30, 11), false},
+		{"*/15 * * * *", at(30, 10), true},
+		{"*/15 * * * *", at(20, 10), false},
+		{"10-40 * * * *", at(30, 10), true},
+		{"10-20 * * * *", at(30, 10), false},
+		{"5,30,55 * * * *", at(30, 10), true},
+		{"5,55 * * * *", at(30, 10), false},
+		{"10-40/10 * * * *", at(30, 10), true},
+		{"10-40/10 * * * *", at(35, 10), false},
+		{"* * * 3 *", at(30, 10), true},
+		{"* * * 4 *", at(30, 10), false},
+		{"* * * * 2", at(30, 10), true}, // Tuesday
+		{"* * * * 3", at(30, 10), false},
+		{"* * 5 * *", at(30, 10), true},
+		// 7 is an alias for Sunday; 2024-03-10 is a Sunday.
+		{"* * * * 7", time.Date(2024, 3, 10, 10, 30, 0, 0, time.UTC), true},
+		{"* * * * 5-7", time.Date(2024, 3, 10, 10, 30, 0, 0, time.UTC), true},
+		{"* * * * 7", at(30, 10), false},
+		// When both day fields are restricted, either may match.
+		{"30 10 1 * 2", at(30, 10), true},  // Tuesday, not the 1st
+		{"30 10 5 * 1", at(30, 10), true},  // the 5th, not a Monday
+		{"30 10 1 * 1", at(30, 10), false}, // neither
+		// A starred day field leaves the other as the only condition.
+		{"30 10 1 * *", at(30, 10), false},
+		{"30 10 * * 2", at(30, 10), true},
+		// "*/2" counts as unrestricted, so day-of-week stays ANDed even
+		// though the 5th matches the step.
+		{"30 10 */2 * 1", at(30, 10), false},
+	}
+	for _, tt := range tests {
+		schedule, err := ParseCron(tt.expr)
+		if err != nil {
+			t.Errorf("ParseCron(%q) failed: %v", tt.expr, err)
+			continue
+		}
+		if got := schedule.Matches(tt.time); got != tt.want {
+			t.Errorf("%q.Matches(%s) = %v; want %v", tt.expr, tt.time, got, tt.want)
+		}
+	}
+}
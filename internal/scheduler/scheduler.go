@@ -0,0 +1,143 @@
+// Package scheduler runs SQL functions on cron schedules defined in the
+// pgarachne.schedules table. Every instance ticks once a minute; which one
+// actually executes a due run is decided by claiming a row in
+// pgarachne.schedule_runs (UNIQUE on schedule and minute), so exactly one
+// instance wins without any external coordination.
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Scheduler drives the schedules of one database.
+type Scheduler struct {
+	dbName string
+	db     *sql.DB
+	done   chan struct{}
+	// leader, when set, gates ticking to the elected instance; the row
+	// claims still protect against double execution either way.
+	leader func() bool
+}
+
+// SetLeaderCheck restricts ticking to whenever check returns true.
+func (s *Scheduler) SetLeaderCheck(check func() bool) {
+	s.leader = check
+}
+
+func New(db *sql.DB, dbName string) *Scheduler {
+	return &Scheduler{dbName: dbName, db: db, done: make(chan struct{})}
+}
+
+// EnsureTables creates the schedule tables on first use, mirroring how the
+// webhook subsystem bootstraps itself.
+func (s *Scheduler) EnsureTables() error {
+	_, err := s.db.Exec(`
+		CREATE SCHEMA IF NOT EXISTS pgarachne;
+		CREATE TABLE IF NOT EXISTS pgarachne.schedules (
+			id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+			cron TEXT NOT NULL,
+			function TEXT NOT NULL,
+			params JSONB NOT NULL DEFAULT '{}'::jsonb,
+			enabled BOOLEAN NOT NULL DEFAULT TRUE
+		);
+		CREATE TABLE IF NOT EXISTS pgarachne.schedule_runs (
+			id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+			schedule_id BIGINT NOT NULL REFERENCES pgarachne.schedules(id) ON DELETE CASCADE,
+			scheduled_for TIMESTAMPTZ NOT NULL,
+			started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			finished_at TIMESTAMPTZ,
+			status TEXT NOT NULL DEFAULT 'running',
+			error TEXT,
+			UNIQUE (schedule_id, scheduled_for)
+		)`)
+	return err
+}
+
+// Run ticks at the top of every minute until Stop is called.
+func (s *Scheduler) Run() {
+	for {
+		now := time.Now()
+		next := now.Truncate(time.Minute).Add(time.Minute)
+		select {
+		case <-s.done:
+			return
+		case <-time.After(next.Sub(now)):
+			s.tick(next)
+		}
+	}
+}
+
+func (s *Scheduler) Stop() {
+	close(s.done)
+}
+
+// tick evaluates every enabled schedule against the minute and executes the
+// due ones this instance manages to claim.
+func (s *Scheduler) tick(minute time.Time) {
+	if s.leader != nil && !s.leader() {
+		return
+	}
+	rows, err := s.db.Query(`SELECT id, cron, function, params FROM pgarachne.schedules WHERE enabled`)
+	if err != nil {
+		slog.Error("Scheduler failed to read schedules", "database", s.dbName, "error", err)
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		var cronExpr, function string
+		var params []byte
+		if err := rows.Scan(&id, &cronExpr, &function, &params); err != nil {
+			continue
+		}
+		schedule, err := ParseCron(cronExpr)
+		if err != nil {
+			slog.Error("Scheduler skipping schedule with invalid cron", "database", s.dbName, "schedule", id, "error", err)
+			continue
+		}
+		if schedule.Matches(minute) {
+			go s.execute(id, minute, function, params)
+		}
+	}
+}
+
+// execute claims the run (losing the claim means another instance runs it)
+// and calls the function, recording the outcome in schedule_runs.
+func (s *Scheduler) execute(scheduleID int64, minute time.Time, function string, params []byte) {
+	var runID int64
+	err := s.db.QueryRow(`
+		INSERT INTO pgarachne.schedule_runs (schedule_id, scheduled_for)
+		VALUES ($1, $2)
+		ON CONFLICT (schedule_id, scheduled_for) DO NOTHING
+		RETURNING id`, scheduleID, minute).Scan(&runID)
+	if err == sql.ErrNoRows {
+		return // another instance claimed this run
+	}
+	if err != nil {
+		slog.Error("Scheduler failed to claim run", "database", s.dbName, "schedule", scheduleID, "error", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	_, execErr := s.db.ExecContext(ctx, fmt.Sprintf("SELECT %s($1::jsonb)", function), params)
+
+	status, errText := "ok", sql.NullString{}
+	if execErr != nil {
+		status = "error"
+		errText = sql.NullString{String: execErr.Error(), Valid: true}
+		slog.Error("Scheduled function failed", "database", s.dbName, "schedule", scheduleID, "function", function, "error", execErr)
+	} else {
+		slog.Info("Scheduled function completed", "database", s.dbName, "schedule", scheduleID, "function", function)
+	}
+	if _, err := s.db.Exec(
+		`UPDATE pgarachne.schedule_runs SET finished_at = NOW(), status = $1, error = $2 WHERE id = $3`,
+		status, errText, runID,
+	); err != nil {
+		slog.Error("Scheduler failed to record run result", "database", s.dbName, "schedule", scheduleID, "error", err)
+	}
+}
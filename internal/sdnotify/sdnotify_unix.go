@@ -0,0 +1,83 @@
+//go:build !windows
+
+// Package sdnotify implements the small parts of the systemd integration
+// protocol PgArachne needs: sd_notify state messages (Type=notify readiness
+// and watchdog pings) and socket activation (LISTEN_FDS). Everything is a
+// no-op when not running under systemd, so the same binary works everywhere.
+package sdnotify
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// notify sends a state message to the socket systemd passed in NOTIFY_SOCKET.
+func notify(stateMsg string) error {
+	socketPath := os.Getenv("NOTIFY_SOCKET")
+	if socketPath == "" {
+		return nil
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socketPath, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(stateMsg))
+	return err
+}
+
+// Ready reports successful startup (Type=notify).
+func Ready() error { return notify("READY=1") }
+
+// Stopping reports the beginning of shutdown.
+func Stopping() error { return notify("STOPPING=1") }
+
+// Watchdog sends a keep-alive ping.
+func Watchdog() error { return notify("WATCHDOG=1") }
+
+// WatchdogInterval returns the recommended ping interval (half the
+// WatchdogSec configured in the unit) and whether the watchdog is armed for
+// this process.
+func WatchdogInterval() (time.Duration, bool) {
+	usecStr := os.Getenv("WATCHDOG_USEC")
+	if usecStr == "" {
+		return 0, false
+	}
+	if pidStr := os.Getenv("WATCHDOG_PID"); pidStr != "" {
+		if pid, err := strconv.Atoi(pidStr); err != nil || pid != os.Getpid() {
+			return 0, false
+		}
+	}
+	usec, err := strconv.ParseInt(usecStr, 10, 64)
+	if err != nil || usec <= 0 {
+		return 0, false
+	}
+	return time.Duration(usec) * time.Microsecond / 2, true
+}
+
+// Listeners returns the sockets systemd passed via socket activation
+// (fds 3..3+LISTEN_FDS), or nil when none were passed to this process.
+func Listeners() []net.Listener {
+	pidStr := os.Getenv("LISTEN_PID")
+	if pid, err := strconv.Atoi(pidStr); err != nil || pid != os.Getpid() {
+		return nil
+	}
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count < 1 {
+		return nil
+	}
+
+	var listeners []net.Listener
+	for fd := 3; fd < 3+count; fd++ {
+		file := os.NewFile(uintptr(fd), "systemd-socket")
+		listener, err := net.FileListener(file)
+		file.Close()
+		if err != nil {
+			continue
+		}
+		listeners = append(listeners, listener)
+	}
+	return listeners
+}
@@ -0,0 +1,18 @@
+//go:build windows
+
+package sdnotify
+
+import (
+	"net"
+	"time"
+)
+
+// systemd does not exist on Windows; every call is a no-op.
+
+func Ready() error    { return nil }
+func Stopping() error { return nil }
+func Watchdog() error { return nil }
+
+func WatchdogInterval() (time.Duration, bool) { return 0, false }
+
+func Listeners() []net.Listener { return nil }
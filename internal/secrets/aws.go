@@ -0,0 +1,149 @@
+package secrets
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// AWS Secrets Manager over its JSON API with a minimal SigV4 signer, so no
+// SDK dependency is needed. Credentials come from the standard environment
+// (AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, optional AWS_SESSION_TOKEN)
+// plus AWS_REGION. References are the secret name or ARN, optionally with
+// '#<field>' to pull one key out of a JSON-valued secret.
+type awsProvider struct {
+	region       string
+	accessKey    string
+	secretKey    string
+	sessionToken string
+}
+
+func newAWSProvider() (*awsProvider, error) {
+	p := &awsProvider{
+		region:       os.Getenv("AWS_REGION"),
+		accessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if p.region == "" {
+		return nil, fmt.Errorf("SECRETS_PROVIDER=aws requires AWS_REGION")
+	}
+	if p.accessKey == "" || p.secretKey == "" {
+		return nil, fmt.Errorf("SECRETS_PROVIDER=aws requires AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+	return p, nil
+}
+
+func (p *awsProvider) Fetch(ctx context.Context, ref string) (string, error) {
+	secretID, field, _ := strings.Cut(ref, "#")
+
+	payload, err := json.Marshal(map[string]string{"SecretId": secretID})
+	if err != nil {
+		return "", err
+	}
+	host := "secretsmanager." + p.region + ".amazonaws.com"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://"+host+"/", strings.NewReader(string(payload)))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	p.sign(req, payload, time.Now().UTC())
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("secretsmanager returned %s for '%s'", resp.Status, secretID)
+	}
+	var body struct {
+		SecretString string `json:"SecretString"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	if field == "" {
+		return body.SecretString, nil
+	}
+	var fields map[string]string
+	if err := json.Unmarshal([]byte(body.SecretString), &fields); err != nil {
+		return "", fmt.Errorf("secret '%s' is not a JSON object, cannot extract '%s'", secretID, field)
+	}
+	value, ok := fields[field]
+	if !ok {
+		return "", fmt.Errorf("secret '%s' has no field '%s'", secretID, field)
+	}
+	return value, nil
+}
+
+// sign adds the SigV4 Authorization header for the secretsmanager service.
+func (p *awsProvider) sign(req *http.Request, payload []byte, now time.Time) {
+	const service = "secretsmanager"
+	amzDate := now.Format("20060102T150405Z")
+	dateStamp := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if p.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", p.sessionToken)
+	}
+
+	// Canonical headers, lowercase and sorted.
+	headerNames := []string{"content-type", "host", "x-amz-date", "x-amz-target"}
+	if p.sessionToken != "" {
+		// Still sorted: x-amz-security-token < x-amz-target.
+		headerNames = []string{"content-type", "host", "x-amz-date", "x-amz-security-token", "x-amz-target"}
+	}
+	var canonicalHeaders strings.Builder
+	for _, name := range headerNames {
+		value := req.Header.Get(name)
+		if name == "host" {
+			value = req.URL.Host
+		}
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+	signedHeaders := strings.Join(headerNames, ";")
+
+	payloadHash := sha256.Sum256(payload)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		"/",
+		"", // no query string
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := strings.Join([]string{dateStamp, p.region, service, "aws4_request"}, "/")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(requestHash[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+p.secretKey), dateStamp)
+	key = hmacSHA256(key, p.region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		p.accessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
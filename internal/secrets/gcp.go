@@ -0,0 +1,94 @@
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// GCP Secret Manager over its REST API, authenticating with an access token
+// from the GCE/GKE metadata server (i.e. the attached service account). A
+// reference is the version resource name, either in full
+// ("projects/p/secrets/name/versions/latest") or shortened to "name" or
+// "name/3" with GCP_PROJECT supplying the project.
+type gcpProvider struct {
+	project string
+}
+
+func newGCPProvider() (*gcpProvider, error) {
+	return &gcpProvider{project: os.Getenv("GCP_PROJECT")}, nil
+}
+
+const gcpMetadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+
+func (p *gcpProvider) accessToken(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, gcpMetadataTokenURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("metadata server unreachable (not on GCP?): %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("metadata server returned %s", resp.Status)
+	}
+	var body struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	return body.AccessToken, nil
+}
+
+func (p *gcpProvider) Fetch(ctx context.Context, ref string) (string, error) {
+	name := ref
+	if !strings.HasPrefix(name, "projects/") {
+		if p.project == "" {
+			return "", fmt.Errorf("short secret reference '%s' requires GCP_PROJECT", ref)
+		}
+		secret, version, ok := strings.Cut(name, "/")
+		if !ok {
+			version = "latest"
+		}
+		name = fmt.Sprintf("projects/%s/secrets/%s/versions/%s", p.project, secret, version)
+	}
+	token, err := p.accessToken(ctx)
+	if err != nil {
+		return "", err
+	}
+	url := "https://secretmanager.googleapis.com/v1/" + name + ":access"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("secretmanager returned %s for '%s'", resp.Status, name)
+	}
+	var body struct {
+		Payload struct {
+			Data string `json:"data"`
+		} `json:"payload"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(body.Payload.Data)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,100 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// HashiCorp Vault over its HTTP API. Configuration:
+//
+//	VAULT_ADDR        base URL, e.g. https://vault.internal:8200
+//	VAULT_TOKEN       token, or VAULT_TOKEN_FILE for an agent-written sink
+//
+// References name the API path after /v1/ plus the field, separated by '#':
+// for a KV v2 mount that is e.g. "secret/data/pgarachne#jwt_secret".
+type vaultProvider struct {
+	addr      string
+	token     string
+	tokenFile string
+}
+
+func newVaultProvider() (*vaultProvider, error) {
+	p := &vaultProvider{
+		addr:      strings.TrimRight(os.Getenv("VAULT_ADDR"), "/"),
+		token:     os.Getenv("VAULT_TOKEN"),
+		tokenFile: os.Getenv("VAULT_TOKEN_FILE"),
+	}
+	if p.addr == "" {
+		return nil, fmt.Errorf("SECRETS_PROVIDER=vault requires VAULT_ADDR")
+	}
+	if p.token == "" && p.tokenFile == "" {
+		return nil, fmt.Errorf("SECRETS_PROVIDER=vault requires VAULT_TOKEN or VAULT_TOKEN_FILE")
+	}
+	return p, nil
+}
+
+func (p *vaultProvider) currentToken() (string, error) {
+	if p.tokenFile == "" {
+		return p.token, nil
+	}
+	// Re-read per fetch: vault-agent rewrites the sink file on renewal.
+	data, err := os.ReadFile(p.tokenFile)
+	if err != nil {
+		return "", fmt.Errorf("reading VAULT_TOKEN_FILE: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func (p *vaultProvider) Fetch(ctx context.Context, ref string) (string, error) {
+	path, field, ok := strings.Cut(ref, "#")
+	if !ok {
+		return "", fmt.Errorf("vault reference '%s' must be '<path>#<field>'", ref)
+	}
+	token, err := p.currentToken()
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.addr+"/v1/"+strings.TrimLeft(path, "/"), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", token)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault returned %s for '%s'", resp.Status, path)
+	}
+	var body struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	// KV v2 nests the fields one level deeper than KV v1; accept both.
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body.Data, &fields); err != nil {
+		return "", err
+	}
+	if nested, ok := fields["data"]; ok {
+		var inner map[string]json.RawMessage
+		if err := json.Unmarshal(nested, &inner); err == nil && inner != nil {
+			fields = inner
+		}
+	}
+	raw, ok := fields[field]
+	if !ok {
+		return "", fmt.Errorf("vault secret '%s' has no field '%s'", path, field)
+	}
+	var value string
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return "", fmt.Errorf("vault field '%s' is not a string", field)
+	}
+	return value, nil
+}
@@ -0,0 +1,192 @@
+// Package selftest boots the full PgArachne stack in-process and exercises
+// the critical paths (HTTP, login, authenticated function call, notification
+// round-trip) against a target database. It is intended as a post-deploy
+// smoke check: `pgarachne selftest <database> [login] [password]`.
+package selftest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/yourusername/pgarachne/internal/config"
+	"github.com/yourusername/pgarachne/internal/database"
+	"github.com/yourusername/pgarachne/internal/server"
+)
+
+type stepResult struct {
+	name   string
+	passed bool
+	detail string
+}
+
+// Run executes the self-test and returns an error if any step failed.
+// Login and password are optional; without them the authenticated steps are
+// skipped (reported as such, not as failures).
+func Run(cfg *config.Config, dbName, login, password string) error {
+	var results []stepResult
+	record := func(name string, err error, detail string) bool {
+		if err != nil {
+			results = append(results, stepResult{name, false, err.Error()})
+			return false
+		}
+		results = append(results, stepResult{name, true, detail})
+		return true
+	}
+
+	// Boot the full stack on an ephemeral loopback port so the test goes
+	// through the real router, middleware and handlers.
+	srv, err := server.New(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to initialize server: %w", err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return fmt.Errorf("failed to open selftest listener: %w", err)
+	}
+	defer listener.Close()
+	go http.Serve(listener, srv.Router())
+	baseURL := "http://" + listener.Addr().String()
+
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	// Step 1: health endpoint
+	err = checkHealth(client, baseURL)
+	record("http/health", err, "server responds")
+
+	// Step 2: direct database connectivity
+	_, err = database.GetConnection(cfg, dbName)
+	if !record("database/connect", err, "primary reachable") {
+		report(results)
+		return fmt.Errorf("selftest failed")
+	}
+
+	// Step 3: login + authenticated function call
+	if login == "" {
+		results = append(results, stepResult{"auth/login", true, "skipped (no credentials provided)"})
+		results = append(results, stepResult{"auth/function-call", true, "skipped (no credentials provided)"})
+	} else {
+		token, err := doLogin(client, baseURL, dbName, login, password)
+		if record("auth/login", err, "JWT issued") {
+			err = doFunctionCall(client, baseURL, dbName, token)
+			record("auth/function-call", err, "capabilities returned")
+		} else {
+			results = append(results, stepResult{"auth/function-call", false, "skipped (login failed)"})
+		}
+	}
+
+	// Step 4: LISTEN/NOTIFY round-trip
+	err = notifyRoundTrip(cfg, dbName)
+	record("notify/round-trip", err, "notification received")
+
+	failed := report(results)
+	if failed > 0 {
+		return fmt.Errorf("selftest failed: %d of %d steps", failed, len(results))
+	}
+	return nil
+}
+
+func report(results []stepResult) int {
+	failed := 0
+	fmt.Println("\nSelf-test report:")
+	for _, r := range results {
+		status := "PASS"
+		if !r.passed {
+			status = "FAIL"
+			failed++
+		}
+		fmt.Printf("  [%s] %-22s %s\n", status, r.name, r.detail)
+	}
+	fmt.Printf("%d passed, %d failed\n", len(results)-failed, failed)
+	return failed
+}
+
+func checkHealth(client *http.Client, baseURL string) error {
+	resp, err := client.Get(baseURL + "/health")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func doLogin(client *http.Client, baseURL, dbName, login, password string) (string, error) {
+	body, _ := json.Marshal(map[string]string{"login": login, "password": password})
+	resp, err := client.Post(baseURL+"/api/"+dbName+"/login", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("login returned status %d", resp.StatusCode)
+	}
+	var loginResp struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&loginResp); err != nil {
+		return "", err
+	}
+	if loginResp.Token == "" {
+		return "", fmt.Errorf("login response contained no token")
+	}
+	return loginResp.Token, nil
+}
+
+func doFunctionCall(client *http.Client, baseURL, dbName, token string) error {
+	body, _ := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0", "method": "capabilities", "params": map[string]interface{}{}, "id": 1,
+	})
+	req, err := http.NewRequest(http.MethodPost, baseURL+"/api/"+dbName+"/capabilities", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("function call returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// notifyRoundTrip LISTENs on a dedicated channel and NOTIFYs through the
+// regular pool, verifying the LISTEN/NOTIFY path end to end.
+func notifyRoundTrip(cfg *config.Config, dbName string) error {
+	host, port := database.PrimaryAddr(cfg)
+	connStr := database.ConnString(cfg, dbName, host, port)
+
+	listener := pq.NewListener(connStr, time.Second, 10*time.Second, nil)
+	defer listener.Close()
+	if err := listener.Listen("pgarachne_selftest"); err != nil {
+		return fmt.Errorf("LISTEN failed: %w", err)
+	}
+
+	conn, err := database.GetConnection(cfg, dbName)
+	if err != nil {
+		return err
+	}
+	if _, err := conn.Exec("NOTIFY pgarachne_selftest, 'ping'"); err != nil {
+		return fmt.Errorf("NOTIFY failed: %w", err)
+	}
+
+	select {
+	case n := <-listener.Notify:
+		if n == nil {
+			return fmt.Errorf("listener connection lost")
+		}
+		return nil
+	case <-time.After(5 * time.Second):
+		return fmt.Errorf("no notification received within 5s")
+	}
+}
@@ -0,0 +1,153 @@
+package server
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/pgarachne/internal/database"
+	"github.com/yourusername/pgarachne/internal/logging"
+)
+
+// Admin API (/admin, ADMIN_TOKEN-authenticated): runtime introspection and
+// control without restarting the process — pool stats, in-flight requests,
+// dropping a stuck pool, maintenance mode, log level and config reload.
+
+// inflightRequest describes one request currently being handled.
+type inflightRequest struct {
+	ID        uint64    `json:"id"`
+	RequestID string    `json:"request_id,omitempty"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	ClientIP  string    `json:"client_ip"`
+	Started   time.Time `json:"started"`
+}
+
+type inflightTracker struct {
+	mu      sync.Mutex
+	nextID  uint64
+	entries map[uint64]inflightRequest
+}
+
+func newInflightTracker() *inflightTracker {
+	return &inflightTracker{entries: make(map[uint64]inflightRequest)}
+}
+
+func (t *inflightTracker) add(entry inflightRequest) uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.nextID++
+	entry.ID = t.nextID
+	t.entries[entry.ID] = entry
+	return entry.ID
+}
+
+func (t *inflightTracker) remove(id uint64) {
+	t.mu.Lock()
+	delete(t.entries, id)
+	t.mu.Unlock()
+}
+
+func (t *inflightTracker) snapshot() []inflightRequest {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	entries := make([]inflightRequest, 0, len(t.entries))
+	for _, entry := range t.entries {
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// inflightMiddleware records every request for the admin in-flight listing.
+func (s *Server) inflightMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := s.inflight.add(inflightRequest{
+			RequestID: c.GetString("request_id"),
+			Method:    c.Request.Method,
+			Path:      c.Request.URL.Path,
+			ClientIP:  c.ClientIP(),
+			Started:   time.Now(),
+		})
+		defer s.inflight.remove(id)
+		c.Next()
+	}
+}
+
+// maintenanceMiddleware rejects API traffic while maintenance mode is on;
+// health probes and the admin API itself stay reachable.
+func (s *Server) maintenanceMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.maintenance.Load() {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, JSONRPCResponse{
+				Error: &JSONRPCError{Code: -32003, Message: "Server is in maintenance mode"},
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+func (s *Server) registerAdminEndpoints(router *gin.Engine) {
+	if s.Cfg().AdminToken == "" {
+		return
+	}
+
+	admin := router.Group("/admin", s.adminTokenMiddleware())
+
+	admin.GET("/pools", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"pools": database.PoolStats()})
+	})
+
+	admin.DELETE("/pools/:database", func(c *gin.Context) {
+		dbName := c.Param("database")
+		if !database.ClosePool(dbName) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No pool for database " + dbName})
+			return
+		}
+		s.stmts.purge()
+		c.JSON(http.StatusOK, gin.H{"status": "dropped", "database": dbName})
+	})
+
+	admin.GET("/requests", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"requests": s.inflight.snapshot()})
+	})
+
+	admin.POST("/maintenance", func(c *gin.Context) {
+		var body struct {
+			Enabled bool `json:"enabled"`
+		}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Expected {\"enabled\": true|false}"})
+			return
+		}
+		s.maintenance.Store(body.Enabled)
+		reqLogger(c).Warn("Maintenance mode changed", "enabled", body.Enabled)
+		c.JSON(http.StatusOK, gin.H{"maintenance": body.Enabled})
+	})
+
+	admin.PUT("/loglevel", func(c *gin.Context) {
+		var body struct {
+			Level string `json:"level"`
+		}
+		if err := c.ShouldBindJSON(&body); err != nil || body.Level == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Expected {\"level\": \"DEBUG|INFO|WARN|ERROR\"}"})
+			return
+		}
+		logging.SetLevel(body.Level)
+		// Copy and swap: the snapshot behind s.Cfg() is read concurrently
+		// and must never be mutated in place.
+		cfg := *s.Cfg()
+		cfg.LogLevel = body.Level
+		s.cfg.Store(&cfg)
+		c.JSON(http.StatusOK, gin.H{"log_level": body.Level})
+	})
+
+	admin.POST("/reload", func(c *gin.Context) {
+		if err := s.reloadConfig(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"status": "reloaded"})
+	})
+}
@@ -0,0 +1,259 @@
+package server
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Arrow IPC stream output (format=arrow, Accept:
+// application/vnd.apache.arrow.stream) for tabular results, so pandas,
+// Polars and DuckDB clients ingest large result sets without JSON parsing.
+// The stream is one schema message, one record batch and the end-of-stream
+// marker; metadata is emitted through the small builder in flatbuf.go.
+// Columns are typed from the JSON values: float64, bool or utf8 (anything
+// mixed or nested falls back to its JSON text).
+
+// Arrow format constants (MetadataVersion, MessageHeader and Type unions,
+// FloatingPoint precision).
+const (
+	arrowVersionV5       = 4
+	arrowHeaderSchema    = 1
+	arrowHeaderBatch     = 3
+	arrowTypeFloat       = 3
+	arrowTypeUtf8        = 5
+	arrowTypeBool        = 6
+	arrowPrecisionDouble = 2
+)
+
+const (
+	arrowColFloat = iota
+	arrowColBool
+	arrowColUtf8
+)
+
+// arrowColumnTypes infers one Arrow type per column from the values present;
+// columns with no values or mixed types become utf8.
+func arrowColumnTypes(rows []map[string]interface{}, columns []string) []int {
+	types := make([]int, len(columns))
+	for i, column := range columns {
+		colType, seen := arrowColUtf8, false
+		for _, row := range rows {
+			value, present := row[column]
+			if !present || value == nil {
+				continue
+			}
+			var vt int
+			switch value.(type) {
+			case float64:
+				vt = arrowColFloat
+			case bool:
+				vt = arrowColBool
+			default:
+				vt = arrowColUtf8
+			}
+			if !seen {
+				colType, seen = vt, true
+			} else if colType != vt {
+				colType = arrowColUtf8
+			}
+		}
+		types[i] = colType
+	}
+	return types
+}
+
+// arrowSchemaMessage builds the flatbuffer for the schema message.
+func arrowSchemaMessage(columns []string, types []int) []byte {
+	b := newFlatBuilder()
+
+	fieldOffsets := make([]int, len(columns))
+	for i, column := range columns {
+		name := b.createString(column)
+
+		// The per-type tables carry no fields except float's precision.
+		var typeType uint64
+		b.startObject(2)
+		switch types[i] {
+		case arrowColFloat:
+			typeType = arrowTypeFloat
+			b.prependUintSlot(0, arrowPrecisionDouble, 0, 2)
+		case arrowColBool:
+			typeType = arrowTypeBool
+		default:
+			typeType = arrowTypeUtf8
+		}
+		typeTable := b.endObject()
+
+		b.startVector(4, 0, 4)
+		children := b.endVector(0)
+
+		b.startObject(6)
+		b.prependUOffsetSlot(0, name)
+		b.prependUintSlot(1, 1, 0, 1) // nullable
+		b.prependUintSlot(2, typeType, 0, 1)
+		b.prependUOffsetSlot(3, typeTable)
+		b.prependUOffsetSlot(5, children)
+		fieldOffsets[i] = b.endObject()
+	}
+
+	b.startVector(4, len(fieldOffsets), 4)
+	for i := len(fieldOffsets) - 1; i >= 0; i-- {
+		b.prependUOffset(fieldOffsets[i])
+	}
+	fields := b.endVector(len(fieldOffsets))
+
+	b.startObject(2)
+	b.prependUOffsetSlot(1, fields)
+	schema := b.endObject()
+
+	b.startObject(4)
+	b.prependUintSlot(0, arrowVersionV5, 0, 2)
+	b.prependUintSlot(1, arrowHeaderSchema, 0, 1)
+	b.prependUOffsetSlot(2, schema)
+	return b.finish(b.endObject())
+}
+
+// arrowBuffer is one body buffer plus its node bookkeeping.
+type arrowBuffer struct {
+	data []byte
+}
+
+// arrowBitmap packs per-row booleans LSB-first.
+func arrowBitmap(bits []bool) []byte {
+	out := make([]byte, (len(bits)+7)/8)
+	for i, set := range bits {
+		if set {
+			out[i/8] |= 1 << (i % 8)
+		}
+	}
+	return out
+}
+
+// arrowColumnBuffers encodes one column into its validity/data buffers and
+// returns the null count.
+func arrowColumnBuffers(rows []map[string]interface{}, column string, colType int) (buffers []arrowBuffer, nullCount int64) {
+	n := len(rows)
+	valid := make([]bool, n)
+	for i, row := range rows {
+		if value, present := row[column]; present && value != nil {
+			valid[i] = true
+		} else {
+			nullCount++
+		}
+	}
+	validity := arrowBuffer{}
+	if nullCount > 0 {
+		validity.data = arrowBitmap(valid)
+	}
+
+	switch colType {
+	case arrowColFloat:
+		data := make([]byte, 8*n)
+		for i, row := range rows {
+			if v, ok := row[column].(float64); ok {
+				binary.LittleEndian.PutUint64(data[8*i:], math.Float64bits(v))
+			}
+		}
+		return []arrowBuffer{validity, {data}}, nullCount
+	case arrowColBool:
+		bits := make([]bool, n)
+		for i, row := range rows {
+			bits[i], _ = row[column].(bool)
+		}
+		return []arrowBuffer{validity, {arrowBitmap(bits)}}, nullCount
+	default:
+		offsets := make([]byte, 4*(n+1))
+		var data []byte
+		for i, row := range rows {
+			if valid[i] {
+				data = append(data, tabularCell(row[column])...)
+			}
+			binary.LittleEndian.PutUint32(offsets[4*(i+1):], uint32(len(data)))
+		}
+		return []arrowBuffer{validity, {offsets}, {data}}, nullCount
+	}
+}
+
+// arrowBatchMessage builds the record batch metadata and body.
+func arrowBatchMessage(rows []map[string]interface{}, columns []string, types []int) (meta, body []byte) {
+	type node struct{ length, nullCount int64 }
+	type bufref struct{ offset, length int64 }
+
+	var nodes []node
+	var refs []bufref
+	for i, column := range columns {
+		buffers, nullCount := arrowColumnBuffers(rows, column, types[i])
+		nodes = append(nodes, node{int64(len(rows)), nullCount})
+		for _, buf := range buffers {
+			refs = append(refs, bufref{int64(len(body)), int64(len(buf.data))})
+			body = append(body, buf.data...)
+			for len(body)%8 != 0 {
+				body = append(body, 0)
+			}
+		}
+	}
+
+	b := newFlatBuilder()
+
+	b.startVector(16, len(refs), 8)
+	for i := len(refs) - 1; i >= 0; i-- {
+		b.prependUint(uint64(refs[i].length), 8)
+		b.prependUint(uint64(refs[i].offset), 8)
+	}
+	buffersVec := b.endVector(len(refs))
+
+	b.startVector(16, len(nodes), 8)
+	for i := len(nodes) - 1; i >= 0; i-- {
+		b.prependUint(uint64(nodes[i].nullCount), 8)
+		b.prependUint(uint64(nodes[i].length), 8)
+	}
+	nodesVec := b.endVector(len(nodes))
+
+	b.startObject(3)
+	b.prependUintSlot(0, uint64(len(rows)), 0, 8)
+	b.prependUOffsetSlot(1, nodesVec)
+	b.prependUOffsetSlot(2, buffersVec)
+	batch := b.endObject()
+
+	b.startObject(4)
+	b.prependUintSlot(0, arrowVersionV5, 0, 2)
+	b.prependUintSlot(1, arrowHeaderBatch, 0, 1)
+	b.prependUOffsetSlot(2, batch)
+	b.prependUintSlot(3, uint64(len(body)), 0, 8)
+	return b.finish(b.endObject()), body
+}
+
+// writeArrowMessage writes one encapsulated IPC message: continuation
+// marker, metadata length, 8-byte-aligned metadata, then the body.
+func writeArrowMessage(w io.Writer, meta, body []byte) {
+	padded := len(meta)
+	for (8+padded)%8 != 0 {
+		padded++
+	}
+	var prefix [8]byte
+	binary.LittleEndian.PutUint32(prefix[0:], 0xFFFFFFFF)
+	binary.LittleEndian.PutUint32(prefix[4:], uint32(padded))
+	w.Write(prefix[:])
+	w.Write(meta)
+	w.Write(make([]byte, padded-len(meta)))
+	w.Write(body)
+}
+
+// writeArrowStream serves rows as an Arrow IPC stream download.
+func writeArrowStream(c *gin.Context, functionName string, rows []map[string]interface{}, columns []string) {
+	c.Header("Content-Type", "application/vnd.apache.arrow.stream")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.arrows"`, functionName))
+	c.Status(200)
+
+	types := arrowColumnTypes(rows, columns)
+	writeArrowMessage(c.Writer, arrowSchemaMessage(columns, types), nil)
+	meta, body := arrowBatchMessage(rows, columns, types)
+	writeArrowMessage(c.Writer, meta, body)
+
+	// End-of-stream marker.
+	c.Writer.Write([]byte{0xFF, 0xFF, 0xFF, 0xFF, 0, 0, 0, 0})
+}
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/pgarachne/internal/config"
+	"github.com/yourusername/pgarachne/internal/logging"
+)
+
+// Optional audit trail for compliance-sensitive deployments. AUDIT_LOG
+// selects the sink: "db" calls pgarachne.audit() in the target database
+// (outside the request transaction, so failed calls are audited too), any
+// other non-empty value is appended to as a JSON-lines file. Parameter keys
+// listed in AUDIT_REDACT_KEYS are replaced before the entry leaves the
+// process.
+
+type auditEntry struct {
+	Time      time.Time `json:"time"`
+	RequestID string    `json:"request_id,omitempty"`
+	ClientIP  string    `json:"client_ip"`
+	Database  string    `json:"database"`
+	Role      string    `json:"role"`
+	// Impersonator is set when the call ran under X-Impersonate-Role.
+	Impersonator string                 `json:"impersonator,omitempty"`
+	Function     string                 `json:"function"`
+	Params       map[string]interface{} `json:"params,omitempty"`
+	Status       string                 `json:"status"` // "ok" | "error"
+	LatencyMS    int64                  `json:"latency_ms"`
+}
+
+type auditor struct {
+	mode   string // "" (off), "db", or file path
+	redact map[string]struct{}
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+func newAuditor(cfg *config.Config) (*auditor, error) {
+	a := &auditor{mode: cfg.AuditLog, redact: make(map[string]struct{})}
+	for _, key := range cfg.AuditRedactKeys {
+		a.redact[key] = struct{}{}
+	}
+	// The log redaction keys apply to the audit trail as well, so one
+	// setting protects both outputs.
+	for _, key := range cfg.LogRedactKeys {
+		a.redact[key] = struct{}{}
+	}
+	if a.mode != "" && a.mode != "db" {
+		file, err := os.OpenFile(a.mode, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+		if err != nil {
+			return nil, err
+		}
+		a.file = file
+	}
+	return a, nil
+}
+
+func (a *auditor) enabled() bool {
+	return a != nil && a.mode != ""
+}
+
+// redactParams returns a copy of params with configured keys blanked; the
+// caller's map is never modified.
+func (a *auditor) redactParams(params map[string]interface{}) map[string]interface{} {
+	if params == nil {
+		return nil
+	}
+	redacted := make(map[string]interface{}, len(params))
+	for key, value := range params {
+		if _, hidden := a.redact[key]; hidden {
+			redacted[key] = "[redacted]"
+		} else {
+			redacted[key] = logging.RedactValue(value)
+		}
+	}
+	return redacted
+}
+
+// record writes one audit entry. Sink errors are logged, never surfaced to
+// the client: an audit outage must not take the API down with it.
+func (a *auditor) record(c *gin.Context, db *sql.DB, databaseName, role, function string, params map[string]interface{}, status string, started time.Time) {
+	entry := auditEntry{
+		Time:         started,
+		RequestID:    c.GetString("request_id"),
+		ClientIP:     c.ClientIP(),
+		Database:     databaseName,
+		Role:         role,
+		Impersonator: c.GetString("impersonator"),
+		Function:     function,
+		Params:       a.redactParams(params),
+		Status:       status,
+		LatencyMS:    time.Since(started).Milliseconds(),
+	}
+	payload, err := json.Marshal(entry)
+	if err != nil {
+		reqLogger(c).Error("Failed to marshal audit entry", "error", err)
+		return
+	}
+
+	if a.mode == "db" {
+		// Detached from the request: the entry must land even when the
+		// client has already gone away.
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if _, err := db.ExecContext(ctx, `SELECT pgarachne.audit($1::jsonb)`, payload); err != nil {
+				reqLogger(c).Error("Failed to write audit entry to database", "error", err)
+			}
+		}()
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, err := a.file.Write(append(payload, '\n')); err != nil {
+		reqLogger(c).Error("Failed to write audit entry to file", "error", err)
+	}
+}
@@ -0,0 +1,31 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Base path mounting: BASE_PATH moves the whole API under a URL prefix
+// (e.g. /pgarachne) so PgArachne can sit behind path-based ingress routing
+// next to other services. The prefix is stripped before the router matches,
+// so routes, handlers and middleware stay unaware of it; BASE_URL-derived
+// links (static JSON documents) include the prefix automatically.
+
+// basePathHandler strips the configured prefix; requests outside it 404.
+func (s *Server) basePathHandler(next http.Handler) http.Handler {
+	prefix := s.Cfg().BasePath
+	if prefix == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == prefix:
+			http.Redirect(w, r, prefix+"/", http.StatusMovedPermanently)
+		case strings.HasPrefix(r.URL.Path, prefix+"/"):
+			r.URL.Path = strings.TrimPrefix(r.URL.Path, prefix)
+			next.ServeHTTP(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
@@ -0,0 +1,528 @@
+package server
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MessagePack and CBOR encoding for the JSON-RPC envelope, negotiated with
+// Content-Type (requests) and Accept (responses); JSON stays the default.
+// Decoded requests are converted to JSON and fed through the normal binding
+// path, so the parser hardening options keep applying. The codecs below
+// cover the JSON data model — maps, arrays, strings, numbers, booleans,
+// null — which is all the envelope can carry.
+
+// binaryRequestFormat returns "msgpack" or "cbor" when the request body is
+// declared in one of the binary encodings.
+func binaryRequestFormat(c *gin.Context) string {
+	contentType := c.ContentType()
+	switch {
+	case strings.Contains(contentType, "msgpack"):
+		return "msgpack"
+	case contentType == "application/cbor":
+		return "cbor"
+	}
+	return ""
+}
+
+// binaryResponseFormat returns the negotiated binary response encoding, or
+// "" for JSON.
+func binaryResponseFormat(c *gin.Context) string {
+	accept := c.GetHeader("Accept")
+	switch {
+	case strings.Contains(accept, "msgpack"):
+		return "msgpack"
+	case strings.Contains(accept, "application/cbor"):
+		return "cbor"
+	}
+	return ""
+}
+
+// binaryMaxDepth caps container nesting when JSON_MAX_DEPTH is not set. The
+// decoders recurse per level, so this bound is not optional: unbounded
+// nesting (one byte per level) overflows the goroutine stack, which is a
+// fatal, unrecoverable error.
+const binaryMaxDepth = 256
+
+// binaryMaxPrealloc caps how much a declared container length may
+// preallocate; longer containers grow as elements actually arrive, so a
+// forged length cannot allocate memory the body never backs.
+const binaryMaxPrealloc = 1024
+
+// decodeBinaryBody converts a msgpack or CBOR request body to its JSON
+// equivalent. maxDepth bounds container nesting; zero applies the built-in
+// cap.
+func decodeBinaryBody(body []byte, format string, maxDepth int) ([]byte, error) {
+	if maxDepth <= 0 || maxDepth > binaryMaxDepth {
+		maxDepth = binaryMaxDepth
+	}
+	var value interface{}
+	var rest []byte
+	var err error
+	if format == "msgpack" {
+		value, rest, err = msgpackDecode(body, maxDepth)
+	} else {
+		value, rest, err = cborDecode(body, maxDepth)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s body: %w", format, err)
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("invalid %s body: %d trailing bytes", format, len(rest))
+	}
+	return json.Marshal(value)
+}
+
+// writeRPCResponse writes the envelope in the negotiated encoding.
+func writeRPCResponse(c *gin.Context, status int, resp JSONRPCResponse) {
+	format := binaryResponseFormat(c)
+	if format == "" {
+		out, err := jsonMarshal(resp)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, JSONRPCResponse{Error: &JSONRPCError{Message: "Failed to encode response"}})
+			return
+		}
+		c.Data(status, "application/json; charset=utf-8", out)
+		return
+	}
+
+	// Round-trip through JSON so the envelope's RawMessage fields become
+	// plain values the binary encoders understand.
+	encoded, err := jsonMarshal(resp)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, JSONRPCResponse{Error: &JSONRPCError{Message: "Failed to encode response"}})
+		return
+	}
+	dec := json.NewDecoder(bytes.NewReader(encoded))
+	dec.UseNumber()
+	var value interface{}
+	dec.Decode(&value)
+
+	var out []byte
+	contentType := "application/msgpack"
+	if format == "msgpack" {
+		out = msgpackEncode(nil, value)
+	} else {
+		out = cborEncode(nil, value)
+		contentType = "application/cbor"
+	}
+	c.Data(status, contentType, out)
+}
+
+// numberParts splits a json.Number into the integer/float form encoders use.
+func numberParts(n json.Number) (i int64, f float64, isInt bool) {
+	if i, err := n.Int64(); err == nil {
+		return i, 0, true
+	}
+	f, _ = n.Float64()
+	return 0, f, false
+}
+
+// --- MessagePack ---
+
+func msgpackEncode(out []byte, value interface{}) []byte {
+	switch v := value.(type) {
+	case nil:
+		return append(out, 0xc0)
+	case bool:
+		if v {
+			return append(out, 0xc3)
+		}
+		return append(out, 0xc2)
+	case json.Number:
+		if i, f, isInt := numberParts(v); isInt {
+			return msgpackEncodeInt(out, i)
+		} else {
+			return binary.BigEndian.AppendUint64(append(out, 0xcb), math.Float64bits(f))
+		}
+	case float64:
+		return binary.BigEndian.AppendUint64(append(out, 0xcb), math.Float64bits(v))
+	case string:
+		switch {
+		case len(v) < 32:
+			out = append(out, 0xa0|byte(len(v)))
+		case len(v) < 256:
+			out = append(out, 0xd9, byte(len(v)))
+		case len(v) < 65536:
+			out = binary.BigEndian.AppendUint16(append(out, 0xda), uint16(len(v)))
+		default:
+			out = binary.BigEndian.AppendUint32(append(out, 0xdb), uint32(len(v)))
+		}
+		return append(out, v...)
+	case []interface{}:
+		switch {
+		case len(v) < 16:
+			out = append(out, 0x90|byte(len(v)))
+		case len(v) < 65536:
+			out = binary.BigEndian.AppendUint16(append(out, 0xdc), uint16(len(v)))
+		default:
+			out = binary.BigEndian.AppendUint32(append(out, 0xdd), uint32(len(v)))
+		}
+		for _, elem := range v {
+			out = msgpackEncode(out, elem)
+		}
+		return out
+	case map[string]interface{}:
+		switch {
+		case len(v) < 16:
+			out = append(out, 0x80|byte(len(v)))
+		case len(v) < 65536:
+			out = binary.BigEndian.AppendUint16(append(out, 0xde), uint16(len(v)))
+		default:
+			out = binary.BigEndian.AppendUint32(append(out, 0xdf), uint32(len(v)))
+		}
+		for key, elem := range v {
+			out = msgpackEncode(out, key)
+			out = msgpackEncode(out, elem)
+		}
+		return out
+	default:
+		return msgpackEncode(out, fmt.Sprintf("%v", v))
+	}
+}
+
+func msgpackEncodeInt(out []byte, i int64) []byte {
+	switch {
+	case i >= 0 && i < 128:
+		return append(out, byte(i))
+	case i < 0 && i >= -32:
+		return append(out, byte(i))
+	default:
+		return binary.BigEndian.AppendUint64(append(out, 0xd3), uint64(i))
+	}
+}
+
+func msgpackDecode(b []byte, depth int) (interface{}, []byte, error) {
+	if depth <= 0 {
+		return nil, nil, fmt.Errorf("nesting depth exceeds limit")
+	}
+	if len(b) == 0 {
+		return nil, nil, fmt.Errorf("truncated")
+	}
+	tag := b[0]
+	b = b[1:]
+
+	need := func(n int) error {
+		if len(b) < n {
+			return fmt.Errorf("truncated")
+		}
+		return nil
+	}
+	str := func(n int) (interface{}, []byte, error) {
+		if err := need(n); err != nil {
+			return nil, nil, err
+		}
+		return string(b[:n]), b[n:], nil
+	}
+	array := func(n int) (interface{}, []byte, error) {
+		out := make([]interface{}, 0, min(n, binaryMaxPrealloc))
+		for i := 0; i < n; i++ {
+			elem, rest, err := msgpackDecode(b, depth-1)
+			if err != nil {
+				return nil, nil, err
+			}
+			out, b = append(out, elem), rest
+		}
+		return out, b, nil
+	}
+	object := func(n int) (interface{}, []byte, error) {
+		out := make(map[string]interface{}, min(n, binaryMaxPrealloc))
+		for i := 0; i < n; i++ {
+			var key, val interface{}
+			var err error
+			if key, b, err = msgpackDecode(b, depth-1); err != nil {
+				return nil, nil, err
+			}
+			if val, b, err = msgpackDecode(b, depth-1); err != nil {
+				return nil, nil, err
+			}
+			keyStr, ok := key.(string)
+			if !ok {
+				keyStr = fmt.Sprintf("%v", key)
+			}
+			out[keyStr] = val
+		}
+		return out, b, nil
+	}
+
+	switch {
+	case tag < 0x80: // positive fixint
+		return float64(tag), b, nil
+	case tag >= 0xe0: // negative fixint
+		return float64(int8(tag)), b, nil
+	case tag >= 0xa0 && tag < 0xc0:
+		return str(int(tag & 0x1f))
+	case tag >= 0x90 && tag < 0xa0:
+		return array(int(tag & 0x0f))
+	case tag >= 0x80 && tag < 0x90:
+		return object(int(tag & 0x0f))
+	}
+
+	switch tag {
+	case 0xc0:
+		return nil, b, nil
+	case 0xc2:
+		return false, b, nil
+	case 0xc3:
+		return true, b, nil
+	case 0xcc:
+		if err := need(1); err != nil {
+			return nil, nil, err
+		}
+		return float64(b[0]), b[1:], nil
+	case 0xcd:
+		if err := need(2); err != nil {
+			return nil, nil, err
+		}
+		return float64(binary.BigEndian.Uint16(b)), b[2:], nil
+	case 0xce:
+		if err := need(4); err != nil {
+			return nil, nil, err
+		}
+		return float64(binary.BigEndian.Uint32(b)), b[4:], nil
+	case 0xcf:
+		if err := need(8); err != nil {
+			return nil, nil, err
+		}
+		return float64(binary.BigEndian.Uint64(b)), b[8:], nil
+	case 0xd0:
+		if err := need(1); err != nil {
+			return nil, nil, err
+		}
+		return float64(int8(b[0])), b[1:], nil
+	case 0xd1:
+		if err := need(2); err != nil {
+			return nil, nil, err
+		}
+		return float64(int16(binary.BigEndian.Uint16(b))), b[2:], nil
+	case 0xd2:
+		if err := need(4); err != nil {
+			return nil, nil, err
+		}
+		return float64(int32(binary.BigEndian.Uint32(b))), b[4:], nil
+	case 0xd3:
+		if err := need(8); err != nil {
+			return nil, nil, err
+		}
+		return float64(int64(binary.BigEndian.Uint64(b))), b[8:], nil
+	case 0xca:
+		if err := need(4); err != nil {
+			return nil, nil, err
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), b[4:], nil
+	case 0xcb:
+		if err := need(8); err != nil {
+			return nil, nil, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), b[8:], nil
+	case 0xd9:
+		if err := need(1); err != nil {
+			return nil, nil, err
+		}
+		n := int(b[0])
+		b = b[1:]
+		return str(n)
+	case 0xda:
+		if err := need(2); err != nil {
+			return nil, nil, err
+		}
+		n := int(binary.BigEndian.Uint16(b))
+		b = b[2:]
+		return str(n)
+	case 0xdb:
+		if err := need(4); err != nil {
+			return nil, nil, err
+		}
+		n := int(binary.BigEndian.Uint32(b))
+		b = b[4:]
+		return str(n)
+	case 0xdc:
+		if err := need(2); err != nil {
+			return nil, nil, err
+		}
+		n := int(binary.BigEndian.Uint16(b))
+		b = b[2:]
+		return array(n)
+	case 0xdd:
+		if err := need(4); err != nil {
+			return nil, nil, err
+		}
+		n := int(binary.BigEndian.Uint32(b))
+		b = b[4:]
+		return array(n)
+	case 0xde:
+		if err := need(2); err != nil {
+			return nil, nil, err
+		}
+		n := int(binary.BigEndian.Uint16(b))
+		b = b[2:]
+		return object(n)
+	case 0xdf:
+		if err := need(4); err != nil {
+			return nil, nil, err
+		}
+		n := int(binary.BigEndian.Uint32(b))
+		b = b[4:]
+		return object(n)
+	}
+	return nil, nil, fmt.Errorf("unsupported msgpack tag 0x%02x", tag)
+}
+
+// --- CBOR ---
+
+func cborEncode(out []byte, value interface{}) []byte {
+	head := func(major byte, n uint64) []byte {
+		switch {
+		case n < 24:
+			return append(out, major<<5|byte(n))
+		case n < 256:
+			return append(out, major<<5|24, byte(n))
+		case n < 65536:
+			return binary.BigEndian.AppendUint16(append(out, major<<5|25), uint16(n))
+		case n < 1<<32:
+			return binary.BigEndian.AppendUint32(append(out, major<<5|26), uint32(n))
+		default:
+			return binary.BigEndian.AppendUint64(append(out, major<<5|27), n)
+		}
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return append(out, 0xf6)
+	case bool:
+		if v {
+			return append(out, 0xf5)
+		}
+		return append(out, 0xf4)
+	case json.Number:
+		if i, f, isInt := numberParts(v); isInt {
+			if i >= 0 {
+				return head(0, uint64(i))
+			}
+			return head(1, uint64(-1-i))
+		} else {
+			return binary.BigEndian.AppendUint64(append(out, 0xfb), math.Float64bits(f))
+		}
+	case float64:
+		return binary.BigEndian.AppendUint64(append(out, 0xfb), math.Float64bits(v))
+	case string:
+		return append(head(3, uint64(len(v))), v...)
+	case []interface{}:
+		out = head(4, uint64(len(v)))
+		for _, elem := range v {
+			out = cborEncode(out, elem)
+		}
+		return out
+	case map[string]interface{}:
+		out = head(5, uint64(len(v)))
+		for key, elem := range v {
+			out = cborEncode(out, key)
+			out = cborEncode(out, elem)
+		}
+		return out
+	default:
+		return cborEncode(out, fmt.Sprintf("%v", v))
+	}
+}
+
+func cborDecode(b []byte, depth int) (interface{}, []byte, error) {
+	if depth <= 0 {
+		return nil, nil, fmt.Errorf("nesting depth exceeds limit")
+	}
+	if len(b) == 0 {
+		return nil, nil, fmt.Errorf("truncated")
+	}
+	major, info := b[0]>>5, b[0]&0x1f
+	b = b[1:]
+
+	var n uint64
+	switch {
+	case info < 24:
+		n = uint64(info)
+	case info == 24:
+		if len(b) < 1 {
+			return nil, nil, fmt.Errorf("truncated")
+		}
+		n, b = uint64(b[0]), b[1:]
+	case info == 25:
+		if len(b) < 2 {
+			return nil, nil, fmt.Errorf("truncated")
+		}
+		n, b = uint64(binary.BigEndian.Uint16(b)), b[2:]
+	case info == 26:
+		if len(b) < 4 {
+			return nil, nil, fmt.Errorf("truncated")
+		}
+		n, b = uint64(binary.BigEndian.Uint32(b)), b[4:]
+	case info == 27:
+		if len(b) < 8 {
+			return nil, nil, fmt.Errorf("truncated")
+		}
+		n, b = binary.BigEndian.Uint64(b), b[8:]
+	default:
+		return nil, nil, fmt.Errorf("unsupported indefinite-length item")
+	}
+
+	switch major {
+	case 0: // unsigned int
+		return float64(n), b, nil
+	case 1: // negative int
+		return -1 - float64(n), b, nil
+	case 2, 3: // byte / text string
+		if uint64(len(b)) < n {
+			return nil, nil, fmt.Errorf("truncated")
+		}
+		return string(b[:n]), b[n:], nil
+	case 4:
+		out := make([]interface{}, 0, int(min(n, binaryMaxPrealloc)))
+		for i := uint64(0); i < n; i++ {
+			elem, rest, err := cborDecode(b, depth-1)
+			if err != nil {
+				return nil, nil, err
+			}
+			out, b = append(out, elem), rest
+		}
+		return out, b, nil
+	case 5:
+		out := make(map[string]interface{}, int(min(n, binaryMaxPrealloc)))
+		for i := uint64(0); i < n; i++ {
+			var key, val interface{}
+			var err error
+			if key, b, err = cborDecode(b, depth-1); err != nil {
+				return nil, nil, err
+			}
+			if val, b, err = cborDecode(b, depth-1); err != nil {
+				return nil, nil, err
+			}
+			keyStr, ok := key.(string)
+			if !ok {
+				keyStr = fmt.Sprintf("%v", key)
+			}
+			out[keyStr] = val
+		}
+		return out, b, nil
+	case 6: // tag: decode the tagged value, ignore the tag
+		return cborDecode(b, depth-1)
+	default: // simple / float
+		switch info {
+		case 20:
+			return false, b, nil
+		case 21:
+			return true, b, nil
+		case 22, 23:
+			return nil, b, nil
+		case 26:
+			return float64(math.Float32frombits(uint32(n))), b, nil
+		case 27:
+			return math.Float64frombits(n), b, nil
+		}
+		return nil, nil, fmt.Errorf("unsupported simple value %d", info)
+	}
+}
@@ -0,0 +1,172 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// decodeToJSON is the test shorthand for the request-side conversion.
+func decodeToJSON(t *testing.T, body []byte, format string) string {
+	t.Helper()
+	out, err := decodeBinaryBody(body, format, 0)
+	if err != nil {
+		t.Fatalf("decodeBinaryBody(%x, %s) failed: %v", body, format, err)
+	}
+	return string(out)
+}
+
+func TestMsgpackDecode(t *testing.T) {
+	tests := []struct {
+		name string
+		body []byte
+		want string
+	}{
+		{"positive fixint", []byte{0x01}, "1"},
+		{"negative fixint", []byte{0xff}, "-1"},
+		{"nil", []byte{0xc0}, "null"},
+		{"false", []byte{0xc2}, "false"},
+		{"true", []byte{0xc3}, "true"},
+		{"fixstr", []byte{0xa3, 'f', 'o', 'o'}, `"foo"`},
+		{"str8", []byte{0xd9, 0x02, 'h', 'i'}, `"hi"`},
+		{"uint8", []byte{0xcc, 0xff}, "255"},
+		{"uint16", []byte{0xcd, 0x01, 0x00}, "256"},
+		{"int8", []byte{0xd0, 0xff}, "-1"},
+		{"int16", []byte{0xd1, 0xff, 0x00}, "-256"},
+		{"float64", []byte{0xcb, 0x40, 0x0c, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, "3.5"},
+		{"fixarray", []byte{0x92, 0x01, 0x02}, "[1,2]"},
+		{"fixmap", []byte{0x81, 0xa1, 'a', 0x01}, `{"a":1}`},
+		{"nested", []byte{0x81, 0xa1, 'a', 0x91, 0xc3}, `{"a":[true]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := decodeToJSON(t, tt.body, "msgpack"); got != tt.want {
+				t.Errorf("decoded %x to %s; want %s", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCBORDecode(t *testing.T) {
+	tests := []struct {
+		name string
+		body []byte
+		want string
+	}{
+		{"small uint", []byte{0x01}, "1"},
+		{"uint8", []byte{0x18, 0x64}, "100"},
+		{"uint16", []byte{0x19, 0x01, 0x00}, "256"},
+		{"negative", []byte{0x20}, "-1"},
+		{"negative uint8", []byte{0x38, 0x63}, "-100"},
+		{"text", []byte{0x63, 'f', 'o', 'o'}, `"foo"`},
+		{"false", []byte{0xf4}, "false"},
+		{"true", []byte{0xf5}, "true"},
+		{"null", []byte{0xf6}, "null"},
+		{"float64", []byte{0xfb, 0x40, 0x0c, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, "3.5"},
+		{"array", []byte{0x82, 0x01, 0x02}, "[1,2]"},
+		{"map", []byte{0xa1, 0x61, 'a', 0x01}, `{"a":1}`},
+		{"tagged value", []byte{0xc0, 0x61, 'a'}, `"a"`},
+		{"nested", []byte{0xa1, 0x61, 'a', 0x81, 0xf5}, `{"a":[true]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := decodeToJSON(t, tt.body, "cbor"); got != tt.want {
+				t.Errorf("decoded %x to %s; want %s", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBinaryDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		body    []byte
+		wantErr string
+	}{
+		{"msgpack truncated string", "msgpack", []byte{0xa3, 'f'}, "truncated"},
+		{"msgpack truncated array", "msgpack", []byte{0x92, 0x01}, "truncated"},
+		{"msgpack trailing bytes", "msgpack", []byte{0x01, 0x01}, "trailing"},
+		{"msgpack unsupported tag", "msgpack", []byte{0xc1}, "unsupported"},
+		// A forged 4-billion-element header must fail on the missing body,
+		// not allocate the declared length.
+		{"msgpack forged array length", "msgpack", []byte{0xdd, 0xff, 0xff, 0xff, 0xff}, "truncated"},
+		{"cbor truncated", "cbor", []byte{0x63, 'f'}, "truncated"},
+		{"cbor trailing bytes", "cbor", []byte{0x01, 0x01}, "trailing"},
+		{"cbor indefinite length", "cbor", []byte{0x9f}, "indefinite"},
+		{"cbor forged map length", "cbor", []byte{0xbb, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, "truncated"},
+		{"empty body", "msgpack", nil, "truncated"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decodeBinaryBody(tt.body, tt.format, 0)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("decodeBinaryBody(%x) error = %v; want substring %q", tt.body, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBinaryDecodeDepthLimit(t *testing.T) {
+	// [[1]] needs three levels; a cap of 2 must reject it, 3 must allow it.
+	msgpack := []byte{0x91, 0x91, 0x01}
+	if _, err := decodeBinaryBody(msgpack, "msgpack", 2); err == nil {
+		t.Error("depth 2 accepted three levels of msgpack nesting")
+	}
+	if _, err := decodeBinaryBody(msgpack, "msgpack", 3); err != nil {
+		t.Errorf("depth 3 rejected three levels of msgpack nesting: %v", err)
+	}
+
+	cbor := []byte{0x81, 0x81, 0x01}
+	if _, err := decodeBinaryBody(cbor, "cbor", 2); err == nil {
+		t.Error("depth 2 accepted three levels of CBOR nesting")
+	}
+	if _, err := decodeBinaryBody(cbor, "cbor", 3); err != nil {
+		t.Errorf("depth 3 rejected three levels of CBOR nesting: %v", err)
+	}
+
+	// The built-in cap applies when the configured depth is zero: one byte
+	// per level must error out instead of overflowing the stack.
+	deep := append(bytes.Repeat([]byte{0x91}, binaryMaxDepth+1), 0x01)
+	if _, err := decodeBinaryBody(deep, "msgpack", 0); err == nil {
+		t.Error("built-in depth cap did not reject deep nesting")
+	}
+}
+
+func TestBinaryEncodeRoundTrip(t *testing.T) {
+	// The response path round-trips the envelope through JSON with UseNumber
+	// before encoding; mirror that here and check decode(encode(x)) == x.
+	const doc = `{"jsonrpc":"2.0","id":7,"result":{"name":"åäö","count":42,"ratio":0.5,"ok":true,"gone":null,"items":[1,"two",false]}}`
+	dec := json.NewDecoder(strings.NewReader(doc))
+	dec.UseNumber()
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		t.Fatalf("failed to decode fixture: %v", err)
+	}
+	var want interface{}
+	if err := json.Unmarshal([]byte(doc), &want); err != nil {
+		t.Fatalf("failed to unmarshal fixture: %v", err)
+	}
+
+	for _, format := range []string{"msgpack", "cbor"} {
+		var encoded []byte
+		if format == "msgpack" {
+			encoded = msgpackEncode(nil, value)
+		} else {
+			encoded = cborEncode(nil, value)
+		}
+		decoded, err := decodeBinaryBody(encoded, format, 0)
+		if err != nil {
+			t.Fatalf("%s round trip failed to decode: %v", format, err)
+		}
+		var got interface{}
+		if err := json.Unmarshal(decoded, &got); err != nil {
+			t.Fatalf("%s round trip produced invalid JSON: %v", format, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s round trip mismatch:\ngot  %v\nwant %v", format, got, want)
+		}
+	}
+}
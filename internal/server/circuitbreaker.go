@@ -0,0 +1,135 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Per-database circuit breaker: once a database fails repeatedly with
+// connection-level errors, further calls fail fast for a cooldown period
+// instead of stacking up connection attempts against a host that is down.
+// After the cooldown a single probe request is let through (half-open); its
+// outcome decides between closing the circuit and another cooldown.
+
+const (
+	breakerClosed = iota
+	breakerHalfOpen
+	breakerOpen
+)
+
+const (
+	// breakerFailureThreshold consecutive transient failures open the circuit.
+	breakerFailureThreshold = 5
+	breakerCooldown         = 30 * time.Second
+)
+
+var (
+	breakerStateGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "pgarachne_circuit_state",
+		Help: "Circuit breaker state per database (0 closed, 1 half-open, 2 open).",
+	}, []string{"database"})
+	breakerTripsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pgarachne_circuit_trips_total",
+		Help: "Times the circuit for a database has opened.",
+	}, []string{"database"})
+)
+
+type breakerEntry struct {
+	state      int
+	failures   int
+	openedAt   time.Time
+	probing    bool
+	probeStart time.Time
+}
+
+type circuitBreaker struct {
+	mu      sync.Mutex
+	entries map[string]*breakerEntry
+	// onTrip, when set, is notified (asynchronously) each time a circuit opens.
+	onTrip func(dbName string)
+}
+
+func newCircuitBreaker() *circuitBreaker {
+	return &circuitBreaker{entries: make(map[string]*breakerEntry)}
+}
+
+func (b *circuitBreaker) entry(dbName string) *breakerEntry {
+	e, ok := b.entries[dbName]
+	if !ok {
+		e = &breakerEntry{}
+		b.entries[dbName] = e
+	}
+	return e
+}
+
+// allow reports whether a call against dbName may proceed. In the half-open
+// state exactly one probe request is admitted at a time.
+func (b *circuitBreaker) allow(dbName string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	e := b.entry(dbName)
+	switch e.state {
+	case breakerClosed:
+		return true
+	case breakerOpen:
+		if time.Since(e.openedAt) < breakerCooldown {
+			return false
+		}
+		e.state = breakerHalfOpen
+		e.probing = true
+		e.probeStart = time.Now()
+		breakerStateGauge.WithLabelValues(dbName).Set(breakerHalfOpen)
+		return true
+	default: // half-open
+		// A probe that never reported back (e.g. the request failed before
+		// touching the database) releases its slot after a grace period.
+		if e.probing && time.Since(e.probeStart) < 10*time.Second {
+			return false
+		}
+		e.probing = true
+		e.probeStart = time.Now()
+		return true
+	}
+}
+
+// recordSuccess closes the circuit after a successful call.
+func (b *circuitBreaker) recordSuccess(dbName string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	e := b.entry(dbName)
+	e.state = breakerClosed
+	e.failures = 0
+	e.probing = false
+	breakerStateGauge.WithLabelValues(dbName).Set(breakerClosed)
+}
+
+// recordFailure counts a transient failure; enough of them in a row — or any
+// failed half-open probe — open the circuit.
+func (b *circuitBreaker) recordFailure(dbName string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	e := b.entry(dbName)
+	e.probing = false
+	if e.state == breakerHalfOpen {
+		b.open(e, dbName)
+		return
+	}
+	e.failures++
+	if e.state == breakerClosed && e.failures >= breakerFailureThreshold {
+		b.open(e, dbName)
+	}
+}
+
+// open trips the circuit; callers hold the mutex.
+func (b *circuitBreaker) open(e *breakerEntry, dbName string) {
+	e.state = breakerOpen
+	e.openedAt = time.Now()
+	breakerStateGauge.WithLabelValues(dbName).Set(breakerOpen)
+	breakerTripsTotal.WithLabelValues(dbName).Inc()
+	if b.onTrip != nil {
+		go b.onTrip(dbName)
+	}
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/pgarachne/internal/config"
+)
+
+// Per-client concurrency limiting: where the per-database semaphore
+// (concurrency.go) protects Postgres from the sum of all callers, this caps
+// how many requests any single client may have in flight, so one misbehaving
+// integration cannot monopolize the shared pool. A client is identified by
+// its Authorization credential when present (the same token across IPs is
+// one client), otherwise by its IP.
+
+type clientLimiter struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func newClientLimiter() *clientLimiter {
+	return &clientLimiter{counts: make(map[string]int)}
+}
+
+// acquire reserves an in-flight slot for the client, or reports saturation.
+// Unlike the database semaphore there is no queue: a client at its own limit
+// should back off, not pile up waiters.
+func (l *clientLimiter) acquire(key string, limit int) (release func(), ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.counts[key] >= limit {
+		return nil, false
+	}
+	l.counts[key]++
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if l.counts[key]--; l.counts[key] <= 0 {
+			delete(l.counts, key)
+		}
+	}, true
+}
+
+// clientLimitKey derives the client identity. Credentials are hashed so the
+// map never holds live tokens.
+func clientLimitKey(c *gin.Context) string {
+	if auth := c.GetHeader("Authorization"); auth != "" {
+		sum := sha256.Sum256([]byte(auth))
+		return "token:" + hex.EncodeToString(sum[:16])
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// clientLimit resolves the cap for dbName: the per-database override wins,
+// zero disables the check.
+func clientLimit(cfg *config.Config, dbName string) int {
+	if limit, ok := cfg.MaxCallsPerClientDB[dbName]; ok {
+		return limit
+	}
+	return cfg.MaxCallsPerClient
+}
+
+func (s *Server) clientLimitMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		dbName := c.Param("database")
+		limit := clientLimit(s.Cfg(), dbName)
+		if limit <= 0 {
+			c.Next()
+			return
+		}
+		release, ok := s.clients.acquire(dbName+"/"+clientLimitKey(c), limit)
+		if !ok {
+			rateLimitHitsTotal.WithLabelValues(dbName, "per_client").Inc()
+			c.Header("Retry-After", "1")
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, JSONRPCResponse{Error: &JSONRPCError{
+				Code:    -32004,
+				Message: "Too many concurrent requests from this client",
+			}})
+			return
+		}
+		defer release()
+		c.Next()
+	}
+}
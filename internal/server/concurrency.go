@@ -0,0 +1,99 @@
+package server
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/pgarachne/internal/config"
+)
+
+// Per-database concurrency limiting: a semaphore caps how many function
+// calls run against each database at once. Excess callers wait in a bounded
+// queue (at most one full semaphore's worth, for at most CALL_QUEUE_TIMEOUT)
+// and then get 503 + Retry-After — a clear backpressure signal instead of
+// pool exhaustion errors out of Postgres.
+
+type dbSemaphore struct {
+	slots   chan struct{}
+	waiting int64
+}
+
+type callLimiter struct {
+	mu   sync.Mutex
+	sems map[string]*dbSemaphore
+}
+
+func newCallLimiter() *callLimiter {
+	return &callLimiter{sems: make(map[string]*dbSemaphore)}
+}
+
+// semaphore returns the semaphore for dbName, (re)building it when the
+// configured limit changed (e.g. after a reload).
+func (l *callLimiter) semaphore(cfg *config.Config, dbName string) *dbSemaphore {
+	limit := cfg.MaxConcurrentCalls
+	if dbLimit, ok := cfg.MaxConcurrentCallsDB[dbName]; ok {
+		limit = dbLimit
+	}
+	if limit <= 0 {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	sem, ok := l.sems[dbName]
+	if !ok || cap(sem.slots) != limit {
+		sem = &dbSemaphore{slots: make(chan struct{}, limit)}
+		l.sems[dbName] = sem
+	}
+	return sem
+}
+
+// acquire reserves a call slot, queueing up to the timeout. It returns the
+// release function, or false when the database is saturated.
+func (l *callLimiter) acquire(cfg *config.Config, dbName string) (release func(), ok bool) {
+	sem := l.semaphore(cfg, dbName)
+	if sem == nil {
+		return func() {}, true
+	}
+	release = func() { <-sem.slots }
+
+	select {
+	case sem.slots <- struct{}{}:
+		return release, true
+	default:
+	}
+
+	// The queue is bounded to one semaphore's worth of waiters; beyond that
+	// waiting would only prolong the pile-up.
+	if atomic.AddInt64(&sem.waiting, 1) > int64(cap(sem.slots)) {
+		atomic.AddInt64(&sem.waiting, -1)
+		return nil, false
+	}
+	defer atomic.AddInt64(&sem.waiting, -1)
+
+	select {
+	case sem.slots <- struct{}{}:
+		return release, true
+	case <-time.After(cfg.CallQueueTimeout):
+		return nil, false
+	}
+}
+
+func (s *Server) concurrencyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		release, ok := s.limiter.acquire(s.Cfg(), c.Param("database"))
+		if !ok {
+			rateLimitHitsTotal.WithLabelValues(c.Param("database"), "concurrency").Inc()
+			c.Header("Retry-After", "1")
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, JSONRPCResponse{Error: &JSONRPCError{
+				Code:    -32004,
+				Message: "Too many concurrent calls, retry later",
+			}})
+			return
+		}
+		defer release()
+		c.Next()
+	}
+}
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+)
+
+// CORS policy selection: multi-tenant hosts serve different frontends per
+// database, so /api/:database routes can carry their own origin list,
+// credentials setting and exposed headers (CORS_*_DB). Everything else — and
+// databases without an override — uses the global ALLOWED_ORIGINS policy.
+
+func originChecker(origins []string) func(string) bool {
+	return func(origin string) bool {
+		if len(origins) == 1 && origins[0] == "*" {
+			return true
+		}
+		for _, allowedOrigin := range origins {
+			if allowedOrigin == origin {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func (s *Server) corsMiddleware() gin.HandlerFunc {
+	// Developer mode answers any origin so local frontends on random ports
+	// just work.
+	if s.Cfg().DevMode {
+		permissive := cors.Config{
+			AllowMethods:     []string{"POST", "OPTIONS", "GET", "DELETE"},
+			AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
+			AllowCredentials: true,
+			AllowOriginFunc:  func(string) bool { return true },
+		}
+		return cors.New(permissive)
+	}
+
+	baseConfig := cors.Config{
+		AllowMethods:     []string{"POST", "OPTIONS", "GET"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
+		AllowCredentials: true,
+		AllowOriginFunc:  originChecker(s.Cfg().AllowedOrigins),
+	}
+	defaultHandler := cors.New(baseConfig)
+
+	// One prepared handler per database with an override.
+	perDB := make(map[string]gin.HandlerFunc)
+	for dbName, origins := range s.Cfg().CORSOriginsDB {
+		dbConfig := baseConfig
+		dbConfig.AllowOriginFunc = originChecker(origins)
+		if credentials, ok := s.Cfg().CORSCredentialsDB[dbName]; ok {
+			dbConfig.AllowCredentials = credentials
+		}
+		if headers, ok := s.Cfg().CORSExposeHeadersDB[dbName]; ok {
+			dbConfig.ExposeHeaders = headers
+		}
+		perDB[dbName] = cors.New(dbConfig)
+	}
+
+	return func(c *gin.Context) {
+		handler := defaultHandler
+		if dbHandler, ok := perDB[c.Param("database")]; ok {
+			handler = dbHandler
+		}
+		handler(c)
+		// The cors handler aborts with 403 for a disallowed origin; count it
+		// so policy misconfigurations and probing both show up on /metrics.
+		if c.IsAborted() && c.Writer.Status() == http.StatusForbidden && c.Request.Header.Get("Origin") != "" {
+			corsRejectionsTotal.WithLabelValues(c.Param("database")).Inc()
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Database allowlist: with DATABASES set, any /api/:database request naming
+// an unlisted catalog is rejected with 404 before authentication or any
+// connection attempt, so the server cannot be coaxed into opening pools to
+// postgres, template1 or other unrelated catalogs.
+
+func (s *Server) databaseAllowlistMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		dbName := c.Param("database")
+		if dbName == "" || len(s.Cfg().Databases) == 0 {
+			c.Next()
+			return
+		}
+		for _, allowed := range s.Cfg().Databases {
+			if allowed == dbName {
+				c.Next()
+				return
+			}
+		}
+		reqLogger(c).Warn("Request for unlisted database", "database", dbName)
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Database not found"})
+	}
+}
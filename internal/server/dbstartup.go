@@ -0,0 +1,108 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/yourusername/pgarachne/internal/database"
+	"github.com/yourusername/pgarachne/internal/lifecycle"
+)
+
+// Startup dependency policy: databases listed in DB_CRITICAL must be
+// reachable before the listeners come up (fail fast instead of surprising
+// the first request), DB_OPTIONAL ones are probed with a warning only. The
+// per-database outcome is kept for readiness reporting.
+
+// dbStatusMap tracks the last known connection status per database.
+type dbStatusMap struct {
+	mu       sync.RWMutex
+	statuses map[string]string // database -> "ok" | error text
+}
+
+func newDBStatusMap() *dbStatusMap {
+	return &dbStatusMap{statuses: make(map[string]string)}
+}
+
+func (m *dbStatusMap) set(database, status string) {
+	m.mu.Lock()
+	m.statuses[database] = status
+	m.mu.Unlock()
+}
+
+// snapshot returns a copy for serialization in status endpoints.
+func (m *dbStatusMap) snapshot() map[string]string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	snapshot := make(map[string]string, len(m.statuses))
+	for k, v := range m.statuses {
+		snapshot[k] = v
+	}
+	return snapshot
+}
+
+// registerPoolCloseHook closes the connection pools at the very end of
+// shutdown. It is registered before every other hook so its Stop — hooks
+// stop in reverse registration order — runs after the listeners have
+// drained their in-flight requests.
+func (s *Server) registerPoolCloseHook(manager *lifecycle.Manager) {
+	manager.Register(lifecycle.Hook{
+		Name:  "db-pools",
+		Start: func() error { return nil },
+		Stop: func(context.Context) error {
+			s.stmts.purge()
+			database.CloseAll()
+			return nil
+		},
+	})
+}
+
+// registerHealthCheckHook runs the background pool health checker for the
+// lifetime of the server.
+func (s *Server) registerHealthCheckHook(manager *lifecycle.Manager) {
+	var stop func()
+	manager.Register(lifecycle.Hook{
+		Name: "db-health",
+		Start: func() error {
+			stop = database.StartHealthChecker()
+			return nil
+		},
+		Stop: func(context.Context) error {
+			stop()
+			return nil
+		},
+	})
+}
+
+// registerDatabaseStartupHook probes the declared databases in order,
+// critical ones first. Registered before the listener hooks so a critical
+// failure prevents the server from ever accepting traffic.
+func (s *Server) registerDatabaseStartupHook(manager *lifecycle.Manager) {
+	if len(s.Cfg().DBCritical) == 0 && len(s.Cfg().DBOptional) == 0 {
+		return
+	}
+	manager.Register(lifecycle.Hook{
+		Name: "databases",
+		Start: func() error {
+			for _, dbName := range s.Cfg().DBCritical {
+				if _, err := database.GetConnection(s.Cfg(), dbName); err != nil {
+					s.dbStatus.set(dbName, err.Error())
+					return fmt.Errorf("critical database %s unreachable: %w", dbName, err)
+				}
+				s.dbStatus.set(dbName, "ok")
+				slog.Info("Critical database reachable", "database", dbName)
+			}
+			for _, dbName := range s.Cfg().DBOptional {
+				if _, err := database.GetConnection(s.Cfg(), dbName); err != nil {
+					s.dbStatus.set(dbName, err.Error())
+					slog.Warn("Optional database unreachable, continuing startup", "database", dbName, "error", err)
+					continue
+				}
+				s.dbStatus.set(dbName, "ok")
+				slog.Info("Optional database reachable", "database", dbName)
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"crypto/subtle"
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Runtime debug endpoints (DEBUG_ENDPOINTS=true): /debug/pprof/* for CPU,
+// heap and goroutine profiles and /debug/vars for an expvar snapshot
+// (memstats, cmdline). Both sit behind the admin token so they can stay
+// enabled on production instances without exposing internals.
+
+// adminTokenMiddleware rejects requests whose bearer token does not match
+// ADMIN_TOKEN. The comparison is constant-time.
+func (s *Server) adminTokenMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if token == "" {
+			token = c.GetHeader("X-Admin-Token")
+		}
+		if s.Cfg().AdminToken == "" ||
+			subtle.ConstantTimeCompare([]byte(token), []byte(s.Cfg().AdminToken)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Admin token required"})
+			return
+		}
+		c.Next()
+	}
+}
+
+func (s *Server) registerDebugEndpoints(router *gin.Engine) {
+	if !s.Cfg().DebugEndpoints {
+		return
+	}
+
+	debug := router.Group("/debug", s.adminTokenMiddleware())
+	debug.GET("/vars", gin.WrapH(expvar.Handler()))
+	debug.Any("/pprof/*name", func(c *gin.Context) {
+		switch strings.TrimPrefix(c.Param("name"), "/") {
+		case "cmdline":
+			pprof.Cmdline(c.Writer, c.Request)
+		case "profile":
+			pprof.Profile(c.Writer, c.Request)
+		case "symbol":
+			pprof.Symbol(c.Writer, c.Request)
+		case "trace":
+			pprof.Trace(c.Writer, c.Request)
+		default:
+			// Index also serves the named lookup profiles (heap,
+			// goroutine, block, mutex, ...) from the request path.
+			pprof.Index(c.Writer, c.Request)
+		}
+	})
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Differential responses (delta sync): a polling client replays the version
+// it got last time in the If-None-Match header. When a companion function
+// "<function>_delta" exists, the call is rerouted there with the version
+// injected into params, and the convention function returns only rows changed
+// since that version plus the new version. The response carries the new
+// version as an ETag so clients can store it opaquely. Without the header (or
+// without a companion function) the call behaves exactly as before.
+
+// hasDeltaFunction checks (and caches) the existence of the "<function>_delta"
+// companion in the catalog.
+func (s *Server) hasDeltaFunction(ctx context.Context, db *sql.DB, databaseName, functionName string) bool {
+	cacheKey := databaseName + "/" + functionName
+	if cached, ok := s.deltaFuncs.get(cacheKey); ok {
+		return cached
+	}
+
+	schema, name := splitFunctionName(functionName, "public")
+	var exists bool
+	err := db.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM pg_proc p
+			JOIN pg_namespace n ON n.oid = p.pronamespace
+			WHERE n.nspname = $1 AND p.proname = $2
+		)`, schema, name+"_delta").Scan(&exists)
+	if err != nil {
+		slog.Warn("Failed to check delta companion function", "function", functionName, "error", err)
+		exists = false
+	}
+
+	s.deltaFuncs.set(cacheKey, exists)
+	return exists
+}
+
+// applyDeltaSync reroutes the call to the delta companion when the client
+// sent a version. It returns the (possibly rewritten) function name.
+func (s *Server) applyDeltaSync(c *gin.Context, db *sql.DB, databaseName, functionName string, params map[string]interface{}) string {
+	clientVersion := strings.Trim(c.GetHeader("If-None-Match"), `"`)
+	if clientVersion == "" || params == nil {
+		return functionName
+	}
+	if !s.hasDeltaFunction(c.Request.Context(), db, databaseName, functionName) {
+		return functionName
+	}
+	params["version"] = clientVersion
+	return functionName + "_delta"
+}
+
+// setVersionETag exposes the "version" field of an object result as the
+// response ETag, closing the sync loop for the next poll.
+func setVersionETag(c *gin.Context, resultJSON json.RawMessage) {
+	var probe struct {
+		Version json.RawMessage `json:"version"`
+	}
+	if err := json.Unmarshal(resultJSON, &probe); err != nil || len(probe.Version) == 0 {
+		return
+	}
+	version := strings.Trim(string(probe.Version), `"`)
+	if version == "" || version == "null" {
+		return
+	}
+	c.Header("ETag", fmt.Sprintf("%q", version))
+}
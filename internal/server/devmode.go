@@ -0,0 +1,82 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Developer mode (DEV_MODE=true): pretty-printed JSON responses, full
+// Postgres error context, request/response body logging and permissive
+// CORS, so SQL functions can be developed against readable output instead
+// of production-terse errors. Never enable it on an internet-facing
+// instance — it leaks exactly the detail production mode withholds.
+
+// devMode mirrors Config.DevMode for the few call sites without a Server
+// receiver (error shaping).
+var devMode bool
+
+// devBodyLimit caps how much of a body ends up in the log.
+const devBodyLimit = 16 * 1024
+
+// devBufferWriter captures the response instead of writing it through, so
+// it can be re-indented and logged after the handler returns.
+type devBufferWriter struct {
+	gin.ResponseWriter
+	buf bytes.Buffer
+}
+
+func (w *devBufferWriter) Write(b []byte) (int, error) {
+	return w.buf.Write(b)
+}
+
+func (w *devBufferWriter) WriteString(s string) (int, error) {
+	return w.buf.WriteString(s)
+}
+
+// devEchoMiddleware logs request and response bodies and pretty-prints
+// JSON responses. Buffering whole responses is fine here: developer mode
+// trades throughput for readability by design.
+func (s *Server) devEchoMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Body != nil {
+			body, err := io.ReadAll(c.Request.Body)
+			if err == nil {
+				c.Request.Body = io.NopCloser(bytes.NewReader(body))
+				reqLogger(c).Info("Request body", "method", c.Request.Method, "path", c.Request.URL.Path, "body", devClip(body))
+			}
+		}
+
+		writer := &devBufferWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		response := writer.buf.Bytes()
+		if strings.Contains(writer.Header().Get("Content-Type"), "json") {
+			var pretty bytes.Buffer
+			if json.Indent(&pretty, response, "", "  ") == nil {
+				pretty.WriteByte('\n')
+				response = pretty.Bytes()
+			}
+		}
+		reqLogger(c).Info("Response body", "status", c.Writer.Status(), "body", devClip(response))
+		c.Writer.Write(response)
+	}
+}
+
+// devClip truncates bodies for the log; binary content stays out of it.
+func devClip(body []byte) string {
+	if len(body) > devBodyLimit {
+		body = body[:devBodyLimit]
+	}
+	if !bytes.HasPrefix(bytes.TrimSpace(body), []byte("{")) &&
+		!bytes.HasPrefix(bytes.TrimSpace(body), []byte("[")) &&
+		bytes.ContainsRune(body, 0) {
+		return "[binary]"
+	}
+	return string(body)
+}
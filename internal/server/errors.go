@@ -0,0 +1,120 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// SQLSTATE codes that indicate lock contention: retrying after a short pause
+// usually succeeds, so clients get a Retry-After hint instead of a plain error.
+var lockContentionCodes = map[string]bool{
+	"55P03": true, // lock_not_available (lock_timeout / NOWAIT)
+	"40P01": true, // deadlock_detected
+	"40001": true, // serialization_failure
+}
+
+// lockRetryHint checks whether err is a Postgres lock contention error.
+// It returns the SQLSTATE and a suggested Retry-After in seconds (1-5s with
+// jitter, so a herd of clients doesn't retry into the same contention at once).
+func lockRetryHint(err error) (sqlstate string, retryAfter int, ok bool) {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return "", 0, false
+	}
+	code := string(pqErr.Code)
+	if !lockContentionCodes[code] {
+		return "", 0, false
+	}
+	return code, 1 + rand.Intn(4), true
+}
+
+// sqlErrorResponse maps a Postgres error to an HTTP status and a structured
+// JSON-RPC error carrying the SQLSTATE plus whatever context the server
+// reported (constraint, table, hint, detail). Clients get 409 for unique and
+// foreign key violations, 422 for check/not-null violations, 403 for
+// insufficient privilege and 404 for undefined functions instead of a
+// generic 500 with raw error text.
+func sqlErrorResponse(err error) (int, *JSONRPCError, bool) {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return 0, nil, false
+	}
+	code := string(pqErr.Code)
+
+	// SQL-defined statuses: RAISE EXCEPTION ... USING ERRCODE='PT404' maps
+	// straight to HTTP 404 (PostgREST-compatible convention). A JSON object
+	// in DETAIL becomes the error's data, and its numeric "code" field the
+	// JSON-RPC error code, so functions can shape the full error object.
+	if len(code) == 5 && strings.HasPrefix(code, "PT") {
+		if status, convErr := strconv.Atoi(code[2:]); convErr == nil && status >= 400 && status < 600 {
+			rpcErr := &JSONRPCError{Code: -32011, Message: pqErr.Message, Data: gin.H{"sqlstate": code}}
+			var detail map[string]interface{}
+			if json.Unmarshal([]byte(pqErr.Detail), &detail) == nil {
+				data := rpcErr.Data.(gin.H)
+				for k, v := range detail {
+					data[k] = v
+				}
+				if codeVal, ok := detail["code"].(float64); ok {
+					rpcErr.Code = int(codeVal)
+				}
+			}
+			return status, rpcErr, true
+		}
+	}
+
+	status := http.StatusInternalServerError
+	switch {
+	case code == "23505" || code == "23503": // unique / foreign key violation
+		status = http.StatusConflict
+	case code == "23502" || code == "23514": // not-null / check violation
+		status = http.StatusUnprocessableEntity
+	case code == "42501": // insufficient_privilege
+		status = http.StatusForbidden
+	case code == "42883" || code == "3F000": // undefined function / schema
+		status = http.StatusNotFound
+	case code == "P0001": // raise_exception from SQL
+		status = http.StatusBadRequest
+	case pqErr.Code.Class() == "22": // data exception (bad cast, range, ...)
+		status = http.StatusBadRequest
+	}
+
+	data := gin.H{"sqlstate": code}
+	if pqErr.Constraint != "" {
+		data["constraint"] = pqErr.Constraint
+	}
+	if pqErr.Table != "" {
+		data["table"] = pqErr.Table
+	}
+	if pqErr.Column != "" {
+		data["column"] = pqErr.Column
+	}
+	if pqErr.Detail != "" {
+		data["detail"] = pqErr.Detail
+	}
+	if pqErr.Hint != "" {
+		data["hint"] = pqErr.Hint
+	}
+	// Developer mode adds the context normally reserved for server logs.
+	if devMode {
+		if pqErr.Where != "" {
+			data["where"] = pqErr.Where
+		}
+		if pqErr.Position != "" {
+			data["position"] = pqErr.Position
+		}
+		if pqErr.Routine != "" {
+			data["routine"] = pqErr.Routine
+		}
+		if pqErr.InternalQuery != "" {
+			data["internal_query"] = pqErr.InternalQuery
+		}
+	}
+	return status, &JSONRPCError{Code: -32010, Message: pqErr.Message, Data: data}, true
+}
@@ -0,0 +1,133 @@
+package server
+
+import "encoding/binary"
+
+// Minimal FlatBuffers builder, just enough to emit the Arrow IPC metadata
+// messages in arrow.go without pulling in the flatbuffers dependency. It
+// follows the reference builder: the buffer is filled back to front and
+// offsets are measured from the end. Vtable deduplication is skipped — the
+// handful of tables per message makes it irrelevant.
+
+type flatBuilder struct {
+	buf       []byte // data occupies buf[head:]
+	head      int
+	minalign  int
+	vtable    []int // field offsets of the table being built, by slot
+	objectEnd int
+}
+
+func newFlatBuilder() *flatBuilder {
+	const initial = 1024
+	return &flatBuilder{buf: make([]byte, initial), head: initial, minalign: 1}
+}
+
+// offset is the current write position measured from the end of the buffer.
+func (b *flatBuilder) offset() int { return len(b.buf) - b.head }
+
+// prep aligns for a value of the given size preceded by additional bytes,
+// growing the buffer as needed.
+func (b *flatBuilder) prep(size, additional int) {
+	if size > b.minalign {
+		b.minalign = size
+	}
+	alignSize := (^(len(b.buf) - b.head + additional) + 1) & (size - 1)
+	for b.head < alignSize+size+additional {
+		old := b.buf
+		b.buf = make([]byte, len(old)*2)
+		b.head += len(old)
+		copy(b.buf[b.head:], old[b.head-len(old):])
+	}
+	for i := 0; i < alignSize; i++ {
+		b.head--
+		b.buf[b.head] = 0
+	}
+}
+
+func (b *flatBuilder) place(v uint64, size int) {
+	b.head -= size
+	for i := 0; i < size; i++ {
+		b.buf[b.head+i] = byte(v >> (8 * i))
+	}
+}
+
+func (b *flatBuilder) prependUint(v uint64, size int) {
+	b.prep(size, 0)
+	b.place(v, size)
+}
+
+// prependUOffset writes a forward reference to a previously built object.
+func (b *flatBuilder) prependUOffset(off int) {
+	b.prep(4, 0)
+	b.place(uint64(b.offset()+4-off), 4)
+}
+
+func (b *flatBuilder) createString(s string) int {
+	b.prep(4, len(s)+1)
+	b.head--
+	b.buf[b.head] = 0
+	b.head -= len(s)
+	copy(b.buf[b.head:], s)
+	b.place(uint64(len(s)), 4)
+	return b.offset()
+}
+
+func (b *flatBuilder) startVector(elemSize, count, alignment int) {
+	b.prep(4, elemSize*count)
+	b.prep(alignment, elemSize*count)
+}
+
+func (b *flatBuilder) endVector(count int) int {
+	b.place(uint64(count), 4)
+	return b.offset()
+}
+
+func (b *flatBuilder) startObject(numFields int) {
+	b.vtable = make([]int, numFields)
+	b.objectEnd = b.offset()
+}
+
+func (b *flatBuilder) slot(n int) { b.vtable[n] = b.offset() }
+
+func (b *flatBuilder) prependUintSlot(n int, v, def uint64, size int) {
+	if v == def {
+		return
+	}
+	b.prependUint(v, size)
+	b.slot(n)
+}
+
+func (b *flatBuilder) prependUOffsetSlot(n, off int) {
+	if off == 0 {
+		return
+	}
+	b.prependUOffset(off)
+	b.slot(n)
+}
+
+func (b *flatBuilder) endObject() int {
+	// Placeholder for the soffset to the vtable, patched below.
+	b.prependUint(0, 4)
+	objectOffset := b.offset()
+
+	for i := len(b.vtable) - 1; i >= 0; i-- {
+		var off uint16
+		if b.vtable[i] != 0 {
+			off = uint16(objectOffset - b.vtable[i])
+		}
+		b.prependUint(uint64(off), 2)
+	}
+	b.prependUint(uint64(objectOffset-b.objectEnd), 2) // table size
+	b.prependUint(uint64((len(b.vtable)+2)*2), 2)      // vtable size
+	vtableOffset := b.offset()
+
+	pos := len(b.buf) - objectOffset
+	binary.LittleEndian.PutUint32(b.buf[pos:], uint32(vtableOffset-objectOffset))
+	return objectOffset
+}
+
+// finish writes the root reference and returns the completed buffer.
+func (b *flatBuilder) finish(root int) []byte {
+	b.prep(b.minalign, 4)
+	b.prependUOffset(root)
+	return b.buf[b.head:]
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// The assertions below read the finished buffer the way a FlatBuffers
+// consumer does: the root uoffset at position 0, the table's soffset back to
+// its vtable, and per-field uint16 offsets relative to the table position.
+
+// flatTablePos resolves the root table position.
+func flatTablePos(buf []byte) int {
+	return int(binary.LittleEndian.Uint32(buf))
+}
+
+// flatFieldOffset returns the position of a field's value, or 0 when the
+// slot is absent from the vtable (i.e. the default applies).
+func flatFieldOffset(buf []byte, tablePos, slot int) int {
+	vtablePos := tablePos - int(int32(binary.LittleEndian.Uint32(buf[tablePos:])))
+	vtableLen := int(binary.LittleEndian.Uint16(buf[vtablePos:]))
+	fieldEntry := vtablePos + 4 + 2*slot
+	if fieldEntry >= vtablePos+vtableLen {
+		return 0
+	}
+	off := int(binary.LittleEndian.Uint16(buf[fieldEntry:]))
+	if off == 0 {
+		return 0
+	}
+	return tablePos + off
+}
+
+// flatString follows a uoffset to a string and returns its bytes.
+func flatString(buf []byte, pos int) string {
+	strPos := pos + int(binary.LittleEndian.Uint32(buf[pos:]))
+	n := int(binary.LittleEndian.Uint32(buf[strPos:]))
+	return string(buf[strPos+4 : strPos+4+n])
+}
+
+func TestFlatBuilderTable(t *testing.T) {
+	b := newFlatBuilder()
+	name := b.createString("hello")
+	b.startObject(3)
+	b.prependUintSlot(0, 42, 0, 4)
+	b.prependUintSlot(1, 0, 0, 4) // default value: slot stays empty
+	b.prependUOffsetSlot(2, name)
+	root := b.endObject()
+	buf := b.finish(root)
+
+	tablePos := flatTablePos(buf)
+
+	pos := flatFieldOffset(buf, tablePos, 0)
+	if pos == 0 {
+		t.Fatal("slot 0 missing from vtable")
+	}
+	if got := binary.LittleEndian.Uint32(buf[pos:]); got != 42 {
+		t.Errorf("slot 0 = %d; want 42", got)
+	}
+
+	if pos := flatFieldOffset(buf, tablePos, 1); pos != 0 {
+		t.Errorf("default-valued slot 1 written at %d; want omitted", pos)
+	}
+
+	pos = flatFieldOffset(buf, tablePos, 2)
+	if pos == 0 {
+		t.Fatal("slot 2 missing from vtable")
+	}
+	if got := flatString(buf, pos); got != "hello" {
+		t.Errorf("slot 2 string = %q; want %q", got, "hello")
+	}
+}
+
+func TestFlatBuilderString(t *testing.T) {
+	b := newFlatBuilder()
+	off := b.createString("abc")
+	buf := b.buf[b.head:]
+
+	// The string sits at offset-from-end `off`: length prefix, bytes, NUL.
+	pos := len(buf) - off
+	if n := binary.LittleEndian.Uint32(buf[pos:]); n != 3 {
+		t.Fatalf("string length prefix = %d; want 3", n)
+	}
+	if got := string(buf[pos+4 : pos+7]); got != "abc" {
+		t.Errorf("string bytes = %q; want %q", got, "abc")
+	}
+	if buf[pos+7] != 0 {
+		t.Error("string missing NUL terminator")
+	}
+}
+
+func TestFlatBuilderVector(t *testing.T) {
+	b := newFlatBuilder()
+	b.startVector(4, 3, 4)
+	for _, v := range []uint64{3, 2, 1} { // prepended, so reversed
+		b.prependUint(v, 4)
+	}
+	off := b.endVector(3)
+	buf := b.buf[b.head:]
+
+	pos := len(buf) - off
+	if n := binary.LittleEndian.Uint32(buf[pos:]); n != 3 {
+		t.Fatalf("vector length prefix = %d; want 3", n)
+	}
+	for i, want := range []uint32{1, 2, 3} {
+		if got := binary.LittleEndian.Uint32(buf[pos+4+4*i:]); got != want {
+			t.Errorf("vector[%d] = %d; want %d", i, got, want)
+		}
+	}
+}
+
+// TestFlatBuilderGrowth exercises the buffer doubling: data written before a
+// growth must survive it byte for byte.
+func TestFlatBuilderGrowth(t *testing.T) {
+	b := newFlatBuilder()
+	long := make([]byte, 3000)
+	for i := range long {
+		long[i] = byte('a' + i%26)
+	}
+	off := b.createString(string(long))
+	buf := b.buf[b.head:]
+	pos := len(buf) - off
+	if n := binary.LittleEndian.Uint32(buf[pos:]); int(n) != len(long) {
+		t.Fatalf("string length prefix = %d; want %d", n, len(long))
+	}
+	if got := string(buf[pos+4 : pos+4+len(long)]); got != string(long) {
+		t.Error("string bytes corrupted across buffer growth")
+	}
+}
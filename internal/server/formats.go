@@ -0,0 +1,179 @@
+package server
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CSV/TSV output: when the client asks for it (?format=csv or an Accept
+// header) and the function returned an array of objects, the result is
+// served as a spreadsheet-friendly download with a header row instead of
+// JSON. Anything that is not tabular falls back to the normal JSON response.
+
+// tabularFormat returns "csv" or "tsv" when the request negotiates one of
+// the tabular formats, or "" for the default JSON response.
+func tabularFormat(c *gin.Context) string {
+	switch c.Query("format") {
+	case "csv":
+		return "csv"
+	case "tsv":
+		return "tsv"
+	case "xlsx":
+		return "xlsx"
+	case "arrow":
+		return "arrow"
+	case "parquet":
+		return "parquet"
+	}
+	accept := c.GetHeader("Accept")
+	if strings.Contains(accept, "text/csv") {
+		return "csv"
+	}
+	if strings.Contains(accept, "text/tab-separated-values") {
+		return "tsv"
+	}
+	if strings.Contains(accept, "spreadsheetml.sheet") {
+		return "xlsx"
+	}
+	if strings.Contains(accept, "application/vnd.apache.arrow.stream") {
+		return "arrow"
+	}
+	if strings.Contains(accept, "application/vnd.apache.parquet") {
+		return "parquet"
+	}
+	return ""
+}
+
+// tabularColumns derives the column order: the first row's key order as it
+// appears in the JSON text, then any keys only later rows have, sorted.
+func tabularColumns(firstRow json.RawMessage, rows []map[string]interface{}) []string {
+	var columns []string
+	seen := make(map[string]bool)
+	dec := json.NewDecoder(bytes.NewReader(firstRow))
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			break
+		}
+		switch t := tok.(type) {
+		case json.Delim:
+			switch t {
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+			}
+		case string:
+			// At depth 1 string tokens alternate key/value; skip the value.
+			if depth == 1 && !seen[t] {
+				columns = append(columns, t)
+				seen[t] = true
+				var skip json.RawMessage
+				dec.Decode(&skip)
+			}
+		}
+	}
+
+	var extra []string
+	for _, row := range rows {
+		for key := range row {
+			if !seen[key] {
+				extra = append(extra, key)
+				seen[key] = true
+			}
+		}
+	}
+	sort.Strings(extra)
+	return append(columns, extra...)
+}
+
+// tabularCell renders one value for a CSV cell: scalars as their natural
+// text, nested objects and arrays as JSON, null as an empty cell.
+func tabularCell(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(encoded)
+	}
+}
+
+// decodeTabular parses resultJSON as an array of objects and derives the
+// column order; ok is false when the result is not tabular.
+func decodeTabular(resultJSON json.RawMessage) (rows []map[string]interface{}, columns []string, ok bool) {
+	var rawRows []json.RawMessage
+	if json.Unmarshal(resultJSON, &rawRows) != nil {
+		return nil, nil, false
+	}
+	rows = make([]map[string]interface{}, len(rawRows))
+	for i, raw := range rawRows {
+		if json.Unmarshal(raw, &rows[i]) != nil {
+			return nil, nil, false
+		}
+	}
+	if len(rawRows) > 0 {
+		columns = tabularColumns(rawRows[0], rows)
+	}
+	return rows, columns, true
+}
+
+// writeTabular serves resultJSON in the negotiated tabular format when it is
+// an array of objects; it reports whether a response was written.
+func writeTabular(c *gin.Context, functionName string, resultJSON json.RawMessage, format string) bool {
+	rows, columns, ok := decodeTabular(resultJSON)
+	if !ok {
+		return false
+	}
+	switch format {
+	case "xlsx":
+		writeXLSX(c, functionName, rows, columns)
+		return true
+	case "arrow":
+		writeArrowStream(c, functionName, rows, columns)
+		return true
+	case "parquet":
+		writeParquet(c, functionName, rows, columns)
+		return true
+	}
+
+	contentType := "text/csv; charset=utf-8"
+	if format == "tsv" {
+		contentType = "text/tab-separated-values; charset=utf-8"
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, functionName, format))
+	c.Status(200)
+
+	w := csv.NewWriter(c.Writer)
+	if format == "tsv" {
+		w.Comma = '\t'
+	}
+	w.Write(columns)
+	record := make([]string, len(columns))
+	for _, row := range rows {
+		for i, column := range columns {
+			record[i] = tabularCell(row[column])
+		}
+		w.Write(record)
+	}
+	w.Flush()
+	return true
+}
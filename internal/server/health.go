@@ -0,0 +1,63 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/pgarachne/internal/database"
+)
+
+// Kubernetes-style probes. /livez only proves the process is up and serving
+// HTTP; /readyz additionally verifies the critical databases are reachable,
+// their pools are not saturated, and the instance is not draining for
+// shutdown — so load balancers stop routing traffic before the listeners
+// actually close.
+
+func (s *Server) handleLivez(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+func (s *Server) handleReadyz(c *gin.Context) {
+	if s.draining.Load() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "draining"})
+		return
+	}
+
+	ready := true
+	for _, dbName := range s.Cfg().DBCritical {
+		db, err := database.GetConnection(s.Cfg(), dbName)
+		if err != nil {
+			s.dbStatus.set(dbName, err.Error())
+			ready = false
+			continue
+		}
+		if stats := db.Stats(); stats.MaxOpenConnections > 0 && stats.InUse >= stats.MaxOpenConnections {
+			s.dbStatus.set(dbName, "pool saturated")
+			ready = false
+			continue
+		}
+		s.dbStatus.set(dbName, "ok")
+	}
+
+	status, statusText := http.StatusOK, "ok"
+	if !ready {
+		status, statusText = http.StatusServiceUnavailable, "unavailable"
+	}
+	c.JSON(status, gin.H{"status": statusText, "databases": s.dbStatus.snapshot()})
+}
+
+// drainMiddleware rejects new work once shutdown has started: anything that
+// slips in between /readyz flipping and the listener closing gets an
+// immediate 503 with Connection: close instead of starting a call the
+// shutdown deadline may cut short. The probes stay answerable so load
+// balancers see the drain rather than a dead instance.
+func (s *Server) drainMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !s.draining.Load() || c.Request.URL.Path == "/livez" || c.Request.URL.Path == "/readyz" {
+			c.Next()
+			return
+		}
+		c.Header("Connection", "close")
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "Server is shutting down"})
+	}
+}
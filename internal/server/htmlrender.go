@@ -0,0 +1,113 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"path/filepath"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Server-rendered HTML: GET /api/:database/_html/:function calls the
+// function (params from ?params=, a JSON object) and renders its result
+// through a named html/template from TEMPLATES_PATH, serving text/html.
+// The template is "<function>.html" unless the result object carries a
+// "template" field or ?template= says otherwise — enough for fully
+// server-rendered reports and internal tools with no frontend build.
+
+// templateCache parses the templates directory once and hands out the set;
+// purge() makes a reload pick up edited files.
+type templateCache struct {
+	mu        sync.Mutex
+	templates *template.Template
+}
+
+func (tc *templateCache) get(dir string) (*template.Template, error) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	if tc.templates != nil {
+		return tc.templates, nil
+	}
+	templates, err := template.ParseGlob(filepath.Join(dir, "*.html"))
+	if err != nil {
+		return nil, err
+	}
+	tc.templates = templates
+	return templates, nil
+}
+
+func (tc *templateCache) purge() {
+	tc.mu.Lock()
+	tc.templates = nil
+	tc.mu.Unlock()
+}
+
+func (s *Server) handleHTMLRender(c *gin.Context) {
+	if s.Cfg().TemplatesPath == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "TEMPLATES_PATH is not configured"})
+		return
+	}
+	functionName := c.Param("function")
+	if functionName == "login" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Login must be called via the public endpoint"})
+		return
+	}
+	params := c.DefaultQuery("params", "{}")
+
+	tx := s.loBeginTx(c)
+	if tx == nil {
+		return
+	}
+	defer tx.Rollback()
+
+	var result json.RawMessage
+	query := fmt.Sprintf("SELECT %s($1::jsonb)::json", functionName)
+	if err := tx.QueryRowContext(c.Request.Context(), query, params).Scan(&result); err != nil {
+		reqLogger(c).Error("HTML function call failed", "function", functionName, "error", err)
+		if status, rpcErr, ok := sqlErrorResponse(err); ok {
+			c.JSON(status, gin.H{"error": rpcErr.Message, "data": rpcErr.Data})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Function call failed"})
+		}
+		return
+	}
+	tx.Commit()
+
+	var data interface{}
+	if err := json.Unmarshal(result, &data); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Function returned invalid JSON"})
+		return
+	}
+
+	// The template name: explicit query parameter, the result's own
+	// "template" field, or the function's name.
+	templateName := c.Query("template")
+	if templateName == "" {
+		if object, ok := data.(map[string]interface{}); ok {
+			templateName, _ = object["template"].(string)
+		}
+	}
+	if templateName == "" {
+		templateName = functionName + ".html"
+	}
+
+	templates, err := s.htmlTemplates.get(s.Cfg().TemplatesPath)
+	if err != nil {
+		reqLogger(c).Error("Failed to parse templates", "path", s.Cfg().TemplatesPath, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Template directory failed to parse"})
+		return
+	}
+	if templates.Lookup(templateName) == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Unknown template '%s'", templateName)})
+		return
+	}
+
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Status(http.StatusOK)
+	if err := templates.ExecuteTemplate(c.Writer, templateName, data); err != nil {
+		reqLogger(c).Error("Template execution failed", "template", templateName, "error", err)
+	}
+}
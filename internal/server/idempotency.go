@@ -0,0 +1,121 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Idempotency keys: a mutating call carrying an Idempotency-Key header is
+// executed at most once per key within IDEMPOTENCY_TTL — retries replay the
+// stored response instead of running the function again. Records live in the
+// shared state store, so with STATE_STORAGE=postgres the guarantee holds
+// across instances. Reusing a key with a different request is rejected.
+
+const (
+	idempotencyHeader       = "Idempotency-Key"
+	idempotencyReplayHeader = "Idempotency-Replayed"
+	idempotencyBucket       = "idempotency"
+
+	// idempotencyPendingTTL bounds how long a pending marker can outlive a
+	// crashed instance before the key becomes usable again.
+	idempotencyPendingTTL = 30 * time.Second
+)
+
+// idempotencyRecord is what gets stored per key: the request fingerprint, so
+// key reuse with a different payload is detected, and the response to replay.
+// A record with Status 0 is a pending marker: the first request claimed the
+// key and is still executing.
+type idempotencyRecord struct {
+	Hash   string          `json:"hash"`
+	Status int             `json:"status"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+// idempotencyFingerprint ties a key to the exact call it was first used for.
+func idempotencyFingerprint(databaseName, role, functionName string, paramsJSON []byte) string {
+	h := sha256.New()
+	h.Write([]byte(databaseName + "\x00" + role + "\x00" + functionName + "\x00"))
+	h.Write(paramsJSON)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// claimIdempotent atomically claims the key with a pending marker and
+// returns true when the caller owns it and must execute the function. When
+// the key is already taken it writes the response — the stored replay, a
+// key-reuse conflict, or 409 for a still-running original — and returns
+// false.
+func (s *Server) claimIdempotent(c *gin.Context, storeKey, fingerprint string) bool {
+	pending, _ := json.Marshal(idempotencyRecord{Hash: fingerprint})
+	claimed, err := s.state.SetNX(c.Request.Context(), idempotencyBucket, storeKey, pending, idempotencyPendingTTL)
+	if err != nil {
+		// A broken state store must not block calls; the request just loses
+		// its idempotency guarantee.
+		reqLogger(c).Warn("Idempotency claim failed", "error", err)
+		return true
+	}
+	if claimed {
+		return true
+	}
+
+	stored, ok, err := s.state.Get(c.Request.Context(), idempotencyBucket, storeKey)
+	if err != nil {
+		reqLogger(c).Warn("Idempotency lookup failed", "error", err)
+		return true
+	}
+	var rec idempotencyRecord
+	if !ok || json.Unmarshal(stored, &rec) != nil {
+		return true
+	}
+	if rec.Hash != fingerprint {
+		c.JSON(http.StatusConflict, JSONRPCResponse{Error: &JSONRPCError{
+			Message: "Idempotency-Key was already used for a different request",
+		}})
+		return false
+	}
+	if rec.Status == 0 {
+		// The first request with this key has not finished; the client
+		// should retry once it has, rather than run the function twice.
+		c.Header("Retry-After", "1")
+		c.JSON(http.StatusConflict, JSONRPCResponse{Error: &JSONRPCError{
+			Message: "A request with this Idempotency-Key is still in progress",
+		}})
+		return false
+	}
+	c.Header(idempotencyReplayHeader, "true")
+	if len(rec.Body) == 0 {
+		c.Status(rec.Status)
+	} else {
+		c.Data(rec.Status, "application/json; charset=utf-8", rec.Body)
+	}
+	return false
+}
+
+// releaseIdempotent drops the pending marker after a failed execution, so
+// the client's retry is allowed to run instead of waiting out the TTL.
+func (s *Server) releaseIdempotent(c *gin.Context, storeKey string) {
+	if err := s.state.Delete(c.Request.Context(), idempotencyBucket, storeKey); err != nil {
+		reqLogger(c).Warn("Failed to release idempotency key", "error", err)
+	}
+}
+
+// storeIdempotent records a successful response for later replay; body may
+// be nil for bodiless responses.
+func (s *Server) storeIdempotent(c *gin.Context, storeKey, fingerprint string, status int, body interface{}) {
+	rec := idempotencyRecord{Hash: fingerprint, Status: status}
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return
+		}
+		rec.Body = payload
+	}
+	encoded, _ := json.Marshal(rec)
+	if err := s.state.Set(c.Request.Context(), idempotencyBucket, storeKey, encoded, s.Cfg().IdempotencyTTL); err != nil {
+		reqLogger(c).Warn("Failed to store idempotent response", "error", err)
+	}
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Admin impersonation: roles listed in IMPERSONATION_ROLES may send an
+// X-Impersonate-Role header to act as another role — after the server has
+// verified actual membership with pg_has_role, so the database stays the
+// authority on who may become whom. Both identities end up in the audit
+// trail.
+
+const impersonateHeader = "X-Impersonate-Role"
+
+// resolveImpersonation returns the role the request should run as. When an
+// impersonation header is present and allowed, the authenticated role is
+// recorded in the context (for audit) and the target role is returned.
+func (s *Server) resolveImpersonation(c *gin.Context, db *sql.DB, dbRole string) (string, error) {
+	target := c.GetHeader(impersonateHeader)
+	if target == "" || target == dbRole {
+		return dbRole, nil
+	}
+
+	allowed := false
+	for _, role := range s.Cfg().ImpersonationRoles {
+		if role == dbRole {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return "", fmt.Errorf("role %q is not allowed to impersonate", dbRole)
+	}
+
+	var member bool
+	if err := db.QueryRowContext(c.Request.Context(), `SELECT pg_has_role($1, $2, 'MEMBER')`, dbRole, target).Scan(&member); err != nil {
+		return "", fmt.Errorf("membership check failed: %w", err)
+	}
+	if !member {
+		return "", fmt.Errorf("role %q is not a member of %q", dbRole, target)
+	}
+
+	c.Set("impersonator", dbRole)
+	reqLogger(c).Info("Impersonation", "impersonator", dbRole, "role", target)
+	return target, nil
+}
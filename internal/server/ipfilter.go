@@ -0,0 +1,64 @@
+package server
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CIDR-based access rules: deny rules are checked first, then — when any
+// allow rule applies to the request — the client must match one. Rules come
+// in a global set and per-database sets; the database rules only kick in on
+// /api/:database routes. The client IP comes from gin's trusted-proxy-aware
+// resolution, so rules hold behind load balancers too.
+
+func matchesAny(ip net.IP, nets []*net.IPNet) bool {
+	for _, ipNet := range nets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) ipFilterMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cfg := s.Cfg()
+		if len(cfg.IPAllow) == 0 && len(cfg.IPDeny) == 0 && len(cfg.IPAllowDB) == 0 && len(cfg.IPDenyDB) == 0 {
+			c.Next()
+			return
+		}
+
+		ip := net.ParseIP(c.ClientIP())
+		if ip == nil {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+			return
+		}
+		dbName := c.Param("database")
+
+		deny := cfg.IPDeny
+		allow := cfg.IPAllow
+		if dbName != "" {
+			if dbRules, ok := cfg.IPDenyDB[dbName]; ok {
+				deny = append(append([]*net.IPNet{}, deny...), dbRules...)
+			}
+			if dbRules, ok := cfg.IPAllowDB[dbName]; ok {
+				// A database with its own allowlist is restricted to it.
+				allow = dbRules
+			}
+		}
+
+		if matchesAny(ip, deny) {
+			reqLogger(c).Warn("Request denied by IP rule", "ip", ip.String(), "database", dbName)
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+			return
+		}
+		if len(allow) > 0 && !matchesAny(ip, allow) {
+			reqLogger(c).Warn("Request outside IP allowlist", "ip", ip.String(), "database", dbName)
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+			return
+		}
+		c.Next()
+	}
+}
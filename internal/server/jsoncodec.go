@@ -0,0 +1,19 @@
+//go:build !fastjson
+
+package server
+
+import "encoding/json"
+
+// jsonMarshal and jsonUnmarshal are the encoding seam for the request hot
+// path (envelope decoding, params encoding and response writing all go
+// through them). The default build uses encoding/json; deployments that want
+// a faster encoder such as sonic or go-json can build with -tags fastjson
+// and supply a fastjson-tagged file defining the same two functions, without
+// adding the dependency to every build.
+func jsonMarshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func jsonUnmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,134 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bindJSONRPCRequest decodes the JSON-RPC envelope with the configured
+// hardening options applied: maximum nesting depth, unknown-field rejection
+// on the envelope, and duplicate-key detection. All checks default to off so
+// existing (sloppy) clients keep working unless the operator opts in.
+func (s *Server) bindJSONRPCRequest(c *gin.Context, req *JSONRPCRequest) error {
+	// The size limit applies to the raw bytes, before any decoding runs.
+	reader := io.Reader(c.Request.Body)
+	if limit := s.Cfg().JSONMaxBodyBytes; limit > 0 {
+		reader = io.LimitReader(reader, int64(limit)+1)
+	}
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return fmt.Errorf("failed to read request body: %w", err)
+	}
+	if limit := s.Cfg().JSONMaxBodyBytes; limit > 0 && len(body) > limit {
+		return fmt.Errorf("request body exceeds limit of %d bytes", limit)
+	}
+
+	// Binary-encoded bodies (msgpack, CBOR) are converted to JSON up front,
+	// so the hardening checks below apply to them as well; the depth limit
+	// is enforced during the conversion itself, since the decoders recurse.
+	if format := binaryRequestFormat(c); format != "" {
+		if body, err = decodeBinaryBody(body, format, s.Cfg().JSONMaxDepth); err != nil {
+			return err
+		}
+	}
+
+	if s.Cfg().JSONMaxDepth > 0 || s.Cfg().JSONRejectDuplicateKeys {
+		if err := checkJSONDocument(body, s.Cfg().JSONMaxDepth, s.Cfg().JSONRejectDuplicateKeys); err != nil {
+			return err
+		}
+	}
+
+	// Decode the envelope with params left as raw bytes, so the call path
+	// can forward them to Postgres without marshalling the map back to JSON.
+	var envelope struct {
+		JSONRPC string          `json:"jsonrpc"`
+		Method  string          `json:"method"`
+		Params  json.RawMessage `json:"params"`
+		ID      interface{}     `json:"id"`
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	if s.Cfg().JSONRejectUnknownFields {
+		decoder.DisallowUnknownFields()
+	}
+	if err := decoder.Decode(&envelope); err != nil {
+		return err
+	}
+	req.JSONRPC = envelope.JSONRPC
+	req.Method = envelope.Method
+	req.ID = envelope.ID
+	if len(envelope.Params) > 0 && string(envelope.Params) != "null" {
+		req.RawParams = envelope.Params
+		if err := jsonUnmarshal(envelope.Params, &req.Params); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkJSONDocument walks the token stream once, tracking nesting depth and
+// object keys per level. maxDepth=0 disables the depth check.
+func checkJSONDocument(data []byte, maxDepth int, rejectDuplicates bool) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	type frame struct {
+		isObject  bool
+		keys      map[string]bool
+		expectKey bool
+	}
+	var stack []*frame
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		top := (*frame)(nil)
+		if len(stack) > 0 {
+			top = stack[len(stack)-1]
+		}
+
+		switch t := token.(type) {
+		case json.Delim:
+			switch t {
+			case '{', '[':
+				if maxDepth > 0 && len(stack)+1 > maxDepth {
+					return fmt.Errorf("JSON nesting depth exceeds limit of %d", maxDepth)
+				}
+				stack = append(stack, &frame{isObject: t == '{', keys: nil, expectKey: t == '{'})
+			case '}', ']':
+				stack = stack[:len(stack)-1]
+				if len(stack) > 0 && stack[len(stack)-1].isObject {
+					stack[len(stack)-1].expectKey = true
+				}
+			}
+		case string:
+			if top != nil && top.isObject && top.expectKey {
+				if rejectDuplicates {
+					if top.keys == nil {
+						top.keys = make(map[string]bool)
+					}
+					if top.keys[t] {
+						return fmt.Errorf("duplicate object key %q in JSON document", t)
+					}
+					top.keys[t] = true
+				}
+				top.expectKey = false
+			} else if top != nil && top.isObject {
+				top.expectKey = true
+			}
+		default:
+			if top != nil && top.isObject {
+				top.expectKey = true
+			}
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JWT claims are exposed to SQL the way PostgREST does it: the full claim
+// set as request.jwt.claims (JSON) plus one convenience GUC per scalar
+// claim (request.jwt.claim.sub, request.jwt.claim.tenant_id, ...). All are
+// transaction-local (set_config third argument true), so RLS policies and
+// functions can authorize on user identity, not just the role name.
+
+// fetchLoginClaims calls the configured login function (LOGIN_FUNCTION,
+// e.g. pgarachne.login) on a connection authenticated as the user and
+// returns the extra claims it reports. A NULL result means no extra claims;
+// an error (e.g. RAISE for a disabled account) fails the login.
+func fetchLoginClaims(ctx context.Context, db *sql.DB, function, login, password string) (map[string]interface{}, error) {
+	var claimsJSON []byte
+	query := fmt.Sprintf("SELECT %s($1, $2)::jsonb", function)
+	if err := db.QueryRowContext(ctx, query, login, password).Scan(&claimsJSON); err != nil {
+		return nil, err
+	}
+	if len(claimsJSON) == 0 {
+		return nil, nil
+	}
+	var claims map[string]interface{}
+	if err := json.Unmarshal(claimsJSON, &claims); err != nil {
+		return nil, fmt.Errorf("login function returned invalid claims: %w", err)
+	}
+	return claims, nil
+}
+
+// setJWTClaimGUCs applies the authenticated request's claims inside the
+// open transaction. Requests authenticated by API token or mTLS have no
+// claims and set nothing.
+func setJWTClaimGUCs(ctx context.Context, tx *sql.Tx, c *gin.Context) error {
+	value, ok := c.Get("jwt_claims")
+	if !ok {
+		return nil
+	}
+	claims, ok := value.(map[string]interface{})
+	if !ok || len(claims) == 0 {
+		return nil
+	}
+
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `SELECT set_config('request.jwt.claims', $1, true)`, string(claimsJSON)); err != nil {
+		return err
+	}
+
+	for name, claim := range claims {
+		var text string
+		switch v := claim.(type) {
+		case string:
+			text = v
+		case float64, bool:
+			text = fmt.Sprint(v)
+		default:
+			// Nested objects and arrays stay available via the full
+			// request.jwt.claims document.
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, `SELECT set_config('request.jwt.claim.' || $1, $2, true)`, name, text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setApplicationName tags the backend as pgarachne/<function> for the rest
+// of the transaction, making pg_stat_activity attribution per API function.
+// Failures only cost the tag, never the call.
+func setApplicationName(c *gin.Context, tx *sql.Tx, functionName string) {
+	if functionName == "" {
+		return
+	}
+	query := `SELECT set_config('application_name', $1, true)`
+	if _, err := tx.ExecContext(c.Request.Context(), query, "pgarachne/"+functionName); err != nil {
+		reqLogger(c).Warn("Failed to set application_name", "function", functionName, "error", err)
+	}
+}
@@ -0,0 +1,37 @@
+package server
+
+import (
+	_ "embed"
+	"html/template"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Version is stamped by the main package at startup so the landing page can
+// report it without importing cmd.
+var Version = "dev"
+
+// Built-in landing page at /: server version, served databases and links to
+// capabilities and the console, so a fresh install greets with something
+// better than a 404. An operator-provided static directory replaces it.
+
+//go:embed landing/index.html
+var landingHTML string
+
+var landingTemplate = template.Must(template.New("landing").Parse(landingHTML))
+
+func (s *Server) registerLandingPage(router *gin.Engine) {
+	if s.Cfg().StaticFilesPath != "" {
+		return
+	}
+	router.GET("/", func(c *gin.Context) {
+		c.Header("Content-Type", "text/html; charset=utf-8")
+		c.Status(http.StatusOK)
+		landingTemplate.Execute(c.Writer, gin.H{
+			"Version":   Version,
+			"Databases": s.Cfg().Databases,
+			"UIEnabled": s.Cfg().UIEnabled,
+		})
+	})
+}
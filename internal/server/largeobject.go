@@ -0,0 +1,253 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/pgarachne/internal/database"
+)
+
+// Large object streaming: GET /api/:database/_lo/:function calls the
+// function (params from ?params=, a JSON object), expects it to return a
+// pg_largeobject OID — either bare or as {"oid": n} — and streams the
+// object's bytes to the client in chunks, honoring Range requests so video
+// players and download resumers work. POST /api/:database/_lo streams the
+// request body into a new large object and returns its OID. Neither
+// direction ever buffers the blob in memory; everything runs through
+// lo_open/loread/lowrite inside one transaction as the caller's role.
+
+const (
+	loChunkSize = 256 * 1024
+	loInvRead   = 0x40000
+	loInvWrite  = 0x20000
+)
+
+// loBeginTx opens the transaction every large-object operation runs in,
+// with role and claims applied; it writes the error response on failure.
+func (s *Server) loBeginTx(c *gin.Context) *sql.Tx {
+	databaseName := c.Param("database")
+	dbRole := c.GetString("db_role")
+
+	db, err := database.GetConnection(s.Cfg(), databaseName)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Database connection failed"})
+		return nil
+	}
+	tx, err := db.BeginTx(c.Request.Context(), nil)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Database unavailable"})
+		return nil
+	}
+
+	quotedRole := fmt.Sprintf(`"%s"`, strings.ReplaceAll(dbRole, `"`, `""`))
+	if _, err := tx.ExecContext(c.Request.Context(), fmt.Sprintf("SET LOCAL ROLE %s", quotedRole)); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied for the specified role"})
+		return nil
+	}
+	if err := setJWTClaimGUCs(c.Request.Context(), tx, c); err != nil {
+		tx.Rollback()
+		reqLogger(c).Error("Failed to set JWT claim GUCs", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply session claims"})
+		return nil
+	}
+	setApplicationName(c, tx, c.Param("function"))
+	return tx
+}
+
+// loOID extracts the large object OID from a function result.
+func loOID(result json.RawMessage) (int64, bool) {
+	var oid int64
+	if json.Unmarshal(result, &oid) == nil {
+		return oid, oid > 0
+	}
+	var wrapped struct {
+		OID int64 `json:"oid"`
+	}
+	if json.Unmarshal(result, &wrapped) == nil {
+		return wrapped.OID, wrapped.OID > 0
+	}
+	return 0, false
+}
+
+// parseRange interprets a single-range bytes= header against the object
+// size; ok is false for absent or unusable headers.
+func parseRange(header string, size int64) (start, end int64, ok bool) {
+	spec, found := strings.CutPrefix(header, "bytes=")
+	if !found || strings.Contains(spec, ",") {
+		return 0, 0, false
+	}
+	fromStr, toStr, found := strings.Cut(spec, "-")
+	if !found {
+		return 0, 0, false
+	}
+	if fromStr == "" { // suffix range: last N bytes
+		n, err := strconv.ParseInt(toStr, 10, 64)
+		if err != nil || n <= 0 {
+			return 0, 0, false
+		}
+		if n > size {
+			n = size
+		}
+		return size - n, size - 1, true
+	}
+	start, err := strconv.ParseInt(fromStr, 10, 64)
+	if err != nil || start >= size {
+		return 0, 0, false
+	}
+	end = size - 1
+	if toStr != "" {
+		if end, err = strconv.ParseInt(toStr, 10, 64); err != nil || end < start {
+			return 0, 0, false
+		}
+		if end >= size {
+			end = size - 1
+		}
+	}
+	return start, end, true
+}
+
+// handleLargeObjectDownload streams the large object named by the
+// function's result.
+func (s *Server) handleLargeObjectDownload(c *gin.Context) {
+	functionName := c.Param("function")
+	if functionName == "login" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Login must be called via the public endpoint"})
+		return
+	}
+	params := c.DefaultQuery("params", "{}")
+
+	tx := s.loBeginTx(c)
+	if tx == nil {
+		return
+	}
+	defer tx.Rollback()
+
+	var result json.RawMessage
+	query := fmt.Sprintf("SELECT %s($1::jsonb)::json", functionName)
+	if err := tx.QueryRowContext(c.Request.Context(), query, params).Scan(&result); err != nil {
+		reqLogger(c).Error("Large object function call failed", "function", functionName, "error", err)
+		if status, rpcErr, ok := sqlErrorResponse(err); ok {
+			c.JSON(status, gin.H{"error": rpcErr.Message, "data": rpcErr.Data})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Function call failed"})
+		}
+		return
+	}
+	oid, ok := loOID(result)
+	if !ok {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Function did not return a large object OID"})
+		return
+	}
+
+	var fd int
+	if err := tx.QueryRowContext(c.Request.Context(), `SELECT lo_open($1, $2)`, oid, loInvRead).Scan(&fd); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Large object not found"})
+		return
+	}
+	var size int64
+	if err := tx.QueryRowContext(c.Request.Context(), `SELECT lo_lseek64($1, 0, 2)`, fd).Scan(&size); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to size large object"})
+		return
+	}
+
+	start, end := int64(0), size-1
+	status := http.StatusOK
+	if header := c.GetHeader("Range"); header != "" {
+		var ok bool
+		if start, end, ok = parseRange(header, size); !ok {
+			c.Header("Content-Range", fmt.Sprintf("bytes */%d", size))
+			c.Status(http.StatusRequestedRangeNotSatisfiable)
+			return
+		}
+		status = http.StatusPartialContent
+		c.Header("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, size))
+	}
+	if _, err := tx.ExecContext(c.Request.Context(), `SELECT lo_lseek64($1, $2, 0)`, fd, start); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to seek large object"})
+		return
+	}
+
+	c.Header("Accept-Ranges", "bytes")
+	c.Header("Content-Length", strconv.FormatInt(end-start+1, 10))
+	c.Header("Content-Type", "application/octet-stream")
+	c.Status(status)
+
+	remaining := end - start + 1
+	for remaining > 0 {
+		chunkSize := int64(loChunkSize)
+		if remaining < chunkSize {
+			chunkSize = remaining
+		}
+		var chunk []byte
+		if err := tx.QueryRowContext(c.Request.Context(), `SELECT loread($1, $2)`, fd, chunkSize).Scan(&chunk); err != nil {
+			reqLogger(c).Error("Large object read failed", "oid", oid, "error", err)
+			return
+		}
+		if len(chunk) == 0 {
+			break
+		}
+		if _, err := c.Writer.Write(chunk); err != nil {
+			return
+		}
+		c.Writer.Flush()
+		remaining -= int64(len(chunk))
+	}
+	tx.Commit()
+}
+
+// handleLargeObjectUpload streams the request body into a new large object.
+func (s *Server) handleLargeObjectUpload(c *gin.Context) {
+	tx := s.loBeginTx(c)
+	if tx == nil {
+		return
+	}
+	defer tx.Rollback()
+
+	var oid int64
+	if err := tx.QueryRowContext(c.Request.Context(), `SELECT lo_create(0)`).Scan(&oid); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Large object creation denied"})
+		return
+	}
+	var fd int
+	if err := tx.QueryRowContext(c.Request.Context(), `SELECT lo_open($1, $2)`, oid, loInvWrite).Scan(&fd); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open large object"})
+		return
+	}
+
+	var total int64
+	buf := make([]byte, loChunkSize)
+	for {
+		n, readErr := c.Request.Body.Read(buf)
+		if n > 0 {
+			if _, err := tx.ExecContext(c.Request.Context(), `SELECT lowrite($1, $2)`, fd, buf[:n]); err != nil {
+				reqLogger(c).Error("Large object write failed", "oid", oid, "error", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write large object"})
+				return
+			}
+			total += int64(n)
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			// A truncated body (client disconnect, read timeout) must not be
+			// committed as a complete object.
+			reqLogger(c).Error("Large object upload body read failed", "oid", oid, "error", readErr)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Request body read failed"})
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit large object"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"oid": oid, "bytes": total})
+}
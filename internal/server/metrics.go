@@ -0,0 +1,40 @@
+package server
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Custom metrics exposed alongside the default Go runtime metrics on
+// /metrics.
+
+// slowCallsTotal counts function calls that exceeded SLOW_CALL_THRESHOLD_MS.
+var slowCallsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "pgarachne_slow_calls_total",
+	Help: "Number of function calls slower than the configured slow-call threshold.",
+}, []string{"database", "function"})
+
+// Security event counters: the signals a credential-stuffing or token-replay
+// pattern shows up in first.
+var (
+	loginAttemptsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pgarachne_login_attempts_total",
+		Help: "Login attempts by outcome (success or failure).",
+	}, []string{"database", "outcome"})
+	tokenFailuresTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pgarachne_token_failures_total",
+		Help: "Requests rejected because the bearer token or API token did not verify.",
+	}, []string{"database"})
+	lockoutsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pgarachne_login_lockouts_total",
+		Help: "Logins that crossed the repeated-failure threshold within the tracking window.",
+	}, []string{"database"})
+	corsRejectionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pgarachne_cors_rejections_total",
+		Help: "Requests rejected by the CORS policy.",
+	}, []string{"database"})
+	rateLimitHitsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pgarachne_rate_limit_hits_total",
+		Help: "Requests rejected by a rate or concurrency limit.",
+	}, []string{"database", "limiter"})
+)
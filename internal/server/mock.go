@@ -0,0 +1,71 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Mock mode (MOCK_FIXTURES_PATH): function calls are answered from JSON
+// fixture files instead of Postgres, so a frontend team can develop against
+// the API contract before the SQL exists. The fixture for api.get_orders is
+// <dir>/<database>/api.get_orders.json, falling back to
+// <dir>/api.get_orders.json; its content is served as the call's result.
+// MOCK_LATENCY_MS delays every response to approximate real call times.
+// Authentication still applies — use ANON_ROLE or a JWT, since API-token
+// verification would need the database mock mode is there to avoid.
+
+// handleMockCall replaces the transaction pipeline of handleFunctionCall.
+func (s *Server) handleMockCall(c *gin.Context, functionName string) {
+	var req JSONRPCRequest
+	if err := s.bindJSONRPCRequest(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, JSONRPCResponse{Error: &JSONRPCError{Message: fmt.Sprintf("Invalid JSON request: %v", err)}})
+		return
+	}
+	c.Set("jsonrpc_id", req.ID)
+	if functionName == "" {
+		functionName = req.Method
+	}
+	if functionName == "" {
+		c.JSON(http.StatusBadRequest, JSONRPCResponse{Error: &JSONRPCError{Code: -32600, Message: "Missing method"}, ID: req.ID})
+		return
+	}
+	// Function names are identifiers (optionally schema-qualified); anything
+	// else could walk out of the fixtures directory.
+	if strings.ContainsAny(functionName, `/\`) || strings.Contains(functionName, "..") {
+		c.JSON(http.StatusBadRequest, JSONRPCResponse{Error: &JSONRPCError{Message: "Invalid function name"}, ID: req.ID})
+		return
+	}
+
+	if s.Cfg().MockLatency > 0 {
+		time.Sleep(s.Cfg().MockLatency)
+	}
+
+	fixture, err := os.ReadFile(filepath.Join(s.Cfg().MockFixturesPath, c.Param("database"), functionName+".json"))
+	if err != nil {
+		fixture, err = os.ReadFile(filepath.Join(s.Cfg().MockFixturesPath, functionName+".json"))
+	}
+	if err != nil {
+		c.JSON(http.StatusNotFound, JSONRPCResponse{Error: &JSONRPCError{
+			Message: fmt.Sprintf("No fixture for function '%s'", functionName),
+		}, ID: req.ID})
+		return
+	}
+	if !json.Valid(fixture) {
+		reqLogger(c).Error("Mock fixture is not valid JSON", "function", functionName)
+		c.JSON(http.StatusInternalServerError, JSONRPCResponse{Error: &JSONRPCError{Message: "Fixture is not valid JSON"}, ID: req.ID})
+		return
+	}
+
+	if req.ID == nil {
+		c.Status(http.StatusNoContent)
+		return
+	}
+	writeRPCResponse(c, http.StatusOK, JSONRPCResponse{JSONRPC: "2.0", Result: json.RawMessage(fixture), ID: req.ID})
+}
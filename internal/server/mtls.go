@@ -0,0 +1,142 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// mtlsAuth verifies machine clients by certificate and maps the certificate
+// Common Name to a database role via TLS_CLIENT_ROLE_MAP_FILE (lines of
+// "common-name = role", '#' comments). Both the CA bundle and the mapping
+// reload when the files change, so enrolling a new client needs no restart.
+type mtlsAuth struct {
+	caFile  string
+	mapFile string
+
+	mu         sync.RWMutex
+	clientCAs  *x509.CertPool
+	roles      map[string]string
+	caModTime  time.Time
+	mapModTime time.Time
+
+	done chan struct{}
+}
+
+func newMTLSAuth(caFile, mapFile string) (*mtlsAuth, error) {
+	m := &mtlsAuth{
+		caFile:  caFile,
+		mapFile: mapFile,
+		done:    make(chan struct{}),
+	}
+	if err := m.reload(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *mtlsAuth) reload() error {
+	caPEM, err := os.ReadFile(m.caFile)
+	if err != nil {
+		return fmt.Errorf("failed to read client CA bundle: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return fmt.Errorf("client CA bundle %s contains no valid certificates", m.caFile)
+	}
+
+	mapData, err := os.ReadFile(m.mapFile)
+	if err != nil {
+		return fmt.Errorf("failed to read client role map: %w", err)
+	}
+	roles := make(map[string]string)
+	for lineNo, line := range strings.Split(string(mapData), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid role map line %d: '%s', expected common-name = role", lineNo+1, line)
+		}
+		roles[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+
+	caInfo, _ := os.Stat(m.caFile)
+	mapInfo, _ := os.Stat(m.mapFile)
+
+	m.mu.Lock()
+	m.clientCAs = pool
+	m.roles = roles
+	if caInfo != nil {
+		m.caModTime = caInfo.ModTime()
+	}
+	if mapInfo != nil {
+		m.mapModTime = mapInfo.ModTime()
+	}
+	m.mu.Unlock()
+	return nil
+}
+
+// getConfigForClient hands the TLS handshake a config with the current CA
+// pool, which is how the reloaded bundle takes effect per connection.
+func (m *mtlsAuth) getConfigForClient(base *tls.Config) func(*tls.ClientHelloInfo) (*tls.Config, error) {
+	return func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		m.mu.RLock()
+		pool := m.clientCAs
+		m.mu.RUnlock()
+		cfg := base.Clone()
+		cfg.ClientCAs = pool
+		return cfg, nil
+	}
+}
+
+// roleFor maps a verified client certificate CN to a database role.
+func (m *mtlsAuth) roleFor(commonName string) (string, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	role, ok := m.roles[commonName]
+	return role, ok
+}
+
+// watch polls the file mtimes, same approach as the server certificate
+// reloader.
+func (m *mtlsAuth) watch() {
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.done:
+			return
+		case <-ticker.C:
+			caInfo, errCA := os.Stat(m.caFile)
+			mapInfo, errMap := os.Stat(m.mapFile)
+			if errCA != nil || errMap != nil {
+				slog.Warn("Failed to stat mTLS files", "ca_error", errCA, "map_error", errMap)
+				continue
+			}
+			m.mu.RLock()
+			changed := caInfo.ModTime().After(m.caModTime) || mapInfo.ModTime().After(m.mapModTime)
+			m.mu.RUnlock()
+			if !changed {
+				continue
+			}
+			if err := m.reload(); err != nil {
+				slog.Error("Failed to reload mTLS client allow-list", "error", err)
+				continue
+			}
+			slog.Info("Reloaded mTLS client allow-list")
+		}
+	}
+}
+
+func (m *mtlsAuth) stop(context.Context) error {
+	close(m.done)
+	return nil
+}
@@ -0,0 +1,140 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Object storage offload: POST /api/:database/_files streams the request
+// body to the configured S3-compatible backend and hands only the generated
+// key plus metadata to SQL — either in the response for the client to pass
+// on, or directly to the function named with ?function=. GET
+// /api/:database/_files/*key redirects to a presigned URL (or proxies the
+// bytes with ?proxy=1), so huge binaries never touch Postgres.
+
+// objectKey builds a collision-free key under the database's prefix,
+// keeping the original extension so content types survive.
+func objectKey(databaseName, filename string) string {
+	raw := make([]byte, 16)
+	rand.Read(raw)
+	return fmt.Sprintf("%s/%s/%s%s",
+		databaseName, time.Now().UTC().Format("2006/01/02"), hex.EncodeToString(raw), path.Ext(filename))
+}
+
+func (s *Server) handleObjectUpload(c *gin.Context) {
+	if s.storage == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Object storage not configured"})
+		return
+	}
+	databaseName := c.Param("database")
+
+	filename := c.Query("filename")
+	key := objectKey(databaseName, filename)
+	contentType := c.ContentType()
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	if err := s.storage.Put(c.Request.Context(), key, contentType, c.Request.Body, c.Request.ContentLength); err != nil {
+		reqLogger(c).Error("Object upload failed", "key", key, "error", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Object storage upload failed"})
+		return
+	}
+
+	meta := gin.H{
+		"key":          key,
+		"size":         c.Request.ContentLength,
+		"content_type": contentType,
+		"filename":     filename,
+	}
+
+	// ?function= records the upload in SQL in the same request; a failure
+	// there removes the orphaned object again.
+	if functionName := c.Query("function"); functionName != "" {
+		result, err := s.callObjectFunction(c, functionName, meta)
+		if err != nil {
+			s.storage.Delete(c.Request.Context(), key)
+			return // callObjectFunction wrote the response
+		}
+		meta["result"] = result
+	}
+
+	c.JSON(http.StatusCreated, meta)
+}
+
+// callObjectFunction passes the upload metadata to a SQL function as the
+// caller's role.
+func (s *Server) callObjectFunction(c *gin.Context, functionName string, meta gin.H) (json.RawMessage, error) {
+	tx := s.loBeginTx(c)
+	if tx == nil {
+		return nil, fmt.Errorf("transaction setup failed")
+	}
+	defer tx.Rollback()
+
+	params, err := json.Marshal(meta)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to marshal metadata"})
+		return nil, err
+	}
+	var result json.RawMessage
+	query := fmt.Sprintf("SELECT %s($1::jsonb)::json", functionName)
+	if err := tx.QueryRowContext(c.Request.Context(), query, params).Scan(&result); err != nil {
+		reqLogger(c).Error("Upload function call failed", "function", functionName, "error", err)
+		if status, rpcErr, ok := sqlErrorResponse(err); ok {
+			c.JSON(status, gin.H{"error": rpcErr.Message, "data": rpcErr.Data})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Function call failed"})
+		}
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Transaction commit failed"})
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *Server) handleObjectDownload(c *gin.Context) {
+	if s.storage == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Object storage not configured"})
+		return
+	}
+	databaseName := c.Param("database")
+	key := strings.TrimPrefix(c.Param("key"), "/")
+
+	// Keys are namespaced per database; a token for one database must not
+	// fetch another database's objects.
+	if !strings.HasPrefix(key, databaseName+"/") || strings.Contains(key, "..") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Key outside this database's namespace"})
+		return
+	}
+
+	if c.Query("proxy") == "" {
+		if presigned := s.storage.PresignGet(key, s.Cfg().S3PresignTTL); presigned != "" {
+			c.Redirect(http.StatusFound, presigned)
+			return
+		}
+	}
+
+	object, err := s.storage.Get(c.Request.Context(), key)
+	if err != nil {
+		reqLogger(c).Warn("Object download failed", "key", key, "error", err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Object not found"})
+		return
+	}
+	defer object.Body.Close()
+
+	contentType := object.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	c.DataFromReader(http.StatusOK, object.ContentLength, contentType, object.Body, nil)
+}
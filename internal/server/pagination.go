@@ -0,0 +1,137 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+)
+
+// Automatic pagination for functions annotated with "@list" in their COMMENT.
+// Clients send page/page_size in params; the server translates them into
+// limit/offset, wraps the result in {items, total, next} and computes total
+// via the companion "<function>_count" function (same params, no pagination).
+
+const defaultPageSize = 50
+const maxPageSize = 1000
+
+type paginationRequest struct {
+	page     int
+	pageSize int
+}
+
+// extractPagination pulls page/page_size out of the params (removing them)
+// and replaces them with limit/offset for the SQL function. Returns nil when
+// the client did not ask for pagination.
+func extractPagination(params map[string]interface{}) *paginationRequest {
+	pageValue, ok := params["page"]
+	if !ok {
+		return nil
+	}
+	page := intParam(pageValue, 1)
+	if page < 1 {
+		page = 1
+	}
+	pageSize := intParam(params["page_size"], defaultPageSize)
+	if pageSize < 1 {
+		pageSize = defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+
+	delete(params, "page")
+	delete(params, "page_size")
+	params["limit"] = pageSize
+	params["offset"] = (page - 1) * pageSize
+
+	return &paginationRequest{page: page, pageSize: pageSize}
+}
+
+// intParam converts a decoded JSON value (float64 or string) to int.
+func intParam(value interface{}, fallback int) int {
+	switch v := value.(type) {
+	case float64:
+		return int(v)
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return int(n)
+		}
+	}
+	return fallback
+}
+
+// isListFunction reports whether the function's COMMENT carries the "@list"
+// annotation, enabling the pagination envelope.
+func (s *Server) isListFunction(ctx context.Context, db *sql.DB, databaseName, functionName string) bool {
+	cacheKey := databaseName + "/" + functionName
+	if cached, ok := s.listFuncs.get(cacheKey); ok {
+		return cached
+	}
+
+	schema, name := splitFunctionName(functionName, "public")
+	query := `
+		SELECT COALESCE(obj_description(p.oid, 'pg_proc'), '') LIKE '%@list%'
+		FROM pg_proc p
+		JOIN pg_namespace n ON n.oid = p.pronamespace
+		WHERE n.nspname = $1 AND p.proname = $2
+		LIMIT 1`
+
+	var isList bool
+	if err := db.QueryRowContext(ctx, query, schema, name).Scan(&isList); err != nil {
+		if err != sql.ErrNoRows {
+			slog.Warn("Failed to determine list annotation", "function", functionName, "error", err)
+		}
+		isList = false
+	}
+
+	s.listFuncs.set(cacheKey, isList)
+	return isList
+}
+
+// countFunctionTotal runs the companion "<function>_count" function inside
+// the call's transaction. It checks existence via the catalog first, so a
+// missing count function doesn't abort the transaction; in that case (or on
+// error) total is -1 and the envelope carries null.
+func countFunctionTotal(ctx context.Context, tx *sql.Tx, functionName string, countParams []byte) int64 {
+	schema, name := splitFunctionName(functionName, "public")
+	var exists bool
+	err := tx.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM pg_proc p
+			JOIN pg_namespace n ON n.oid = p.pronamespace
+			WHERE n.nspname = $1 AND p.proname = $2
+		)`, schema, name+"_count").Scan(&exists)
+	if err != nil || !exists {
+		if err != nil {
+			slog.Warn("Failed to check companion count function", "function", functionName+"_count", "error", err)
+		}
+		return -1
+	}
+
+	var total int64
+	query := "SELECT " + quoteQualifiedIdentifier(schema, name+"_count") + "($1::jsonb)"
+	if err := tx.QueryRowContext(ctx, query, countParams).Scan(&total); err != nil {
+		slog.Warn("Companion count function failed", "function", functionName+"_count", "error", err)
+		return -1
+	}
+	return total
+}
+
+// paginationEnvelope wraps the raw result. total is -1 when the companion
+// count function is missing or failed; next is null on the last page.
+func paginationEnvelope(items json.RawMessage, total int64, p *paginationRequest) interface{} {
+	envelope := map[string]interface{}{
+		"items": items,
+		"next":  nil,
+	}
+	if total >= 0 {
+		envelope["total"] = total
+		if int64(p.page)*int64(p.pageSize) < total {
+			envelope["next"] = p.page + 1
+		}
+	} else {
+		envelope["total"] = nil
+	}
+	return envelope
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sync"
+)
+
+// Parameter validation against the schemas pgarachne.capabilities() publishes
+// per function: required fields must be present and declared types must
+// match, reported as JSON-RPC -32602 with field-level details before the
+// database is ever hit. Unknown fields pass — the default capability schema
+// for unannotated functions does not list real parameters, so rejecting
+// extras would break them. Validation fails open when no schema is known.
+
+// paramProperty is one entry of a capability "parameters.properties" object.
+type paramProperty struct {
+	Type string `json:"type"`
+}
+
+type paramSchema struct {
+	Properties map[string]paramProperty `json:"properties"`
+	Required   []string                 `json:"required"`
+}
+
+// capabilitySchemaCache holds the parsed schemas per database, fetched once
+// per database from pgarachne.capabilities().
+type capabilitySchemaCache struct {
+	mu      sync.RWMutex
+	schemas map[string]map[string]paramSchema
+}
+
+func newCapabilitySchemaCache() *capabilitySchemaCache {
+	return &capabilitySchemaCache{schemas: make(map[string]map[string]paramSchema)}
+}
+
+// get returns the schema map for a database, loading it on first use. A
+// database without a capabilities function caches an empty map, so the
+// lookup is not retried per request.
+func (cc *capabilitySchemaCache) get(ctx context.Context, db *sql.DB, databaseName string) map[string]paramSchema {
+	cc.mu.RLock()
+	schemas, ok := cc.schemas[databaseName]
+	cc.mu.RUnlock()
+	if ok {
+		return schemas
+	}
+
+	schemas = make(map[string]paramSchema)
+	var raw json.RawMessage
+	if err := db.QueryRowContext(ctx, `SELECT pgarachne.capabilities('{}'::jsonb)::json`).Scan(&raw); err == nil {
+		var methods []struct {
+			Method     string      `json:"method"`
+			Parameters paramSchema `json:"parameters"`
+		}
+		if err := json.Unmarshal(raw, &methods); err == nil {
+			for _, m := range methods {
+				schemas[m.Method] = m.Parameters
+			}
+		}
+	}
+
+	cc.mu.Lock()
+	cc.schemas[databaseName] = schemas
+	cc.mu.Unlock()
+	return schemas
+}
+
+// matchesJSONType checks a decoded JSON value against a JSON-schema type
+// name. Unknown type names pass.
+func matchesJSONType(value interface{}, typeName string) bool {
+	switch typeName {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		f, ok := value.(float64)
+		return ok && f == math.Trunc(f)
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "null":
+		return value == nil
+	default:
+		return true
+	}
+}
+
+// validateParams checks params against the function's published schema and
+// returns one message per violated field, empty when the call is fine.
+func (s *Server) validateParams(ctx context.Context, db *sql.DB, databaseName, functionName string, params map[string]interface{}) []string {
+	schema, ok := s.capSchemas.get(ctx, db, databaseName)[functionName]
+	if !ok {
+		return nil
+	}
+
+	var errs []string
+	for _, name := range schema.Required {
+		if _, present := params[name]; !present {
+			errs = append(errs, fmt.Sprintf("missing required parameter '%s'", name))
+		}
+	}
+	for name, value := range params {
+		prop, declared := schema.Properties[name]
+		if !declared || prop.Type == "" {
+			continue
+		}
+		if !matchesJSONType(value, prop.Type) {
+			errs = append(errs, fmt.Sprintf("parameter '%s' must be of type %s", name, prop.Type))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,283 @@
+package server
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Parquet file download (format=parquet, Accept: application/vnd.apache.parquet)
+// for tabular results. The file is the simplest valid layout — one row group,
+// one PLAIN-encoded uncompressed data page per column, optional fields with
+// bit-packed definition levels — and the footer metadata is written with the
+// small Thrift compact-protocol encoder below, so no Parquet dependency is
+// needed. Column typing follows arrow.go: float64, bool or utf8 byte arrays.
+
+// Thrift compact protocol wire types.
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// Parquet enum values used below.
+const (
+	parquetBoolean      = 0 // Type
+	parquetDouble       = 5
+	parquetByteArray    = 6
+	parquetOptional     = 1 // FieldRepetitionType
+	parquetUTF8         = 0 // ConvertedType
+	parquetPlain        = 0 // Encoding
+	parquetRLE          = 3
+	parquetUncompressed = 0 // CompressionCodec
+	parquetDataPage     = 0 // PageType
+)
+
+// thriftWriter emits the compact protocol: field headers are a type nibble
+// plus a field-id delta, integers are zigzag varints.
+type thriftWriter struct {
+	buf    *bytes.Buffer
+	lastID []int // field-id stack, one entry per open struct
+}
+
+func newThriftWriter(buf *bytes.Buffer) *thriftWriter {
+	return &thriftWriter{buf: buf, lastID: []int{0}}
+}
+
+func (w *thriftWriter) varint(v uint64) {
+	var tmp [10]byte
+	n := binary.PutUvarint(tmp[:], v)
+	w.buf.Write(tmp[:n])
+}
+
+func (w *thriftWriter) zigzag(v int64) {
+	w.varint(uint64((v << 1) ^ (v >> 63)))
+}
+
+func (w *thriftWriter) field(typ byte, id int) {
+	delta := id - w.lastID[len(w.lastID)-1]
+	if delta > 0 && delta < 16 {
+		w.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		w.buf.WriteByte(typ)
+		w.zigzag(int64(id))
+	}
+	w.lastID[len(w.lastID)-1] = id
+}
+
+func (w *thriftWriter) i32Field(id int, v int64) {
+	w.field(thriftI32, id)
+	w.zigzag(v)
+}
+
+func (w *thriftWriter) i64Field(id int, v int64) {
+	w.field(thriftI64, id)
+	w.zigzag(v)
+}
+
+func (w *thriftWriter) stringField(id int, s string) {
+	w.field(thriftBinary, id)
+	w.varint(uint64(len(s)))
+	w.buf.WriteString(s)
+}
+
+func (w *thriftWriter) listField(id, size int, elemType byte) {
+	w.field(thriftList, id)
+	if size < 15 {
+		w.buf.WriteByte(byte(size)<<4 | elemType)
+	} else {
+		w.buf.WriteByte(0xF0 | elemType)
+		w.varint(uint64(size))
+	}
+}
+
+func (w *thriftWriter) structField(id int) {
+	w.field(thriftStruct, id)
+	w.structBegin()
+}
+
+func (w *thriftWriter) structBegin() { w.lastID = append(w.lastID, 0) }
+
+func (w *thriftWriter) structEnd() {
+	w.buf.WriteByte(0) // STOP
+	w.lastID = w.lastID[:len(w.lastID)-1]
+}
+
+// parquetType maps the arrow.go column type to the Parquet physical type.
+func parquetType(colType int) int64 {
+	switch colType {
+	case arrowColFloat:
+		return parquetDouble
+	case arrowColBool:
+		return parquetBoolean
+	default:
+		return parquetByteArray
+	}
+}
+
+// parquetDefLevels encodes the definition levels (1 bit, 1 = present) as a
+// length-prefixed bit-packed RLE-hybrid run.
+func parquetDefLevels(valid []bool) []byte {
+	groups := (len(valid) + 7) / 8
+	payload := make([]byte, 0, groups+2)
+	var tmp [10]byte
+	n := binary.PutUvarint(tmp[:], uint64(groups)<<1|1) // bit-packed header
+	payload = append(payload, tmp[:n]...)
+	packed := make([]byte, groups)
+	for i, set := range valid {
+		if set {
+			packed[i/8] |= 1 << (i % 8)
+		}
+	}
+	payload = append(payload, packed...)
+
+	out := make([]byte, 4, 4+len(payload))
+	binary.LittleEndian.PutUint32(out, uint32(len(payload)))
+	return append(out, payload...)
+}
+
+// parquetPlainValues encodes the non-null values of one column PLAIN.
+func parquetPlainValues(rows []map[string]interface{}, column string, colType int, valid []bool) []byte {
+	var out []byte
+	switch colType {
+	case arrowColFloat:
+		var tmp [8]byte
+		for _, row := range rows {
+			if v, ok := row[column].(float64); ok {
+				binary.LittleEndian.PutUint64(tmp[:], math.Float64bits(v))
+				out = append(out, tmp[:]...)
+			}
+		}
+	case arrowColBool:
+		bit := 0
+		for i, row := range rows {
+			if !valid[i] {
+				continue
+			}
+			if bit%8 == 0 {
+				out = append(out, 0)
+			}
+			if v, _ := row[column].(bool); v {
+				out[len(out)-1] |= 1 << (bit % 8)
+			}
+			bit++
+		}
+	default:
+		var tmp [4]byte
+		for i, row := range rows {
+			if !valid[i] {
+				continue
+			}
+			s := tabularCell(row[column])
+			binary.LittleEndian.PutUint32(tmp[:], uint32(len(s)))
+			out = append(out, tmp[:]...)
+			out = append(out, s...)
+		}
+	}
+	return out
+}
+
+// writeParquet serves rows as a single-row-group Parquet file download.
+func writeParquet(c *gin.Context, functionName string, rows []map[string]interface{}, columns []string) {
+	c.Header("Content-Type", "application/vnd.apache.parquet")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.parquet"`, functionName))
+	c.Status(200)
+
+	types := arrowColumnTypes(rows, columns)
+	var file bytes.Buffer
+	file.WriteString("PAR1")
+
+	// One data page per column; offsets and sizes feed the footer below.
+	pageOffsets := make([]int64, len(columns))
+	pageSizes := make([]int64, len(columns))
+	for i, column := range columns {
+		valid := make([]bool, len(rows))
+		for r, row := range rows {
+			value, present := row[column]
+			valid[r] = present && value != nil
+		}
+		page := parquetDefLevels(valid)
+		page = append(page, parquetPlainValues(rows, column, types[i], valid)...)
+
+		pageOffsets[i] = int64(file.Len())
+		header := newThriftWriter(&file)
+		header.i32Field(1, parquetDataPage)
+		header.i32Field(2, int64(len(page)))
+		header.i32Field(3, int64(len(page)))
+		header.structField(5) // data_page_header
+		header.i32Field(1, int64(len(rows)))
+		header.i32Field(2, parquetPlain)
+		header.i32Field(3, parquetRLE)
+		header.i32Field(4, parquetRLE)
+		header.structEnd()
+		file.WriteByte(0) // end of PageHeader struct
+		file.Write(page)
+		pageSizes[i] = int64(file.Len()) - pageOffsets[i]
+	}
+
+	// Footer: FileMetaData.
+	footerStart := file.Len()
+	w := newThriftWriter(&file)
+	w.i32Field(1, 1) // version
+
+	w.listField(2, len(columns)+1, thriftStruct)
+	w.structBegin() // root schema element
+	w.stringField(4, "schema")
+	w.i32Field(5, int64(len(columns)))
+	w.structEnd()
+	for i, column := range columns {
+		w.structBegin()
+		w.i32Field(1, parquetType(types[i]))
+		w.i32Field(3, parquetOptional)
+		w.stringField(4, column)
+		if types[i] == arrowColUtf8 {
+			w.i32Field(6, parquetUTF8)
+		}
+		w.structEnd()
+	}
+
+	w.i64Field(3, int64(len(rows)))
+
+	w.listField(4, 1, thriftStruct) // row_groups
+	w.structBegin()
+	w.listField(1, len(columns), thriftStruct)
+	var totalSize int64
+	for _, size := range pageSizes {
+		totalSize += size
+	}
+	for i, column := range columns {
+		w.structBegin() // ColumnChunk
+		w.i64Field(2, pageOffsets[i])
+		w.structField(3) // ColumnMetaData
+		w.i32Field(1, parquetType(types[i]))
+		w.listField(2, 1, thriftI32)
+		w.zigzag(parquetPlain)
+		w.listField(3, 1, thriftBinary)
+		w.varint(uint64(len(column)))
+		w.buf.WriteString(column)
+		w.i32Field(4, parquetUncompressed)
+		w.i64Field(5, int64(len(rows)))
+		w.i64Field(6, pageSizes[i])
+		w.i64Field(7, pageSizes[i])
+		w.i64Field(9, pageOffsets[i])
+		w.structEnd()
+		w.structEnd()
+	}
+	w.i64Field(2, totalSize)
+	w.i64Field(3, int64(len(rows)))
+	w.structEnd()
+
+	file.WriteByte(0) // end of FileMetaData struct
+
+	var footerLen [4]byte
+	binary.LittleEndian.PutUint32(footerLen[:], uint32(file.Len()-footerStart))
+	file.Write(footerLen[:])
+	file.WriteString("PAR1")
+
+	c.Writer.Write(file.Bytes())
+}
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestThriftWriterFields(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(w *thriftWriter)
+		want  []byte
+	}{
+		{
+			"short-form field header with zigzag value",
+			func(w *thriftWriter) { w.i32Field(1, 1) },
+			[]byte{0x15, 0x02},
+		},
+		{
+			"negative value",
+			func(w *thriftWriter) { w.i32Field(1, -3) },
+			[]byte{0x15, 0x05},
+		},
+		{
+			"multi-byte varint",
+			func(w *thriftWriter) { w.i64Field(2, 300) },
+			[]byte{0x26, 0xd8, 0x04},
+		},
+		{
+			"long-form header for a field-id delta of 16 or more",
+			func(w *thriftWriter) { w.i32Field(20, 0) },
+			[]byte{0x05, 0x28, 0x00},
+		},
+		{
+			"delta accumulates across fields",
+			func(w *thriftWriter) { w.i32Field(1, 0); w.i32Field(3, 0) },
+			[]byte{0x15, 0x00, 0x25, 0x00},
+		},
+		{
+			"string field is length-prefixed, not zigzagged",
+			func(w *thriftWriter) { w.stringField(4, "ab") },
+			[]byte{0x48, 0x02, 'a', 'b'},
+		},
+		{
+			"short-form list header",
+			func(w *thriftWriter) { w.listField(2, 3, thriftStruct) },
+			[]byte{0x29, 0x3c},
+		},
+		{
+			"long-form list header for 15+ elements",
+			func(w *thriftWriter) { w.listField(2, 20, thriftStruct) },
+			[]byte{0x29, 0xfc, 0x14},
+		},
+		{
+			"nested struct restarts field ids and restores them on end",
+			func(w *thriftWriter) {
+				w.i32Field(1, 0)
+				w.structField(3)
+				w.i32Field(1, 5)
+				w.structEnd()
+				w.i32Field(4, -1)
+			},
+			[]byte{0x15, 0x00, 0x2c, 0x15, 0x0a, 0x00, 0x15, 0x01},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tt.write(newThriftWriter(&buf))
+			if !bytes.Equal(buf.Bytes(), tt.want) {
+				t.Errorf("wrote %x; want %x", buf.Bytes(), tt.want)
+			}
+		})
+	}
+}
+
+func TestParquetDefLevels(t *testing.T) {
+	// Three rows, middle one null: one bit-packed group, header
+	// uvarint(1<<1|1) = 0x03, bits 0b101 = 0x05, 4-byte length prefix.
+	got := parquetDefLevels([]bool{true, false, true})
+	want := []byte{0x02, 0x00, 0x00, 0x00, 0x03, 0x05}
+	if !bytes.Equal(got, want) {
+		t.Errorf("parquetDefLevels = %x; want %x", got, want)
+	}
+
+	// Nine rows need two groups; the ninth bit lands in the second byte.
+	valid := make([]bool, 9)
+	valid[8] = true
+	got = parquetDefLevels(valid)
+	want = []byte{0x03, 0x00, 0x00, 0x00, 0x05, 0x00, 0x01}
+	if !bytes.Equal(got, want) {
+		t.Errorf("parquetDefLevels = %x; want %x", got, want)
+	}
+}
+
+func TestParquetPlainValues(t *testing.T) {
+	rows := []map[string]interface{}{
+		{"f": 1.5, "b": true, "s": "ab"},
+		{"f": nil, "b": false, "s": nil},
+		{"f": 2.0, "b": true, "s": "c"},
+	}
+	valid := func(column string) []bool {
+		out := make([]bool, len(rows))
+		for i, row := range rows {
+			out[i] = row[column] != nil
+		}
+		return out
+	}
+
+	// Floats: only the two non-null values, little-endian IEEE 754.
+	got := parquetPlainValues(rows, "f", arrowColFloat, valid("f"))
+	want := []byte{
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xf8, 0x3f, // 1.5
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x40, // 2.0
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("float values = %x; want %x", got, want)
+	}
+
+	// Booleans: bit-packed LSB-first, true/false/true = 0b101.
+	got = parquetPlainValues(rows, "b", arrowColBool, valid("b"))
+	if !bytes.Equal(got, []byte{0x05}) {
+		t.Errorf("bool values = %x; want 05", got)
+	}
+
+	// Strings: 4-byte length prefix per non-null value.
+	got = parquetPlainValues(rows, "s", arrowColUtf8, valid("s"))
+	want = []byte{0x02, 0x00, 0x00, 0x00, 'a', 'b', 0x01, 0x00, 0x00, 0x00, 'c'}
+	if !bytes.Equal(got, want) {
+		t.Errorf("string values = %x; want %x", got, want)
+	}
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/pgarachne/internal/database"
+)
+
+// Self-service password rotation: POST /api/{database}/password verifies
+// the current password the same way login does (a direct connection as the
+// user) and then changes it on that connection — either through the
+// delegated PASSWORD_FUNCTION or with a plain ALTER ROLE. Because the
+// change runs as the user themselves, no elevated credentials are involved.
+
+type passwordChangeRequest struct {
+	Login           string `json:"login" binding:"required"`
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+// quoteLiteral makes a string safe for embedding as a SQL string literal;
+// ALTER ROLE cannot take bind parameters.
+func quoteLiteral(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+func (s *Server) handlePasswordChange(c *gin.Context) {
+	var req passwordChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Expected login, current_password and new_password"})
+		return
+	}
+	if len(req.NewPassword) < 8 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "New password must be at least 8 characters"})
+		return
+	}
+
+	// Verify the current credentials exactly like login does.
+	primaryHost, primaryPort := database.PrimaryAddr(s.Cfg())
+	connStr := database.ConnString(s.Cfg(), c.Param("database"), primaryHost, primaryPort) +
+		fmt.Sprintf(" user=%s password=%s", req.Login, req.CurrentPassword)
+	userDB, err := sql.Open("postgres", connStr)
+	if err != nil {
+		reqLogger(c).Error("Failed to open verification connection", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal authentication error"})
+		return
+	}
+	defer userDB.Close()
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+	defer cancel()
+	if err := userDB.PingContext(ctx); err != nil {
+		reqLogger(c).Warn("Password change authentication failed", "user", req.Login, "error", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid login or password"})
+		return
+	}
+
+	if s.Cfg().PasswordFunction != "" {
+		// Delegated change: the function runs as the user and can enforce
+		// policy (history, complexity) before applying the new password.
+		query := fmt.Sprintf("SELECT %s($1)", s.Cfg().PasswordFunction)
+		if _, err := userDB.ExecContext(ctx, query, req.NewPassword); err != nil {
+			reqLogger(c).Error("Password function failed", "function", s.Cfg().PasswordFunction, "user", req.Login, "error", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Password change rejected"})
+			return
+		}
+	} else {
+		quotedRole := fmt.Sprintf(`"%s"`, strings.ReplaceAll(req.Login, `"`, `""`))
+		statement := fmt.Sprintf("ALTER ROLE %s PASSWORD %s", quotedRole, quoteLiteral(req.NewPassword))
+		if _, err := userDB.ExecContext(ctx, statement); err != nil {
+			reqLogger(c).Error("ALTER ROLE PASSWORD failed", "user", req.Login, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Password change failed"})
+			return
+		}
+	}
+
+	reqLogger(c).Info("Password changed", "user", req.Login)
+	c.JSON(http.StatusOK, gin.H{"status": "password changed"})
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/pgarachne/internal/auth"
+	"github.com/yourusername/pgarachne/internal/database"
+)
+
+// Password reset flow with one-time tokens. The server generates the token
+// and stores only its SHA-256 hash via pgarachne.create_reset_token; the
+// raw token is published on the pgarachne_password_reset NOTIFY channel so
+// the webhook dispatcher (or any listener) can deliver it to the user.
+// Confirmation goes through pgarachne.consume_reset_token, which validates
+// expiry and single use and applies the new password in SQL.
+
+const passwordResetChannel = "pgarachne_password_reset"
+
+func (s *Server) handlePasswordResetRequest(c *gin.Context) {
+	var req struct {
+		Login string `json:"login" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Expected login"})
+		return
+	}
+
+	// Whatever happens below, the response is the same: the endpoint must
+	// not reveal whether an account exists.
+	respond := func() {
+		c.JSON(http.StatusOK, gin.H{"status": "If the account exists, a reset token has been issued"})
+	}
+
+	db, err := database.GetConnection(s.Cfg(), c.Param("database"))
+	if err != nil {
+		reqLogger(c).Error("Password reset: database connection failed", "error", err)
+		respond()
+		return
+	}
+
+	raw := make([]byte, 32)
+	rand.Read(raw)
+	token := hex.EncodeToString(raw)
+
+	if _, err := db.ExecContext(c.Request.Context(),
+		`SELECT pgarachne.create_reset_token($1, $2)`, req.Login, auth.HashToken(token)); err != nil {
+		// Unknown logins land here too; log at debug level only.
+		reqLogger(c).Debug("Password reset token not created", "login", req.Login, "error", err)
+		respond()
+		return
+	}
+
+	// Hand the raw token to the delivery side (e-mail sender subscribed to
+	// the channel); it never appears in the HTTP response.
+	payload, _ := json.Marshal(gin.H{"login": req.Login, "token": token})
+	if _, err := db.ExecContext(c.Request.Context(),
+		`SELECT pg_notify($1, $2)`, passwordResetChannel, string(payload)); err != nil {
+		reqLogger(c).Error("Password reset notification failed", "login", req.Login, "error", err)
+	}
+	respond()
+}
+
+func (s *Server) handlePasswordResetConfirm(c *gin.Context) {
+	var req struct {
+		Token       string `json:"token" binding:"required"`
+		NewPassword string `json:"new_password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Expected token and new_password"})
+		return
+	}
+	if len(req.NewPassword) < 8 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "New password must be at least 8 characters"})
+		return
+	}
+
+	db, err := database.GetConnection(s.Cfg(), c.Param("database"))
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Database connection failed"})
+		return
+	}
+
+	var consumed bool
+	if err := db.QueryRowContext(c.Request.Context(),
+		`SELECT pgarachne.consume_reset_token($1, $2)`, auth.HashToken(req.Token), req.NewPassword).Scan(&consumed); err != nil {
+		reqLogger(c).Error("Password reset confirmation failed", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Password reset failed"})
+		return
+	}
+	if !consumed {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired reset token"})
+		return
+	}
+	reqLogger(c).Info("Password reset completed")
+	c.JSON(http.StatusOK, gin.H{"status": "password changed"})
+}
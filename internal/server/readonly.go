@@ -0,0 +1,97 @@
+package server
+
+import (
+	"database/sql"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// funcFlagCache remembers a per-function boolean derived from the catalog
+// (read-only routing, list annotation, ...), so we only hit the catalog once
+// per database/function pair.
+type funcFlagCache struct {
+	mu      sync.RWMutex
+	entries map[string]bool
+}
+
+func newFuncFlagCache() *funcFlagCache {
+	return &funcFlagCache{entries: make(map[string]bool)}
+}
+
+func (c *funcFlagCache) get(key string) (bool, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	v, ok := c.entries[key]
+	return v, ok
+}
+
+func (c *funcFlagCache) set(key string, v bool) {
+	c.mu.Lock()
+	c.entries[key] = v
+	c.mu.Unlock()
+}
+
+// splitFunctionName splits an optionally schema-qualified name; unqualified
+// names fall back to defaultSchema.
+func splitFunctionName(functionName, defaultSchema string) (schema, name string) {
+	if idx := strings.Index(functionName, "."); idx != -1 {
+		return functionName[:idx], functionName[idx+1:]
+	}
+	return defaultSchema, functionName
+}
+
+// quoteIdentifier double-quotes a SQL identifier, escaping embedded quotes.
+func quoteIdentifier(identifier string) string {
+	return `"` + strings.ReplaceAll(identifier, `"`, `""`) + `"`
+}
+
+// quoteQualifiedIdentifier quotes a schema-qualified name.
+func quoteQualifiedIdentifier(schema, name string) string {
+	return quoteIdentifier(schema) + "." + quoteIdentifier(name)
+}
+
+// isReadOnlyFunction reports whether a function can run against a replica.
+// A function qualifies when it is declared STABLE or IMMUTABLE, or when its
+// COMMENT contains the "@readonly" annotation (for VOLATILE functions that
+// the author knows do not write). Unqualified names resolve against the same
+// schema the call itself will run in (callSearchPath); when no schema is
+// configured the name cannot be resolved here and the function is treated as
+// volatile, so the check never consults a different function than the one
+// that executes.
+func (s *Server) isReadOnlyFunction(c *gin.Context, db *sql.DB, databaseName, functionName string) bool {
+	schema, name := splitFunctionName(functionName, s.callSearchPath(c, databaseName))
+	if schema == "" {
+		return false
+	}
+
+	cacheKey := databaseName + "/" + schema + "." + name
+	if cached, ok := s.readOnly.get(cacheKey); ok {
+		return cached
+	}
+
+	// provolatile: 'i' = immutable, 's' = stable, 'v' = volatile
+	query := `
+		SELECT p.provolatile IN ('i', 's')
+		       OR COALESCE(obj_description(p.oid, 'pg_proc'), '') LIKE '%@readonly%'
+		FROM pg_proc p
+		JOIN pg_namespace n ON n.oid = p.pronamespace
+		WHERE n.nspname = $1 AND p.proname = $2
+		LIMIT 1`
+
+	var readOnly bool
+	if err := db.QueryRowContext(c.Request.Context(), query, schema, name).Scan(&readOnly); err != nil {
+		if err != sql.ErrNoRows {
+			slog.Warn("Failed to determine function volatility", "function", functionName, "error", err)
+		}
+		// Unknown functions stay on the primary; the call itself will report
+		// "does not exist" with proper error handling.
+		readOnly = false
+	}
+
+	s.readOnly.set(cacheKey, readOnly)
+
+	return readOnly
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"reflect"
+	"syscall"
+	"time"
+
+	"github.com/yourusername/pgarachne/internal/config"
+	"github.com/yourusername/pgarachne/internal/database"
+	"github.com/yourusername/pgarachne/internal/lifecycle"
+	"github.com/yourusername/pgarachne/internal/logging"
+)
+
+// Configuration hot reload: SIGHUP re-reads pgarachne.env and applies what
+// can change at runtime — log level, CORS origins, static paths, limits —
+// by swapping the config the handlers read. Connection-affecting changes
+// rebuild the pools; listener-affecting changes (ports, TLS files) still
+// need a restart and are logged as such.
+
+// reloadConfig performs one reload cycle; also reachable from the admin API.
+func (s *Server) reloadConfig() error {
+	newCfg, err := config.Reload(s.Cfg().SourcePath)
+	if err != nil {
+		return err
+	}
+
+	oldCfg := s.Cfg()
+
+	logging.SetLevel(newCfg.LogLevel)
+
+	// Handlers read s.Cfg() per request, so the atomic swap is the moment
+	// the new origins, static paths and limits apply.
+	s.cfg.Store(newCfg)
+
+	// Re-parse HTML templates so edited files are picked up.
+	s.htmlTemplates.purge()
+
+	if oldCfg.DBUser != newCfg.DBUser ||
+		oldCfg.DBPort != newCfg.DBPort ||
+		!reflect.DeepEqual(oldCfg.DBHosts, newCfg.DBHosts) ||
+		!reflect.DeepEqual(oldCfg.DBReplicaHosts, newCfg.DBReplicaHosts) {
+		s.stmts.purge()
+		database.ResetPools()
+	}
+
+	if oldCfg.HTTPPort != newCfg.HTTPPort || oldCfg.HTTPSocket != newCfg.HTTPSocket ||
+		oldCfg.TLSCertFile != newCfg.TLSCertFile || oldCfg.TLSKeyFile != newCfg.TLSKeyFile {
+		slog.Warn("Listener configuration changed; a restart is required for it to apply")
+	}
+
+	// Gin bakes the trusted proxy set into the router at build time, so a
+	// changed TRUSTED_PROXIES cannot take effect on reload.
+	if !reflect.DeepEqual(oldCfg.TrustedProxies, newCfg.TrustedProxies) {
+		slog.Warn("TRUSTED_PROXIES changed; a restart is required for it to apply")
+	}
+
+	slog.Info("Configuration reloaded", "config_file", newCfg.SourcePath)
+	return nil
+}
+
+// registerSecretsRefreshHook reloads the configuration on a timer when
+// SECRETS_REFRESH is set, so secrets rotated in an external manager
+// (SECRETS_PROVIDER) are re-fetched without a SIGHUP.
+func (s *Server) registerSecretsRefreshHook(manager *lifecycle.Manager) {
+	if s.Cfg().SecretsRefresh <= 0 {
+		return
+	}
+	done := make(chan struct{})
+	manager.Register(lifecycle.Hook{
+		Name: "secrets-refresh",
+		Start: func() error {
+			ticker := time.NewTicker(s.Cfg().SecretsRefresh)
+			go func() {
+				defer ticker.Stop()
+				for {
+					select {
+					case <-ticker.C:
+						if err := s.reloadConfig(); err != nil {
+							slog.Error("Secrets refresh failed, keeping previous config", "error", err)
+						}
+					case <-done:
+						return
+					}
+				}
+			}()
+			return nil
+		},
+		Stop: func(context.Context) error {
+			close(done)
+			return nil
+		},
+	})
+}
+
+// registerReloadHook installs the SIGHUP handler as a lifecycle subsystem.
+func (s *Server) registerReloadHook(manager *lifecycle.Manager) {
+	reload := make(chan os.Signal, 1)
+	manager.Register(lifecycle.Hook{
+		Name: "config-reload",
+		Start: func() error {
+			signal.Notify(reload, syscall.SIGHUP)
+			go func() {
+				for range reload {
+					if err := s.reloadConfig(); err != nil {
+						slog.Error("Configuration reload failed, keeping previous config", "error", err)
+					}
+				}
+			}()
+			return nil
+		},
+		Stop: func(context.Context) error {
+			signal.Stop(reload)
+			close(reload)
+			return nil
+		},
+	})
+}
@@ -0,0 +1,43 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Every request carries an ID: the client's X-Request-ID when provided (so
+// IDs survive proxy chains), otherwise a generated one. The ID is echoed in
+// the response, attached to the request-scoped logger, and — inside function
+// calls — set as the pgarachne.request_id GUC so SQL logging and
+// pg_stat_activity can be correlated with HTTP logs.
+
+const requestIDHeader = "X-Request-ID"
+
+func requestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(requestIDHeader)
+		if requestID == "" || len(requestID) > 128 {
+			raw := make([]byte, 8)
+			rand.Read(raw)
+			requestID = hex.EncodeToString(raw)
+		}
+		c.Set("request_id", requestID)
+		c.Set("logger", slog.With("request_id", requestID))
+		c.Header(requestIDHeader, requestID)
+		c.Next()
+	}
+}
+
+// reqLogger returns the request-scoped logger carrying the request ID;
+// handlers use it instead of the bare slog default.
+func reqLogger(c *gin.Context) *slog.Logger {
+	if logger, ok := c.Get("logger"); ok {
+		if l, ok := logger.(*slog.Logger); ok {
+			return l
+		}
+	}
+	return slog.Default()
+}
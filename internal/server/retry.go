@@ -0,0 +1,62 @@
+package server
+
+import (
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Transparent retry for transient failures: when a function marked read-only
+// fails with a serialization/deadlock SQLSTATE, an admin shutdown or a broken
+// connection, the whole transaction is retried with exponential backoff
+// before the error ever reaches the client. Functions that are not read-only
+// are never retried — a write may have been applied before the error.
+
+// maxCallAttempts bounds the total tries per request (1 initial + retries).
+const maxCallAttempts = 3
+
+// transientSQLStates are worth an immediate server-side retry; failovers and
+// restarts typically resolve within the backoff window.
+var transientSQLStates = map[string]bool{
+	"40001": true, // serialization_failure
+	"40P01": true, // deadlock_detected
+	"57P01": true, // admin_shutdown
+	"57P02": true, // crash_shutdown
+	"57P03": true, // cannot_connect_now (starting up / in recovery)
+	"08000": true, // connection_exception
+	"08003": true, // connection_does_not_exist
+	"08006": true, // connection_failure
+}
+
+// transientCallError reports whether err is a retry-worthy transient failure.
+func transientCallError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) {
+		return true
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return transientSQLStates[string(pqErr.Code)]
+	}
+	// Network-level failures surface as plain errors from the driver.
+	msg := err.Error()
+	return strings.Contains(msg, "connection refused") ||
+		strings.Contains(msg, "connection reset") ||
+		strings.Contains(msg, "broken pipe") ||
+		strings.Contains(msg, "unexpected EOF")
+}
+
+// callBackoff returns the pause before retry attempt n (1-based):
+// 50ms, then 200ms.
+func callBackoff(attempt int) time.Duration {
+	backoff := 50 * time.Millisecond
+	for i := 1; i < attempt; i++ {
+		backoff *= 4
+	}
+	return backoff
+}
@@ -0,0 +1,176 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"github.com/yourusername/pgarachne/internal/database"
+)
+
+// Role-based rate limits read from the database: with ROLE_LIMITS enabled,
+// each call is checked against the row for its role in pgarachne.limits, so
+// operators manage API tiers (free/pro/internal) with plain SQL instead of
+// redeploying configuration. The table is cached per database and refreshed
+// on NOTIFY pgarachne_limits (the schema installs a trigger) with a periodic
+// reload as a safety net. Roles without a row are unrestricted.
+
+// roleLimitChannel is the NOTIFY channel that invalidates the cache.
+const roleLimitChannel = "pgarachne_limits"
+
+// roleLimitMaxAge bounds staleness when a NOTIFY is missed.
+const roleLimitMaxAge = 5 * time.Minute
+
+type roleLimit struct {
+	requestsPerMinute int
+	maxConcurrent     int
+	monthlyCalls      int64
+}
+
+type roleLimitEntry struct {
+	limits map[string]roleLimit
+	loaded time.Time
+}
+
+type roleLimitCache struct {
+	mu        sync.Mutex
+	entries   map[string]*roleLimitEntry
+	listeners map[string]*pq.Listener
+}
+
+func newRoleLimitCache() *roleLimitCache {
+	return &roleLimitCache{
+		entries:   make(map[string]*roleLimitEntry),
+		listeners: make(map[string]*pq.Listener),
+	}
+}
+
+// limits returns the limit table for the database, loading or refreshing it
+// as needed. Failures degrade to "no limits" rather than blocking calls.
+func (rc *roleLimitCache) limits(s *Server, dbName string) map[string]roleLimit {
+	rc.mu.Lock()
+	entry, ok := rc.entries[dbName]
+	if ok && time.Since(entry.loaded) < roleLimitMaxAge {
+		rc.mu.Unlock()
+		return entry.limits
+	}
+	rc.mu.Unlock()
+
+	limits := loadRoleLimits(s, dbName)
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.entries[dbName] = &roleLimitEntry{limits: limits, loaded: time.Now()}
+	if _, ok := rc.listeners[dbName]; !ok {
+		rc.listeners[dbName] = rc.startListener(s, dbName)
+	}
+	return limits
+}
+
+// startListener subscribes to the invalidation channel; on any notification
+// the cached entry is dropped so the next call reloads.
+func (rc *roleLimitCache) startListener(s *Server, dbName string) *pq.Listener {
+	host, port := database.PrimaryAddr(s.Cfg())
+	listener := pq.NewListener(database.ConnString(s.Cfg(), dbName, host, port), time.Second, time.Minute, nil)
+	if err := listener.Listen(roleLimitChannel); err != nil {
+		slog.Warn("Role limits LISTEN failed, relying on periodic reload", "database", dbName, "error", err)
+		listener.Close()
+		return nil
+	}
+	go func() {
+		for range listener.Notify {
+			rc.mu.Lock()
+			delete(rc.entries, dbName)
+			rc.mu.Unlock()
+		}
+	}()
+	return listener
+}
+
+// loadRoleLimits reads pgarachne.limits; a missing table just means no
+// limits are configured for this database.
+func loadRoleLimits(s *Server, dbName string) map[string]roleLimit {
+	limits := make(map[string]roleLimit)
+	db, err := database.GetConnection(s.Cfg(), dbName)
+	if err != nil {
+		return limits
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := db.QueryContext(ctx, `SELECT role_name, requests_per_minute, max_concurrent, monthly_calls FROM pgarachne.limits`)
+	if err != nil {
+		if !strings.Contains(err.Error(), "does not exist") {
+			slog.Error("Failed to load role limits", "database", dbName, "error", err)
+		}
+		return limits
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var role string
+		var perMinute, concurrent, monthly sql.NullInt64
+		if err := rows.Scan(&role, &perMinute, &concurrent, &monthly); err != nil {
+			continue
+		}
+		limits[role] = roleLimit{
+			requestsPerMinute: int(perMinute.Int64),
+			maxConcurrent:     int(concurrent.Int64),
+			monthlyCalls:      monthly.Int64,
+		}
+	}
+	return limits
+}
+
+func (s *Server) roleLimitMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		dbName := c.Param("database")
+		role := c.GetString("db_role")
+		limit, ok := s.roleLimits.limits(s, dbName)[role]
+		if !ok {
+			c.Next()
+			return
+		}
+
+		// The per-minute budget counts through the shared state store, so
+		// it holds across instances when STATE_STORAGE=postgres.
+		if limit.requestsPerMinute > 0 {
+			window := time.Now().Unix() / 60
+			key := fmt.Sprintf("%s/%s/%d", dbName, role, window)
+			count, err := s.state.Incr(c.Request.Context(), "role_rate", key, 2*time.Minute)
+			if err != nil {
+				reqLogger(c).Error("Role rate limit check failed", "error", err)
+			} else if count > int64(limit.requestsPerMinute) {
+				rateLimitHitsTotal.WithLabelValues(dbName, "role").Inc()
+				c.Header("Retry-After", fmt.Sprintf("%d", 60-time.Now().Unix()%60))
+				c.AbortWithStatusJSON(http.StatusTooManyRequests, JSONRPCResponse{Error: &JSONRPCError{
+					Code:    -32004,
+					Message: "Rate limit for this role exceeded, retry later",
+				}})
+				return
+			}
+		}
+
+		// The concurrency cap reuses the per-client counter with a
+		// role-scoped key; it is instance-local like the client cap.
+		if limit.maxConcurrent > 0 {
+			release, ok := s.clients.acquire("role:"+dbName+"/"+role, limit.maxConcurrent)
+			if !ok {
+				rateLimitHitsTotal.WithLabelValues(dbName, "role").Inc()
+				c.Header("Retry-After", "1")
+				c.AbortWithStatusJSON(http.StatusTooManyRequests, JSONRPCResponse{Error: &JSONRPCError{
+					Code:    -32004,
+					Message: "Too many concurrent calls for this role, retry later",
+				}})
+				return
+			}
+			defer release()
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/yourusername/pgarachne/internal/database"
+	"github.com/yourusername/pgarachne/internal/leader"
+	"github.com/yourusername/pgarachne/internal/lifecycle"
+	"github.com/yourusername/pgarachne/internal/scheduler"
+)
+
+// registerSchedulerHooks starts the built-in cron runner for every database
+// in SCHEDULE_DATABASES. Coordination across instances happens inside the
+// scheduler package via claimed rows in pgarachne.schedule_runs.
+func (s *Server) registerSchedulerHooks(manager *lifecycle.Manager) {
+	for _, dbName := range s.Cfg().ScheduleDatabases {
+		dbName := dbName
+		var sched *scheduler.Scheduler
+		var elector *leader.Elector
+		manager.Register(lifecycle.Hook{
+			Name: "scheduler:" + dbName,
+			Start: func() error {
+				db, err := database.GetConnection(s.Cfg(), dbName)
+				if err != nil {
+					return fmt.Errorf("scheduler for %s: %w", dbName, err)
+				}
+				sched = scheduler.New(db, dbName)
+				if err := sched.EnsureTables(); err != nil {
+					return fmt.Errorf("scheduler for %s: %w", dbName, err)
+				}
+				if s.Cfg().LeaderElection {
+					elector = leader.New(db, "scheduler:"+dbName)
+					go elector.Run()
+					sched.SetLeaderCheck(elector.IsLeader)
+				}
+				go sched.Run()
+				return nil
+			},
+			Stop: func(ctx context.Context) error {
+				sched.Stop()
+				if elector != nil {
+					elector.Stop()
+				}
+				return nil
+			},
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/pgarachne/internal/lifecycle"
+)
+
+// Browser hardening for deployments that serve frontends or the built-in UI
+// directly: SECURITY_HEADERS=true adds the standard response headers, CSP
+// sets a Content-Security-Policy, and HTTPS_REDIRECT_PORT runs a bare
+// listener that bounces plain-HTTP requests to the TLS port. API-only
+// deployments behind a terminating proxy can leave all of it off.
+
+// securityHeadersMiddleware emits the hardening headers on every response.
+// HSTS is only meaningful — and only sent — on TLS connections.
+func (s *Server) securityHeadersMiddleware() gin.HandlerFunc {
+	hsts := fmt.Sprintf("max-age=%d; includeSubDomains", int(s.Cfg().HSTSMaxAge.Seconds()))
+	return func(c *gin.Context) {
+		c.Header("X-Content-Type-Options", "nosniff")
+		c.Header("Referrer-Policy", "no-referrer")
+		c.Header("X-Frame-Options", "DENY")
+		if s.Cfg().CSP != "" {
+			c.Header("Content-Security-Policy", s.Cfg().CSP)
+		}
+		if c.Request.TLS != nil && s.Cfg().HSTSMaxAge > 0 {
+			c.Header("Strict-Transport-Security", hsts)
+		}
+		c.Next()
+	}
+}
+
+// registerHTTPSRedirectHook serves permanent redirects from
+// HTTPS_REDIRECT_PORT to the same host and path over https.
+func (s *Server) registerHTTPSRedirectHook(manager *lifecycle.Manager) {
+	if s.Cfg().HTTPSRedirectPort == "" {
+		return
+	}
+	redirectSrv := &http.Server{
+		Addr:              ":" + s.Cfg().HTTPSRedirectPort,
+		ReadHeaderTimeout: s.Cfg().HTTPReadHeaderTimeout,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			host := r.Host
+			if h, _, err := net.SplitHostPort(host); err == nil {
+				host = h
+			}
+			// Non-default TLS ports stay explicit in the redirect target.
+			if s.Cfg().HTTPPort != "" && s.Cfg().HTTPPort != "443" {
+				host = net.JoinHostPort(host, s.Cfg().HTTPPort)
+			}
+			target := "https://" + host + r.URL.RequestURI()
+			http.Redirect(w, r, target, http.StatusMovedPermanently)
+		}),
+	}
+	manager.Register(lifecycle.Hook{
+		Name: "https-redirect",
+		Start: func() error {
+			listener, err := net.Listen("tcp", redirectSrv.Addr)
+			if err != nil {
+				return fmt.Errorf("https redirect listener: %w", err)
+			}
+			go func() {
+				if err := redirectSrv.Serve(listener); err != nil && err != http.ErrServerClosed {
+					manager.Fail("https-redirect", err)
+				}
+			}()
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			return redirectSrv.Shutdown(ctx)
+		},
+	})
+}
@@ -0,0 +1,147 @@
+package server
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Error tracking: panics, 5xx responses and circuit breaker trips are
+// reported to Sentry (or any collector speaking its store API) when
+// SENTRY_DSN is set, tagged with the release and environment. Events are
+// sent asynchronously by a single worker and dropped when the queue is
+// full — error reporting must never slow down or block request handling.
+
+type sentryEvent struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   string            `json:"timestamp"`
+	Platform    string            `json:"platform"`
+	Logger      string            `json:"logger"`
+	Level       string            `json:"level"`
+	Message     string            `json:"message"`
+	Release     string            `json:"release,omitempty"`
+	Environment string            `json:"environment,omitempty"`
+	ServerName  string            `json:"server_name,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	Extra       map[string]string `json:"extra,omitempty"`
+}
+
+type errorReporter struct {
+	endpoint    string
+	publicKey   string
+	release     string
+	environment string
+	queue       chan sentryEvent
+	client      *http.Client
+}
+
+// newErrorReporter parses the DSN ("https://<key>@<host>/<project>"); a
+// missing DSN disables reporting and returns nil.
+func newErrorReporter(dsn, release, environment string) (*errorReporter, error) {
+	if dsn == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(dsn)
+	if err != nil || u.User == nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid SENTRY_DSN")
+	}
+	project := strings.Trim(u.Path, "/")
+	if project == "" {
+		return nil, fmt.Errorf("SENTRY_DSN is missing the project ID")
+	}
+
+	r := &errorReporter{
+		endpoint:    fmt.Sprintf("%s://%s/api/%s/store/", u.Scheme, u.Host, project),
+		publicKey:   u.User.Username(),
+		release:     release,
+		environment: environment,
+		queue:       make(chan sentryEvent, 64),
+		client:      &http.Client{Timeout: 10 * time.Second},
+	}
+	go r.worker()
+	return r, nil
+}
+
+func (r *errorReporter) worker() {
+	for event := range r.queue {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			continue
+		}
+		req, err := http.NewRequest(http.MethodPost, r.endpoint, bytes.NewReader(payload))
+		if err != nil {
+			continue
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Sentry-Auth", fmt.Sprintf(
+			"Sentry sentry_version=7, sentry_client=pgarachne/%s, sentry_key=%s", Version, r.publicKey))
+		if resp, err := r.client.Do(req); err == nil {
+			resp.Body.Close()
+		}
+	}
+}
+
+// report queues one event; a nil reporter and a full queue are both no-ops.
+func (r *errorReporter) report(level, message string, tags, extra map[string]string) {
+	if r == nil {
+		return
+	}
+	raw := make([]byte, 16)
+	rand.Read(raw)
+	hostname, _ := os.Hostname()
+	event := sentryEvent{
+		EventID:     hex.EncodeToString(raw),
+		Timestamp:   time.Now().UTC().Format("2006-01-02T15:04:05"),
+		Platform:    "go",
+		Logger:      "pgarachne",
+		Level:       level,
+		Message:     message,
+		Release:     r.release,
+		Environment: r.environment,
+		ServerName:  hostname,
+		Tags:        tags,
+		Extra:       extra,
+	}
+	select {
+	case r.queue <- event:
+	default: // queue full: drop rather than block a request
+	}
+}
+
+// requestTags collects the request context every report carries.
+func requestTags(c *gin.Context) map[string]string {
+	return map[string]string{
+		"method":     c.Request.Method,
+		"path":       c.FullPath(),
+		"database":   c.Param("database"),
+		"function":   c.Param("function"),
+		"request_id": c.GetString("request_id"),
+	}
+}
+
+// errorTrackingMiddleware reports panics (then re-panics for gin's recovery
+// to answer the request) and 5xx responses.
+func (s *Server) errorTrackingMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				s.reporter.report("fatal", fmt.Sprintf("panic: %v", recovered), requestTags(c), nil)
+				panic(recovered)
+			}
+		}()
+		c.Next()
+		if status := c.Writer.Status(); status >= http.StatusInternalServerError {
+			s.reporter.report("error", fmt.Sprintf("HTTP %d on %s %s", status, c.Request.Method, c.FullPath()),
+				requestTags(c), map[string]string{"status": fmt.Sprintf("%d", status)})
+		}
+	}
+}
@@ -2,115 +2,489 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"database/sql"
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
-	"os/signal"
+	"strconv"
 	"strings"
-	"syscall"
+	"sync/atomic"
 	"time"
 
-	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/yourusername/pgarachne/internal/config"
 	"github.com/yourusername/pgarachne/internal/database"
+	"github.com/yourusername/pgarachne/internal/lifecycle"
+	"github.com/yourusername/pgarachne/internal/sdnotify"
+	"github.com/yourusername/pgarachne/internal/state"
+	"github.com/yourusername/pgarachne/internal/storage"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
 type Server struct {
-	Cfg *config.Config
+	// cfg is swapped atomically on reload; handlers read it via Cfg().
+	cfg        atomic.Pointer[config.Config]
+	readOnly   *funcFlagCache
+	listFuncs  *funcFlagCache
+	deltaFuncs *funcFlagCache
+	mtls       *mtlsAuth
+
+	// state backs rate limiting, idempotency keys and revocation lists;
+	// the backend (memory or Postgres) is selected via STATE_STORAGE.
+	state state.Store
+	audit *auditor
+
+	// dbStatus holds the last known reachability per declared database.
+	dbStatus *dbStatusMap
+
+	// draining is set once shutdown starts, flipping /readyz to 503 while
+	// in-flight requests finish.
+	draining atomic.Bool
+
+	// maintenance rejects API traffic with 503 when toggled via the admin
+	// API; health probes and /admin itself stay reachable.
+	maintenance atomic.Bool
+
+	// inflight backs the admin API's in-flight request listing.
+	inflight *inflightTracker
+
+	// stmts caches prepared function-call statements per pool.
+	stmts *stmtCache
+
+	// limiter enforces the per-database concurrency caps.
+	limiter *callLimiter
+
+	// clients enforces the per-client in-flight caps.
+	clients *clientLimiter
+
+	// roleLimits caches the per-role limits from pgarachne.limits.
+	roleLimits *roleLimitCache
+
+	// usage tallies per-role consumption for accounting and quotas.
+	usage *usageRecorder
+
+	// breaker fails fast against databases that keep erroring.
+	breaker *circuitBreaker
+
+	// capSchemas caches the parameter schemas from pgarachne.capabilities().
+	capSchemas *capabilitySchemaCache
+
+	// txSessions holds open multi-call transaction sessions.
+	txSessions *txSessionStore
+
+	// storage is the optional object storage backend; nil when disabled.
+	storage storage.Backend
+
+	// htmlTemplates caches the parsed TEMPLATES_PATH set.
+	htmlTemplates *templateCache
+	// reporter ships panics and 5xx responses to Sentry; nil when disabled.
+	reporter *errorReporter
 }
 
-func New(cfg *config.Config) *Server {
-	return &Server{Cfg: cfg}
+func New(cfg *config.Config) (*Server, error) {
+	store, err := state.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	audit, err := newAuditor(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("audit log: %w", err)
+	}
+	objectStore, err := storage.New(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("object storage: %w", err)
+	}
+	devMode = cfg.DevMode
+	if devMode {
+		slog.Warn("DEV_MODE is enabled: verbose errors and body logging are active; never run this in production")
+	}
+	release := cfg.SentryRelease
+	if release == "" {
+		release = Version
+	}
+	reporter, err := newErrorReporter(cfg.SentryDSN, release, cfg.SentryEnvironment)
+	if err != nil {
+		return nil, fmt.Errorf("error tracking: %w", err)
+	}
+	s := &Server{
+		readOnly:      newFuncFlagCache(),
+		listFuncs:     newFuncFlagCache(),
+		deltaFuncs:    newFuncFlagCache(),
+		state:         store,
+		audit:         audit,
+		dbStatus:      newDBStatusMap(),
+		inflight:      newInflightTracker(),
+		stmts:         newStmtCache(),
+		limiter:       newCallLimiter(),
+		clients:       newClientLimiter(),
+		roleLimits:    newRoleLimitCache(),
+		usage:         newUsageRecorder(),
+		breaker:       newCircuitBreaker(),
+		capSchemas:    newCapabilitySchemaCache(),
+		txSessions:    newTxSessionStore(),
+		storage:       objectStore,
+		htmlTemplates: &templateCache{},
+		reporter:      reporter,
+	}
+	s.cfg.Store(cfg)
+	// A circuit trip means a database failed repeatedly — worth a report
+	// even though the individual requests answer with fail-fast errors.
+	s.breaker.onTrip = func(dbName string) {
+		s.reporter.report("error", fmt.Sprintf("Circuit breaker opened for database '%s'", dbName),
+			map[string]string{"database": dbName}, nil)
+	}
+	return s, nil
 }
 
-func (s *Server) Run() error {
+// Cfg returns the current configuration. The pointer is swapped atomically
+// on reload, so callers see a consistent snapshot but must not cache it
+// across requests.
+func (s *Server) Cfg() *config.Config {
+	return s.cfg.Load()
+}
+
+// Router builds the Gin engine with all middleware and routes. It is used by
+// Run and by the selftest command, which drives the full stack in-process.
+func (s *Server) Router() *gin.Engine {
 	gin.SetMode(gin.ReleaseMode)
-	router := gin.Default()
-
-	// CORS setup
-	router.Use(cors.New(cors.Config{
-		AllowMethods:     []string{"POST", "OPTIONS", "GET"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		AllowCredentials: true,
-		AllowOriginFunc: func(origin string) bool {
-			if len(s.Cfg.AllowedOrigins) == 1 && s.Cfg.AllowedOrigins[0] == "*" {
-				return true
-			}
-			for _, allowedOrigin := range s.Cfg.AllowedOrigins {
-				if allowedOrigin == origin {
-					return true
-				}
-			}
-			return false
-		},
-	}))
+	router := gin.New()
+	router.Use(gin.Recovery())
+
+	// X-Forwarded-For is only believed when it arrives from a configured
+	// proxy; without TRUSTED_PROXIES the socket peer is the client IP, so
+	// nobody can spoof their way past IP rules or rate limits.
+	router.SetTrustedProxies(s.Cfg().TrustedProxies)
+
+	// Request ID propagation (header, logs, Postgres GUC)
+	router.Use(requestIDMiddleware())
+	router.Use(s.drainMiddleware())
+	// Access log through slog, after the request ID is assigned
+	if s.Cfg().AccessLogFormat != "off" {
+		router.Use(s.accessLogMiddleware())
+	}
+	// Developer mode: body echo and pretty-printed JSON
+	if s.Cfg().DevMode {
+		router.Use(s.devEchoMiddleware())
+	}
+	// Error tracking (panics and 5xx responses) when SENTRY_DSN is set
+	if s.reporter != nil {
+		router.Use(s.errorTrackingMiddleware())
+	}
+	// In-flight request tracking for the admin API
+	router.Use(s.inflightMiddleware())
+	// CIDR allow/deny rules (global and per-database)
+	router.Use(s.ipFilterMiddleware())
+	// DATABASES allowlist (404 for unlisted catalogs)
+	router.Use(s.databaseAllowlistMiddleware())
+
+	// CORS setup (global policy plus per-database overrides)
+	router.Use(s.corsMiddleware())
+
+	// Hardening headers for browser-facing deployments
+	if s.Cfg().SecurityHeaders {
+		router.Use(s.securityHeadersMiddleware())
+	}
 
 	// Public API
 	router.GET("/health", s.handleHealthCheck)
+	router.GET("/livez", s.handleLivez)
+	router.GET("/readyz", s.handleReadyz)
 	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	s.registerDebugEndpoints(router)
+	s.registerAdminEndpoints(router)
+	s.registerUIEndpoints(router)
+	s.registerLandingPage(router)
 
-	router.POST("/api/:database/login", s.handleLogin)
+	// Public credential endpoints, optionally behind CAPTCHA/proof-of-work.
+	router.GET("/api/:database/pow", s.maintenanceMiddleware(), s.handlePowChallenge)
+	router.POST("/api/:database/login", s.maintenanceMiddleware(), s.captchaMiddleware(), s.handleLogin)
+	router.POST("/api/:database/password", s.maintenanceMiddleware(), s.captchaMiddleware(), s.handlePasswordChange)
+	router.POST("/api/:database/password/reset", s.maintenanceMiddleware(), s.captchaMiddleware(), s.handlePasswordResetRequest)
+	router.POST("/api/:database/password/reset/confirm", s.maintenanceMiddleware(), s.captchaMiddleware(), s.handlePasswordResetConfirm)
+	router.POST("/api/:database/signup", s.maintenanceMiddleware(), s.captchaMiddleware(), s.handleSignup)
 
 	// Protected API
 	protectedAPI := router.Group("/api/:database")
-	protectedAPI.Use(s.authMiddleware())
-	protectedAPI.POST("/:function", s.handleFunctionCall)
+	protectedAPI.Use(s.maintenanceMiddleware(), s.authMiddleware(), s.clientLimitMiddleware())
+	if s.Cfg().RoleLimits {
+		protectedAPI.Use(s.roleLimitMiddleware())
+	}
+	if s.Cfg().UsageAccounting {
+		protectedAPI.Use(s.usageMiddleware())
+		protectedAPI.GET("/_usage", s.handleUsage)
+	}
+	protectedAPI.POST("/:function", s.concurrencyMiddleware(), s.handleFunctionCall)
+
+	// Single-endpoint JSON-RPC: POST /api/:database with the function taken
+	// from the request's "method" field.
+	protectedAPI.POST("", s.concurrencyMiddleware(), s.handleFunctionCall)
+
+	// Bulk ingest through COPY (CSV or NDJSON body)
+	protectedAPI.POST("/copy/:target", s.handleCopyIn)
+
+	// Large object streaming (download via a function returning an OID,
+	// upload of a new object)
+	protectedAPI.GET("/_lo/:function", s.handleLargeObjectDownload)
+	protectedAPI.POST("/_lo", s.handleLargeObjectUpload)
+
+	// Object storage offload (S3/MinIO)
+	protectedAPI.POST("/_files", s.handleObjectUpload)
+	protectedAPI.GET("/_files/*key", s.handleObjectDownload)
+
+	// Server-rendered HTML from SQL results (TEMPLATES_PATH)
+	protectedAPI.GET("/_html/:function", s.handleHTMLRender)
+
+	// Multi-call transaction sessions (X-Transaction header on function calls)
+	protectedAPI.POST("/_tx/begin", s.handleTxBegin)
+	protectedAPI.POST("/_tx/:token/commit", s.handleTxFinish(true))
+	protectedAPI.POST("/_tx/:token/rollback", s.handleTxFinish(false))
+
+	// Webhook subscription management (self-service, scoped per role)
+	protectedAPI.POST("/_webhooks", s.handleWebhookCreate)
+	protectedAPI.GET("/_webhooks", s.handleWebhookList)
+	protectedAPI.DELETE("/_webhooks/:id", s.handleWebhookDelete)
+
+	// Config-defined static JSON documents (agent manifests etc.)
+	s.registerStaticJSONEndpoints(router)
 
 	// Static files
-	// Static files
-	if s.Cfg.StaticFilesPath != "" {
+	if s.Cfg().StaticFilesPath != "" {
 		// Use NoRoute to serve static files when no other route matches.
 		// This avoids conflicts with specific routes like /health at the root level.
-		router.NoRoute(func(c *gin.Context) {
-			fileServer := http.FileServer(http.Dir(s.Cfg.StaticFilesPath))
-			fileServer.ServeHTTP(c.Writer, c.Request)
-		})
-		slog.Info("Serving static files via fallback", "path", s.Cfg.StaticFilesPath)
+		router.NoRoute(s.staticFileHandler())
+		slog.Info("Serving static files via fallback", "path", s.Cfg().StaticFilesPath)
 	}
 
-	slog.Info("Starting PgArachne server", "port", s.Cfg.HTTPPort)
+	return router
+}
+
+func (s *Server) Run() error {
+	router := s.Router()
+
+	slog.Info("Starting PgArachne server", "port", s.Cfg().HTTPPort)
+
+	// Tenant routing and API version segments rewrite /api paths before the
+	// router matches them; the base path prefix (if any) comes off even
+	// earlier.
+	var handler http.Handler = s.basePathHandler(s.apiVersionHandler(s.tenantRewriteHandler(router)))
+
+	// h2c allows plaintext HTTP/2 (multiplexing behind load balancers that
+	// don't re-encrypt). With TLS, net/http negotiates HTTP/2 by itself.
+	if s.Cfg().HTTPH2C && s.Cfg().TLSCertFile == "" {
+		handler = h2c.NewHandler(handler, &http2.Server{})
+		slog.Info("h2c (plaintext HTTP/2) enabled")
+	}
 
 	srv := &http.Server{
-		Addr:    ":" + s.Cfg.HTTPPort,
-		Handler: router,
-	}
-
-	// Initializing the server in a goroutine so that
-	// it won't block the graceful shutdown handling below
-	go func() {
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			slog.Error("listen", "error", err)
-			// If server fails to start, we must exit, but we are in a goroutine.
-			// Ideally we communicate back, but os.Exit is acceptable for fatal startup error.
-			// However, Run() should probably return error.
-			// Let's rely on the main function handling, but here we can't easily bubble up error
-			// without a channel. For simplicity in this structure:
-			// We log and let the shutdown logic finish (or if start failed immediately).
+		Addr:              ":" + s.Cfg().HTTPPort,
+		Handler:           handler,
+		ReadHeaderTimeout: s.Cfg().HTTPReadHeaderTimeout,
+		WriteTimeout:      s.Cfg().HTTPWriteTimeout,
+		IdleTimeout:       s.Cfg().HTTPIdleTimeout,
+	}
+
+	// Servers whose keep-alive connections get closed when draining starts.
+	drainServers := []*http.Server{srv}
+
+	// Each subsystem registers start/stop hooks with the lifecycle manager;
+	// it handles signal waiting and ordered shutdown (SHUTDOWN_TIMEOUT per
+	// subsystem).
+	manager := lifecycle.NewManager(s.Cfg().ShutdownTimeout)
+
+	// Pools close at the very end of shutdown, after the listeners drain.
+	s.registerPoolCloseHook(manager)
+
+	// Declared databases are probed before any listener accepts traffic.
+	s.registerDatabaseStartupHook(manager)
+
+	// Background pool health checking (eviction of broken pools).
+	s.registerHealthCheckHook(manager)
+
+	// Outgoing webhook dispatchers (LISTEN -> signed POST with retries).
+	s.registerWebhookDispatchHooks(manager)
+
+	// Built-in cron runner for pgarachne.schedules.
+	s.registerSchedulerHooks(manager)
+
+	// Periodic usage-accounting flush into pgarachne.usage.
+	s.registerUsageFlushHook(manager)
+
+	// The transaction-session janitor stops with the process.
+	manager.Register(lifecycle.Hook{
+		Name:  "tx-session-janitor",
+		Start: func() error { return nil },
+		Stop: func(context.Context) error {
+			s.txSessions.stop()
+			return nil
+		},
+	})
+
+	// Native TLS termination: with TLS_CERT_FILE/TLS_KEY_FILE set, the
+	// certificate is served via a reloader that picks up renewals on disk.
+	useTLS := s.Cfg().TLSCertFile != ""
+	if useTLS {
+		reloader, err := newCertReloader(s.Cfg().TLSCertFile, s.Cfg().TLSKeyFile)
+		if err != nil {
+			return err
 		}
-	}()
-
-	// Wait for interrupt signal to gracefully shutdown the server with
-	// a timeout of 5 seconds.
-	quit := make(chan os.Signal, 1)
-	// kill (no param) default send syscall.SIGTERM
-	// kill -2 is syscall.SIGINT
-	// kill -9 is syscall.SIGKILL but can't be caught, so don't need to add it
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-	slog.Info("Shutting down server...")
-
-	// The context is used to inform the server it has 5 seconds to finish
-	// the request it is currently handling
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	if err := srv.Shutdown(ctx); err != nil {
-		slog.Error("Server forced to shutdown", "error", err)
+		srv.TLSConfig = &tls.Config{GetCertificate: reloader.GetCertificate}
+		manager.Register(lifecycle.Hook{
+			Name: "tls-reloader",
+			Start: func() error {
+				go reloader.watch()
+				return nil
+			},
+			Stop: reloader.stop,
+		})
+
+		// mTLS client authentication with a hot-reloadable allow-list.
+		if s.Cfg().TLSClientCAFile != "" {
+			mtls, err := newMTLSAuth(s.Cfg().TLSClientCAFile, s.Cfg().TLSClientRoleMapFile)
+			if err != nil {
+				return err
+			}
+			s.mtls = mtls
+			srv.TLSConfig.ClientAuth = tls.RequireAndVerifyClientCert
+			srv.TLSConfig.GetConfigForClient = mtls.getConfigForClient(srv.TLSConfig)
+			manager.Register(lifecycle.Hook{
+				Name: "mtls-reloader",
+				Start: func() error {
+					go mtls.watch()
+					return nil
+				},
+				Stop: mtls.stop,
+			})
+		}
+	}
+
+	if s.Cfg().HTTPPort != "" {
+		manager.Register(lifecycle.Hook{
+			Name: "http",
+			Start: func() error {
+				// The listener is created explicitly (or inherited from a
+				// predecessor after SIGUSR2) so it can be handed over during
+				// a zero-downtime upgrade.
+				listener, err := inheritedListener()
+				if err != nil {
+					return err
+				}
+				if listener == nil {
+					// systemd socket activation: serve on the socket the
+					// unit passed instead of opening our own.
+					if activated := sdnotify.Listeners(); len(activated) > 0 {
+						listener = activated[0]
+						slog.Info("Using systemd-activated socket", "addr", listener.Addr().String())
+					}
+				}
+				if listener == nil {
+					listener, err = net.Listen("tcp", srv.Addr)
+					if err != nil {
+						return err
+					}
+				}
+				s.watchUpgradeSignal(manager, listener)
+
+				// Serve blocks, so it runs in a goroutine and reports fatal
+				// errors back to the manager.
+				go func() {
+					var err error
+					if useTLS {
+						// Cert/key come from TLSConfig.GetCertificate.
+						err = srv.ServeTLS(listener, "", "")
+					} else {
+						err = srv.Serve(listener)
+					}
+					if err != nil && err != http.ErrServerClosed {
+						manager.Fail("http", err)
+					}
+				}()
+				return nil
+			},
+			Stop: srv.Shutdown,
+		})
+	}
+
+	// Optional Unix domain socket listener (HTTP_SOCKET), with or without
+	// the TCP listener. TLS never applies here: local sockets are protected
+	// by file permissions, not certificates.
+	if s.Cfg().HTTPSocket != "" {
+		socketSrv := &http.Server{
+			Handler:           handler,
+			ReadHeaderTimeout: s.Cfg().HTTPReadHeaderTimeout,
+			WriteTimeout:      s.Cfg().HTTPWriteTimeout,
+			IdleTimeout:       s.Cfg().HTTPIdleTimeout,
+		}
+		drainServers = append(drainServers, socketSrv)
+		manager.Register(lifecycle.Hook{
+			Name: "http-socket",
+			Start: func() error {
+				// Remove a stale socket left over from an unclean shutdown.
+				if err := os.Remove(s.Cfg().HTTPSocket); err != nil && !os.IsNotExist(err) {
+					return fmt.Errorf("failed to remove stale socket %s: %w", s.Cfg().HTTPSocket, err)
+				}
+				listener, err := net.Listen("unix", s.Cfg().HTTPSocket)
+				if err != nil {
+					return fmt.Errorf("failed to listen on socket %s: %w", s.Cfg().HTTPSocket, err)
+				}
+				if err := os.Chmod(s.Cfg().HTTPSocket, s.Cfg().HTTPSocketMode); err != nil {
+					listener.Close()
+					return fmt.Errorf("failed to set socket permissions: %w", err)
+				}
+				slog.Info("Listening on Unix socket", "path", s.Cfg().HTTPSocket, "mode", s.Cfg().HTTPSocketMode.String())
+				go func() {
+					if err := socketSrv.Serve(listener); err != nil && err != http.ErrServerClosed {
+						manager.Fail("http-socket", err)
+					}
+				}()
+				return nil
+			},
+			Stop: func(ctx context.Context) error {
+				err := socketSrv.Shutdown(ctx)
+				os.Remove(s.Cfg().HTTPSocket)
+				return err
+			},
+		})
+	}
+
+	// Plain-HTTP listener that bounces everything to the TLS port.
+	s.registerHTTPSRedirectHook(manager)
+
+	// SIGHUP-triggered configuration hot reload.
+	s.registerReloadHook(manager)
+
+	// Periodic secret-manager re-fetch (rotation without SIGHUP).
+	s.registerSecretsRefreshHook(manager)
+
+	// Registered after the listeners so its Stop runs before theirs: /readyz
+	// flips to draining while in-flight requests are still being served, new
+	// calls get 503 + Connection: close (drainMiddleware), and idle
+	// keep-alive connections are told to close instead of lingering until
+	// the shutdown deadline.
+	manager.Register(lifecycle.Hook{
+		Name:  "drain-marker",
+		Start: func() error { return nil },
+		Stop: func(ctx context.Context) error {
+			s.draining.Store(true)
+			for _, drainSrv := range drainServers {
+				drainSrv.SetKeepAlivesEnabled(false)
+			}
+			return nil
+		},
+	})
+
+	// Registered last so readiness is only reported once the listeners are up.
+	s.registerSystemdHook(manager)
+
+	err := manager.Run()
+	if err != nil {
+		slog.Error("Server shutdown with error", "error", err)
 		return err
 	}
 
@@ -129,9 +503,13 @@ func (s *Server) handleLogin(c *gin.Context) {
 	// We try to open a connection to the requested database using the provided credentials.
 	// If successful, the user is authenticated and the role is the login name.
 
-	// Construct connection string for verification (disable SSL for local, adjust as needed)
-	connStr := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
-		s.Cfg.DBHost, s.Cfg.DBPort, loginReq.Login, loginReq.Password, c.Param("database"))
+	// Construct connection string for verification (disable SSL for local, adjust as needed).
+	// With multiple DB_HOST entries, verification goes to the current primary.
+	primaryHost, primaryPort := database.PrimaryAddr(s.Cfg())
+	// The user/password pair goes last so the configured DSN cannot clobber
+	// the credentials being verified.
+	connStr := database.ConnString(s.Cfg(), c.Param("database"), primaryHost, primaryPort) +
+		fmt.Sprintf(" user=%s password=%s", loginReq.Login, loginReq.Password)
 
 	// Try to connect
 	tempDB, err := sql.Open("postgres", connStr)
@@ -148,6 +526,7 @@ func (s *Server) handleLogin(c *gin.Context) {
 
 	if err := tempDB.PingContext(ctx); err != nil {
 		slog.Warn("Authentication failed", "user", loginReq.Login, "error", err)
+		s.recordLoginFailure(c, loginReq.Login)
 		// Don't leak details, just say invalid
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid login or password"})
 		return
@@ -157,23 +536,80 @@ func (s *Server) handleLogin(c *gin.Context) {
 	dbRole := loginReq.Login
 
 	// Create JWT
-	expirationTime := time.Now().Add(time.Duration(s.Cfg.JWTExpiryHours) * time.Hour)
+	expirationTime := time.Now().Add(time.Duration(s.Cfg().JWTExpiryHours) * time.Hour)
 	claims := jwt.MapClaims{"db_role": dbRole, "db_name": c.Param("database"), "exp": expirationTime.Unix()}
+
+	// With LOGIN_FUNCTION configured, the database can enrich the token with
+	// custom claims (tenant_id, permissions, display name, ...). The call
+	// runs on the just-verified connection, i.e. with the user's own
+	// privileges.
+	if s.Cfg().LoginFunction != "" {
+		extraClaims, err := fetchLoginClaims(ctx, tempDB, s.Cfg().LoginFunction, loginReq.Login, loginReq.Password)
+		if err != nil {
+			reqLogger(c).Error("Login claims function failed", "function", s.Cfg().LoginFunction, "user", loginReq.Login, "error", err)
+			s.recordLoginFailure(c, loginReq.Login)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid login or password"})
+			return
+		}
+		for name, claim := range extraClaims {
+			// The registered claims stay server-controlled.
+			if name == "db_role" || name == "db_name" || name == "exp" {
+				continue
+			}
+			claims[name] = claim
+		}
+	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString([]byte(s.Cfg.JWTSecret))
+	tokenString, err := token.SignedString([]byte(s.Cfg().JWTSecret))
 	if err != nil {
 		slog.Error("Failed to sign JWT", "error", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create session token"})
 		return
 	}
 
+	loginAttemptsTotal.WithLabelValues(c.Param("database"), "success").Inc()
 	c.JSON(http.StatusOK, gin.H{"token": tokenString})
 }
 
+// recordLoginFailure feeds the security counters: every failure is counted,
+// and a login crossing lockoutThreshold failures within the tracking window
+// additionally counts as a lockout — the alerting signal for a
+// credential-stuffing run against one account.
+func (s *Server) recordLoginFailure(c *gin.Context, login string) {
+	databaseName := c.Param("database")
+	loginAttemptsTotal.WithLabelValues(databaseName, "failure").Inc()
+	const lockoutThreshold = 10
+	count, err := s.state.Incr(c.Request.Context(), "login_failures", databaseName+"/"+login, 15*time.Minute)
+	if err == nil && count == lockoutThreshold {
+		lockoutsTotal.WithLabelValues(databaseName).Inc()
+		slog.Warn("Login crossed the repeated-failure threshold", "user", login, "database", databaseName)
+	}
+}
+
 func (s *Server) authMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		// mTLS machine clients authenticate by certificate alone: the
+		// handshake already verified the chain, so a mapped CN is enough.
+		if s.mtls != nil && c.Request.TLS != nil && len(c.Request.TLS.PeerCertificates) > 0 {
+			commonName := c.Request.TLS.PeerCertificates[0].Subject.CommonName
+			if role, ok := s.mtls.roleFor(commonName); ok {
+				c.Set("db_role", role)
+				c.Next()
+				return
+			}
+			// Certificates not in the map fall through to token auth.
+		}
+
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
+			// With ANON_ROLE configured, unauthenticated requests run as
+			// that role; which functions are public is decided by the
+			// role's EXECUTE grants, enforced by SET ROLE as usual.
+			if s.Cfg().AnonRole != "" {
+				c.Set("db_role", s.Cfg().AnonRole)
+				c.Next()
+				return
+			}
 			c.JSON(http.StatusUnauthorized, JSONRPCResponse{Error: &JSONRPCError{Message: "Authorization header is missing"}})
 			c.Abort()
 			return
@@ -195,7 +631,7 @@ func (s *Server) authMiddleware() gin.HandlerFunc {
 				if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 					return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 				}
-				return []byte(s.Cfg.JWTSecret), nil
+				return []byte(s.Cfg().JWTSecret), nil
 			})
 
 			if err == nil && token.Valid {
@@ -208,12 +644,14 @@ func (s *Server) authMiddleware() gin.HandlerFunc {
 					requestedDb := c.Param("database")
 					if dbName != requestedDb {
 						slog.Warn("JWT token used for wrong database", "token_db", dbName, "requested_db", requestedDb)
+						tokenFailuresTotal.WithLabelValues(requestedDb).Inc()
 						c.JSON(http.StatusUnauthorized, JSONRPCResponse{Error: &JSONRPCError{Message: "Invalid token for this database"}})
 						c.Abort()
 						return
 					}
 
 					c.Set("db_role", dbRole)
+					c.Set("jwt_claims", map[string]interface{}(claims))
 					c.Next()
 					return
 				}
@@ -224,7 +662,7 @@ func (s *Server) authMiddleware() gin.HandlerFunc {
 		// Logic: We pass the raw token to the DB function 'pgarachne.verify_api_token'.
 		// The DB handles hashing and checking validity.
 		databaseName := c.Param("database")
-		db, err := database.GetConnection(s.Cfg, databaseName)
+		db, err := database.GetConnection(s.Cfg(), databaseName)
 		if err != nil {
 			c.JSON(http.StatusServiceUnavailable, JSONRPCResponse{Error: &JSONRPCError{Message: "Database connection failed"}})
 			c.Abort()
@@ -249,6 +687,7 @@ func (s *Server) authMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		tokenFailuresTotal.WithLabelValues(databaseName).Inc()
 		c.JSON(http.StatusUnauthorized, JSONRPCResponse{Error: &JSONRPCError{Message: "Invalid or expired token"}})
 		c.Abort()
 	}
@@ -263,47 +702,166 @@ func (s *Server) handleFunctionCall(c *gin.Context) {
 		return
 	}
 
-	db, err := database.GetConnection(s.Cfg, databaseName)
+	// Tenant rewriting has settled the database name by now, so this is
+	// where a versioned call against a database lacking that version 404s.
+	if !s.checkAPIVersion(c, databaseName) {
+		return
+	}
+
+	// Mock mode answers from fixture files without touching Postgres.
+	if s.Cfg().MockFixturesPath != "" {
+		s.handleMockCall(c, functionName)
+		return
+	}
+
+	// Circuit breaker: while the database is known to be down, fail fast
+	// instead of stacking up connection attempts.
+	if !s.breaker.allow(databaseName) {
+		c.Header("Retry-After", strconv.Itoa(int(breakerCooldown.Seconds())))
+		c.JSON(http.StatusServiceUnavailable, JSONRPCResponse{Error: &JSONRPCError{
+			Code:    -32005,
+			Message: "Database temporarily unavailable (circuit open), retry later",
+		}})
+		return
+	}
+
+	db, err := database.GetConnection(s.Cfg(), databaseName)
 	if err != nil {
+		s.breaker.recordFailure(databaseName)
 		c.JSON(http.StatusServiceUnavailable, JSONRPCResponse{Error: &JSONRPCError{Message: "Database connection failed"}})
 		return
 	}
 
 	var req JSONRPCRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, JSONRPCResponse{Error: &JSONRPCError{Message: "Invalid JSON request"}})
+	if err := s.bindJSONRPCRequest(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, JSONRPCResponse{Error: &JSONRPCError{Message: fmt.Sprintf("Invalid JSON request: %v", err)}})
 		return
 	}
 
 	c.Set("jsonrpc_id", req.ID)
 
+	// Without a :function path segment (POST /api/:database) the JSON-RPC
+	// "method" field names the function, per the 2.0 spec.
+	if functionName == "" {
+		functionName = req.Method
+		if functionName == "" {
+			c.JSON(http.StatusBadRequest, JSONRPCResponse{Error: &JSONRPCError{Code: -32600, Message: "Missing method"}, ID: req.ID})
+			return
+		}
+		if functionName == "login" {
+			c.JSON(http.StatusForbidden, JSONRPCResponse{Error: &JSONRPCError{Message: "Login must be called via the public endpoint"}, ID: req.ID})
+			return
+		}
+	}
+
+	// Cheap schema validation against the published capabilities before any
+	// transaction is started; catches malformed calls with field-level
+	// messages instead of Postgres cast errors.
+	if violations := s.validateParams(c.Request.Context(), db, databaseName, functionName, req.Params); len(violations) > 0 {
+		c.JSON(http.StatusBadRequest, JSONRPCResponse{Error: &JSONRPCError{
+			Code:    -32602,
+			Message: "Invalid params",
+			Data:    gin.H{"errors": violations},
+		}, ID: req.ID})
+		return
+	}
+
 	dbRole := c.GetString("db_role")
 	if dbRole == "" {
-		slog.Error("db_role not found in context")
+		reqLogger(c).Error("db_role not found in context")
 		c.JSON(http.StatusInternalServerError, JSONRPCResponse{Error: &JSONRPCError{Code: -32000, Message: "Internal Server Error: User role not identified"}, ID: req.ID})
 		return
 	}
 
-	paramsJSON, err := json.Marshal(req.Params)
+	// Impersonation (X-Impersonate-Role): validated against pg_has_role
+	// before anything runs as the target role.
+	dbRole, err = s.resolveImpersonation(c, db, dbRole)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, JSONRPCResponse{Error: &JSONRPCError{Message: "Failed to marshal params"}, ID: req.ID})
+		c.JSON(http.StatusForbidden, JSONRPCResponse{Error: &JSONRPCError{Code: -32001, Message: fmt.Sprintf("Impersonation denied: %v", err)}, ID: req.ID})
 		return
 	}
+	c.Set("db_role", dbRole)
 
-	tx, err := db.BeginTx(c.Request.Context(), nil)
-	if err != nil {
-		slog.Error("Failed to begin transaction", "error", err)
-		c.JSON(http.StatusServiceUnavailable, JSONRPCResponse{Error: &JSONRPCError{Message: "Database unavailable"}, ID: req.ID})
+	// Calls carrying a transaction session token run inside that open
+	// transaction instead of their own.
+	if c.GetHeader(txSessionHeader) != "" {
+		s.handleSessionCall(c, functionName, &req)
 		return
 	}
-	defer tx.Rollback()
 
-	// Safe identifier quoting for role
-	quotedRole := fmt.Sprintf(`"%s"`, strings.ReplaceAll(dbRole, `"`, `""`))
-	if _, err := tx.ExecContext(c.Request.Context(), fmt.Sprintf("SET LOCAL ROLE %s", quotedRole)); err != nil {
-		slog.Error("Failed to SET ROLE", "role", dbRole, "error", err)
-		c.JSON(http.StatusForbidden, JSONRPCResponse{Error: &JSONRPCError{Code: -32001, Message: "Permission denied for the specified role"}, ID: req.ID})
-		return
+	started := time.Now()
+
+	// Slow call logging: anything over the threshold is WARN-logged and
+	// counted, whatever the outcome.
+	if threshold := s.Cfg().SlowCallThreshold; threshold > 0 {
+		defer func() {
+			if elapsed := time.Since(started); elapsed >= threshold {
+				slowCallsTotal.WithLabelValues(databaseName, functionName).Inc()
+				reqLogger(c).Warn("Slow function call",
+					"database", databaseName, "function", functionName, "role", dbRole,
+					"param_bytes", c.Request.ContentLength, "duration", elapsed)
+			}
+		}()
+	}
+
+	// Audit trail: record the outcome on every exit path from here on.
+	if s.audit.enabled() {
+		defer func() {
+			status := "ok"
+			if c.Writer.Status() >= http.StatusBadRequest {
+				status = "error"
+			}
+			s.audit.record(c, db, databaseName, dbRole, functionName, req.Params, status, started)
+		}()
+	}
+
+	// Pagination for "@list"-annotated functions: page/page_size become
+	// limit/offset and the response gets the {items, total, next} envelope.
+	var pagination *paginationRequest
+	if req.Params != nil && s.isListFunction(c.Request.Context(), db, databaseName, functionName) {
+		pagination = extractPagination(req.Params)
+	}
+
+	// Delta sync: an If-None-Match version reroutes to "<function>_delta".
+	deltaName := s.applyDeltaSync(c, db, databaseName, functionName, req.Params)
+	paramsRewritten := pagination != nil || deltaName != functionName
+	functionName = deltaName
+
+	// The client's params bytes go to Postgres verbatim; re-encoding the
+	// decoded map is only needed when pagination or delta sync changed it.
+	paramsJSON := []byte(req.RawParams)
+	if paramsRewritten || len(paramsJSON) == 0 {
+		paramsJSON, err = jsonMarshal(req.Params)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, JSONRPCResponse{Error: &JSONRPCError{Message: "Failed to marshal params"}, ID: req.ID})
+			return
+		}
+	}
+
+	// Idempotency-Key on a mutating call: claim the key (replaying the
+	// stored response or rejecting a concurrent duplicate if it is taken),
+	// then execute and record the outcome below.
+	idemKey, idemFingerprint := "", ""
+	if key := c.GetHeader(idempotencyHeader); key != "" && !s.isReadOnlyFunction(c, db, databaseName, functionName) {
+		idemKey = databaseName + "/" + dbRole + "/" + key
+		idemFingerprint = idempotencyFingerprint(databaseName, dbRole, functionName, paramsJSON)
+		if !s.claimIdempotent(c, idemKey, idemFingerprint) {
+			return
+		}
+	}
+
+	// Read-only functions can be served by a replica when DB_REPLICA_HOSTS
+	// is configured; writes always stay on the primary.
+	execDB := db
+	txOptions := (*sql.TxOptions)(nil)
+	if len(s.Cfg().DBReplicaHosts) > 0 && s.isReadOnlyFunction(c, db, databaseName, functionName) {
+		replicaDB, err := database.GetReplicaConnection(s.Cfg(), databaseName)
+		if err == nil {
+			execDB = replicaDB
+			txOptions = &sql.TxOptions{ReadOnly: true}
+		} else {
+			reqLogger(c).Warn("Replica routing failed, using primary", "database", databaseName, "error", err)
+		}
 	}
 
 	// Call the function
@@ -316,27 +874,197 @@ func (s *Server) handleFunctionCall(c *gin.Context) {
 		query = fmt.Sprintf("SELECT %s($1::jsonb)::json", functionName)
 	}
 
-	var resultJSON json.RawMessage
-	err = tx.QueryRowContext(c.Request.Context(), query, paramsJSON).Scan(&resultJSON)
-	if err != nil {
-		slog.Error("Function call failed", "function", functionName, "error", err)
-		if strings.Contains(err.Error(), "does not exist") {
+	// One attempt covers the full transaction: BEGIN, SET LOCAL ROLE, the
+	// session GUCs, the function call and COMMIT. failedStep selects which
+	// error response applies when the final attempt does not succeed.
+	type callOutcome struct {
+		result     json.RawMessage
+		failedStep string
+		err        error
+	}
+	runOnce := func() callOutcome {
+		tx, err := execDB.BeginTx(c.Request.Context(), txOptions)
+		if err != nil {
+			return callOutcome{failedStep: "begin", err: err}
+		}
+		defer tx.Rollback()
+
+		// Safe identifier quoting for role
+		quotedRole := fmt.Sprintf(`"%s"`, strings.ReplaceAll(dbRole, `"`, `""`))
+		if _, err := tx.ExecContext(c.Request.Context(), fmt.Sprintf("SET LOCAL ROLE %s", quotedRole)); err != nil {
+			return callOutcome{failedStep: "role", err: err}
+		}
+
+		// Unqualified function names resolve in the version's schema for
+		// /api/v2/... calls, or in DEFAULT_SCHEMA otherwise — never in
+		// whatever the connecting user's search_path happens to be.
+		if searchPath := s.callSearchPath(c, databaseName); searchPath != "" {
+			if _, err := tx.ExecContext(c.Request.Context(), `SELECT set_config('search_path', $1, true)`, searchPath); err != nil {
+				return callOutcome{failedStep: "search_path", err: err}
+			}
+		}
+
+		// Expose the request ID to SQL (visible in pg_stat_activity via
+		// current_setting('pgarachne.request_id', true)).
+		if requestID := c.GetString("request_id"); requestID != "" {
+			if _, err := tx.ExecContext(c.Request.Context(), `SELECT set_config('pgarachne.request_id', $1, true)`, requestID); err != nil {
+				reqLogger(c).Warn("Failed to set request_id GUC", "error", err)
+			}
+		}
+
+		// Tag the backend as pgarachne/<function> so DBAs can attribute load
+		// in pg_stat_activity to API functions rather than one anonymous app.
+		setApplicationName(c, tx, functionName)
+
+		// JWT claims become request.jwt.* GUCs for RLS and in-function
+		// authorization.
+		if err := setJWTClaimGUCs(c.Request.Context(), tx, c); err != nil {
+			return callOutcome{failedStep: "claims", err: err}
+		}
+
+		var resultJSON json.RawMessage
+		// The statement is prepared once per pool and reused; Tx.StmtContext
+		// picks up the instance already prepared on this transaction's
+		// connection. Preparation failures (e.g. unknown function) surface
+		// through the same error handling as execution failures.
+		if stmt, prepErr := s.stmts.prepare(c.Request.Context(), execDB, databaseName, query); prepErr == nil {
+			err = tx.StmtContext(c.Request.Context(), stmt).QueryRowContext(c.Request.Context(), paramsJSON).Scan(&resultJSON)
+		} else {
+			err = prepErr
+		}
+		if err != nil {
+			return callOutcome{failedStep: "call", err: err}
+		}
+
+		if pagination != nil {
+			// The count function receives the caller's params without the
+			// injected pagination keys.
+			delete(req.Params, "limit")
+			delete(req.Params, "offset")
+			countParams, _ := jsonMarshal(req.Params)
+			total := countFunctionTotal(c.Request.Context(), tx, functionName, countParams)
+			if wrapped, err := json.Marshal(paginationEnvelope(resultJSON, total, pagination)); err == nil {
+				resultJSON = wrapped
+			}
+		}
+
+		if err := tx.Commit(); err != nil {
+			return callOutcome{failedStep: "commit", err: err}
+		}
+		return callOutcome{result: resultJSON}
+	}
+
+	// Read-only functions are safe to retry transparently on transient
+	// failures (serialization conflicts, failovers); anything else gets
+	// exactly one attempt.
+	canRetry := s.isReadOnlyFunction(c, db, databaseName, functionName)
+	outcome := runOnce()
+	for attempt := 1; outcome.err != nil && canRetry && attempt < maxCallAttempts && transientCallError(outcome.err); attempt++ {
+		reqLogger(c).Warn("Retrying function call after transient error",
+			"function", functionName, "attempt", attempt, "error", outcome.err)
+		time.Sleep(callBackoff(attempt))
+		outcome = runOnce()
+	}
+
+	// Feed the circuit breaker: successes close it, connection-level
+	// failures accumulate towards opening it. SQL-level errors (bad params,
+	// permission denied) say nothing about database health.
+	if outcome.err == nil {
+		s.breaker.recordSuccess(databaseName)
+	} else if transientCallError(outcome.err) {
+		s.breaker.recordFailure(databaseName)
+	}
+
+	// A failed execution releases the key's pending marker, so the client's
+	// retry runs instead of waiting out the marker TTL.
+	if outcome.failedStep != "" && idemKey != "" {
+		s.releaseIdempotent(c, idemKey)
+	}
+
+	switch outcome.failedStep {
+	case "":
+		// Success.
+	case "begin":
+		reqLogger(c).Error("Failed to begin transaction", "error", outcome.err)
+		c.JSON(http.StatusServiceUnavailable, JSONRPCResponse{Error: &JSONRPCError{Message: "Database unavailable"}, ID: req.ID})
+		return
+	case "role":
+		reqLogger(c).Error("Failed to SET ROLE", "role", dbRole, "error", outcome.err)
+		c.JSON(http.StatusForbidden, JSONRPCResponse{Error: &JSONRPCError{Code: -32001, Message: "Permission denied for the specified role"}, ID: req.ID})
+		return
+	case "search_path":
+		reqLogger(c).Error("Failed to set search_path", "error", outcome.err)
+		c.JSON(http.StatusInternalServerError, JSONRPCResponse{Error: &JSONRPCError{Message: "Failed to apply search_path"}, ID: req.ID})
+		return
+	case "claims":
+		reqLogger(c).Error("Failed to set JWT claim GUCs", "error", outcome.err)
+		c.JSON(http.StatusInternalServerError, JSONRPCResponse{Error: &JSONRPCError{Message: "Failed to apply session claims"}, ID: req.ID})
+		return
+	case "call":
+		err := outcome.err
+		reqLogger(c).Error("Function call failed", "function", functionName, "error", err)
+		if sqlstate, retryAfter, ok := lockRetryHint(err); ok {
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.JSON(http.StatusServiceUnavailable, JSONRPCResponse{Error: &JSONRPCError{
+				Code:    -32002,
+				Message: "Resource temporarily locked, retry later",
+				Data: gin.H{
+					"retryable":           true,
+					"retry_after_seconds": retryAfter,
+					"sqlstate":            sqlstate,
+				},
+			}, ID: req.ID})
+		} else if status, rpcErr, ok := sqlErrorResponse(err); ok {
+			c.JSON(status, JSONRPCResponse{Error: rpcErr, ID: req.ID})
+		} else if strings.Contains(err.Error(), "does not exist") {
 			c.JSON(http.StatusNotFound, JSONRPCResponse{Error: &JSONRPCError{Message: "Function does not exist"}, ID: req.ID})
 		} else {
 			c.JSON(http.StatusInternalServerError, JSONRPCResponse{Error: &JSONRPCError{Message: fmt.Sprintf("Function call failed: %v", err)}, ID: req.ID})
 		}
 		return
+	case "commit":
+		reqLogger(c).Error("Transaction commit failed", "error", outcome.err)
+		c.JSON(http.StatusInternalServerError, JSONRPCResponse{Error: &JSONRPCError{Message: "Transaction commit failed"}, ID: req.ID})
+		return
 	}
+	resultJSON := outcome.result
 
-	if err := tx.Commit(); err != nil {
-		slog.Error("Transaction commit failed", "error", err)
-		c.JSON(http.StatusInternalServerError, JSONRPCResponse{Error: &JSONRPCError{Message: "Transaction commit failed"}, ID: req.ID})
+	if s.Cfg().UsageAccounting {
+		c.Set("usage_rows", resultRows(resultJSON))
+	}
+
+	// A request without an "id" is a JSON-RPC notification: the function has
+	// run and committed, but the client asked for no result — respond 204
+	// with no body.
+	if req.ID == nil {
+		if idemKey != "" {
+			s.storeIdempotent(c, idemKey, idemFingerprint, http.StatusNoContent, nil)
+		}
+		c.Status(http.StatusNoContent)
 		return
 	}
 
-	c.JSON(http.StatusOK, JSONRPCResponse{
-		JSONRPC: "2.0", Result: resultJSON, ID: req.ID,
-	})
+	// Tabular downloads: ?format=csv / Accept: text/csv turns an array of
+	// objects into a CSV (or TSV) attachment.
+	if format := tabularFormat(c); format != "" && writeTabular(c, functionName, resultJSON, format) {
+		// The function has committed, so the claimed key must not stay
+		// pending; a retry replays the result as JSON rather than running
+		// the function a second time.
+		if idemKey != "" {
+			s.storeIdempotent(c, idemKey, idemFingerprint,
+				http.StatusOK, JSONRPCResponse{JSONRPC: "2.0", Result: resultJSON, ID: req.ID})
+		}
+		return
+	}
+
+	// Object results with a "version" field feed the delta-sync loop.
+	setVersionETag(c, resultJSON)
+
+	resp := JSONRPCResponse{JSONRPC: "2.0", Result: resultJSON, ID: req.ID}
+	if idemKey != "" {
+		s.storeIdempotent(c, idemKey, idemFingerprint, http.StatusOK, resp)
+	}
+	writeRPCResponse(c, http.StatusOK, resp)
 }
 
 func (s *Server) handleHealthCheck(c *gin.Context) {
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/pgarachne/internal/database"
+)
+
+// Self-service signup: with SIGNUP_FUNCTION configured, the public,
+// rate-limited POST /api/{database}/signup forwards the JSON body to that
+// function, running as SIGNUP_ROLE (when set) so account creation happens
+// under a deliberately restricted identity. The function returns a JSON
+// document that is passed through to the client — typically either a
+// verification-required status or enough to log in right away.
+
+// signupWindow is the fixed rate-limit window for signup attempts per IP.
+const signupWindow = time.Hour
+
+func (s *Server) handleSignup(c *gin.Context) {
+	if s.Cfg().SignupFunction == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Signup is not enabled"})
+		return
+	}
+
+	// Per-IP rate limit through the shared state store, so it holds across
+	// instances when STATE_STORAGE=postgres.
+	count, err := s.state.Incr(c.Request.Context(), "signup", c.ClientIP(), signupWindow)
+	if err != nil {
+		reqLogger(c).Error("Signup rate limit check failed", "error", err)
+	} else if count > int64(s.Cfg().SignupRateLimit) {
+		rateLimitHitsTotal.WithLabelValues(c.Param("database"), "signup").Inc()
+		c.Header("Retry-After", fmt.Sprintf("%d", int(signupWindow.Seconds())))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many signup attempts, try again later"})
+		return
+	}
+
+	var payload map[string]interface{}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON body"})
+		return
+	}
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON body"})
+		return
+	}
+
+	db, err := database.GetConnection(s.Cfg(), c.Param("database"))
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Database connection failed"})
+		return
+	}
+
+	tx, err := db.BeginTx(c.Request.Context(), nil)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Database unavailable"})
+		return
+	}
+	defer tx.Rollback()
+
+	if s.Cfg().SignupRole != "" {
+		quotedRole := fmt.Sprintf(`"%s"`, strings.ReplaceAll(s.Cfg().SignupRole, `"`, `""`))
+		if _, err := tx.ExecContext(c.Request.Context(), fmt.Sprintf("SET LOCAL ROLE %s", quotedRole)); err != nil {
+			reqLogger(c).Error("Signup SET ROLE failed", "role", s.Cfg().SignupRole, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Signup failed"})
+			return
+		}
+	}
+
+	var resultJSON json.RawMessage
+	query := fmt.Sprintf("SELECT %s($1::jsonb)::json", s.Cfg().SignupFunction)
+	if err := tx.QueryRowContext(c.Request.Context(), query, payloadJSON).Scan(&resultJSON); err != nil {
+		reqLogger(c).Warn("Signup function rejected request", "error", err)
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Signup rejected"})
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Signup failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"result": resultJSON})
+}
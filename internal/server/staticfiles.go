@@ -0,0 +1,156 @@
+package server
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Static file fallback with a real caching policy: hashed assets (the
+// `app.3f9c2d1a.js` pattern bundlers emit) are immutable and cacheable for a
+// year, index.html is always revalidated so deploys take effect, everything
+// else gets STATIC_CACHE_MAX_AGE (default 1h). When the client accepts it
+// and a sibling `.br` or `.gz` file exists, the precompressed variant is
+// served with the original content type.
+
+// staticHashedPattern matches a content-hash segment in a filename.
+var staticHashedPattern = regexp.MustCompile(`\.[0-9a-fA-F]{8,}\.`)
+
+// staticCacheControl picks the Cache-Control value for a request path.
+func (s *Server) staticCacheControl(urlPath string) string {
+	base := path.Base(urlPath)
+	switch {
+	case urlPath == "/" || base == "index.html":
+		return "no-cache"
+	case staticHashedPattern.MatchString(base):
+		return "public, max-age=31536000, immutable"
+	default:
+		return fmt.Sprintf("public, max-age=%d", int(s.Cfg().StaticCacheMaxAge.Seconds()))
+	}
+}
+
+// precompressedVariant returns the encoding and on-disk path of a
+// precompressed sibling acceptable to the client, or "" when none applies.
+func precompressedVariant(root, urlPath, acceptEncoding string) (encoding, diskPath string) {
+	if strings.HasSuffix(urlPath, "/") {
+		urlPath += "index.html"
+	}
+	candidates := []struct{ encoding, suffix string }{
+		{"br", ".br"},
+		{"gzip", ".gz"},
+	}
+	for _, candidate := range candidates {
+		if !strings.Contains(acceptEncoding, candidate.encoding) {
+			continue
+		}
+		diskPath := filepath.Join(root, filepath.FromSlash(urlPath)+candidate.suffix)
+		if info, err := os.Stat(diskPath); err == nil && !info.IsDir() {
+			return candidate.encoding, diskPath
+		}
+	}
+	return "", ""
+}
+
+// staticAllowed applies the hardening toggles: dotfiles, directory
+// listings, the extension allowlist and symlink escapes are all refused
+// unless explicitly enabled.
+func (s *Server) staticAllowed(root, urlPath string) bool {
+	if !s.Cfg().StaticServeDotfiles {
+		for _, segment := range strings.Split(urlPath, "/") {
+			if strings.HasPrefix(segment, ".") {
+				return false
+			}
+		}
+	}
+
+	diskPath := filepath.Join(root, filepath.FromSlash(urlPath))
+	info, err := os.Stat(diskPath)
+	if err != nil {
+		return true // let the file server produce its own 404
+	}
+
+	if info.IsDir() {
+		// Directories are only served when they have an index or listings
+		// are enabled.
+		if _, err := os.Stat(filepath.Join(diskPath, "index.html")); err != nil && !s.Cfg().StaticDirListing {
+			return false
+		}
+		return true
+	}
+
+	if len(s.Cfg().StaticExtensions) > 0 {
+		ext := strings.TrimPrefix(strings.ToLower(path.Ext(urlPath)), ".")
+		allowed := false
+		for _, candidate := range s.Cfg().StaticExtensions {
+			if ext == strings.ToLower(strings.TrimPrefix(candidate, ".")) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+
+	if !s.Cfg().StaticFollowSymlinks {
+		// A symlink anywhere in the resolved path must not escape the root.
+		resolved, err := filepath.EvalSymlinks(diskPath)
+		if err != nil {
+			return false
+		}
+		resolvedRoot, err := filepath.EvalSymlinks(root)
+		if err != nil {
+			return false
+		}
+		if resolved != resolvedRoot && !strings.HasPrefix(resolved, resolvedRoot+string(filepath.Separator)) {
+			return false
+		}
+	}
+	return true
+}
+
+// staticFileHandler is the NoRoute fallback serving StaticFilesPath.
+func (s *Server) staticFileHandler() gin.HandlerFunc {
+	root := s.Cfg().StaticFilesPath
+	fileServer := http.FileServer(http.Dir(root))
+
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		urlPath := path.Clean("/" + c.Request.URL.Path)
+
+		if !s.staticAllowed(root, urlPath) {
+			c.Status(http.StatusNotFound)
+			return
+		}
+
+		c.Header("Cache-Control", s.staticCacheControl(urlPath))
+		c.Header("Vary", "Accept-Encoding")
+
+		if encoding, diskPath := precompressedVariant(root, urlPath, c.GetHeader("Accept-Encoding")); encoding != "" {
+			// Content-Type must come from the uncompressed name; ServeFile
+			// would derive it from the .br/.gz suffix.
+			logical := urlPath
+			if strings.HasSuffix(logical, "/") {
+				logical += "index.html"
+			}
+			if contentType := mime.TypeByExtension(path.Ext(logical)); contentType != "" {
+				c.Header("Content-Type", contentType)
+			}
+			c.Header("Content-Encoding", encoding)
+			http.ServeFile(c.Writer, c.Request, diskPath)
+			return
+		}
+
+		fileServer.ServeHTTP(c.Writer, c.Request)
+	}
+}
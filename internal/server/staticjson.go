@@ -0,0 +1,42 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// registerStaticJSONEndpoints serves operator-defined JSON documents (agent
+// manifests, .well-known files, ...) at their configured paths. The documents
+// may contain the placeholder {{BASE_URL}}, replaced with the configured
+// BASE_URL or, if unset, a base URL derived from the incoming request. Files
+// are re-read on each request so manifests can be edited without a restart.
+func (s *Server) registerStaticJSONEndpoints(router *gin.Engine) {
+	for urlPath, filePath := range s.Cfg().StaticJSONFiles {
+		urlPath, filePath := urlPath, filePath
+		router.GET(urlPath, func(c *gin.Context) {
+			content, err := os.ReadFile(filePath)
+			if err != nil {
+				slog.Error("Failed to read static JSON document", "path", urlPath, "file", filePath, "error", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Document unavailable"})
+				return
+			}
+
+			baseURL := s.Cfg().BaseURL
+			if baseURL == "" {
+				scheme := "http"
+				if c.Request.TLS != nil {
+					scheme = "https"
+				}
+				baseURL = scheme + "://" + c.Request.Host + s.Cfg().BasePath
+			}
+
+			rendered := strings.ReplaceAll(string(content), "{{BASE_URL}}", baseURL)
+			c.Data(http.StatusOK, "application/json; charset=utf-8", []byte(rendered))
+		})
+		slog.Info("Serving static JSON endpoint", "path", urlPath, "file", filePath)
+	}
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Prepared statement cache for the hot function-call path: the
+// "SELECT fn($1::jsonb)" text is prepared once per pool and reused, so
+// Postgres skips re-parsing on every request. database/sql tracks the
+// prepared instance per connection, and Tx.StmtContext reuses the one
+// already present on the transaction's connection.
+
+var (
+	stmtCacheHits = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pgarachne_stmt_cache_hits_total",
+		Help: "Function call statements served from the prepared statement cache.",
+	}, []string{"database"})
+	stmtCacheMisses = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pgarachne_stmt_cache_misses_total",
+		Help: "Function call statements prepared because they were not cached.",
+	}, []string{"database"})
+)
+
+type stmtCache struct {
+	mu sync.RWMutex
+	// Keyed by pool so entries from a pool that was reset or dropped can
+	// never be served against its replacement.
+	stmts map[*sql.DB]map[string]*sql.Stmt
+}
+
+func newStmtCache() *stmtCache {
+	return &stmtCache{stmts: make(map[*sql.DB]map[string]*sql.Stmt)}
+}
+
+// prepare returns the cached statement for query on db, preparing and
+// caching it on first use.
+func (sc *stmtCache) prepare(ctx context.Context, db *sql.DB, dbName, query string) (*sql.Stmt, error) {
+	sc.mu.RLock()
+	stmt, ok := sc.stmts[db][query]
+	sc.mu.RUnlock()
+	if ok {
+		stmtCacheHits.WithLabelValues(dbName).Inc()
+		return stmt, nil
+	}
+
+	stmtCacheMisses.WithLabelValues(dbName).Inc()
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if cached, ok := sc.stmts[db][query]; ok {
+		// Lost the race; keep the first one.
+		stmt.Close()
+		return cached, nil
+	}
+	if sc.stmts[db] == nil {
+		sc.stmts[db] = make(map[string]*sql.Stmt)
+	}
+	sc.stmts[db][query] = stmt
+	return stmt, nil
+}
+
+// purge drops every cached statement; called when pools are rebuilt.
+func (sc *stmtCache) purge() {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	for db, stmts := range sc.stmts {
+		for _, stmt := range stmts {
+			stmt.Close()
+		}
+		delete(sc.stmts, db)
+	}
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/yourusername/pgarachne/internal/database"
+	"github.com/yourusername/pgarachne/internal/lifecycle"
+	"github.com/yourusername/pgarachne/internal/sdnotify"
+)
+
+// registerSystemdHook reports readiness (Type=notify) once the listeners are
+// up and the database primary has answered a ping, and keeps the systemd
+// watchdog fed for the lifetime of the process. Must be registered after the
+// listener hooks so Start runs once they are serving. Outside systemd every
+// notification is a no-op.
+func (s *Server) registerSystemdHook(manager *lifecycle.Manager) {
+	done := make(chan struct{})
+	manager.Register(lifecycle.Hook{
+		Name: "systemd",
+		Start: func() error {
+			go func() {
+				// READY=1 is held back until the primary answers; a few
+				// retries cover Postgres starting up in parallel with us.
+				for attempt := 1; attempt <= 5; attempt++ {
+					if err := database.CheckPrimary(s.Cfg()); err == nil {
+						break
+					} else if attempt == 5 {
+						slog.Warn("Reporting ready without database confirmation", "error", err)
+					} else {
+						time.Sleep(2 * time.Second)
+					}
+				}
+				if err := sdnotify.Ready(); err != nil {
+					slog.Warn("Failed to notify systemd readiness", "error", err)
+				}
+
+				interval, enabled := sdnotify.WatchdogInterval()
+				if !enabled {
+					return
+				}
+				ticker := time.NewTicker(interval)
+				defer ticker.Stop()
+				for {
+					select {
+					case <-done:
+						return
+					case <-ticker.C:
+						if err := sdnotify.Watchdog(); err != nil {
+							slog.Warn("Failed to ping systemd watchdog", "error", err)
+						}
+					}
+				}
+			}()
+			return nil
+		},
+		Stop: func(context.Context) error {
+			sdnotify.Stopping()
+			close(done)
+			return nil
+		},
+	})
+}
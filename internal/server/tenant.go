@@ -0,0 +1,73 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Tenant routing: SaaS deployments want tenant-transparent URLs, so
+// "tenant1.api.example.com/api/get_orders" (TENANT_HOST_SUFFIX) or an
+// X-Tenant header (TENANT_HEADER) can select the database instead of the
+// URL path. The resolved tenant maps to a database through TENANT_DB_MAP
+// ("tenant=db" overrides) or TENANT_DB_PATTERN ("tenant_%s"), and the
+// request path is rewritten to the canonical /api/:database form before it
+// reaches the router — every handler, limit and policy keyed by database
+// keeps working unchanged.
+
+// tenantPattern keeps resolved tenant names path- and identifier-safe.
+var tenantPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// resolveTenant extracts the tenant from the header or the host; "" means
+// the request addresses a database by path as usual.
+func (s *Server) resolveTenant(r *http.Request) string {
+	if header := s.Cfg().TenantHeader; header != "" {
+		if tenant := r.Header.Get(header); tenant != "" {
+			return tenant
+		}
+	}
+	if suffix := s.Cfg().TenantHostSuffix; suffix != "" {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if tenant, found := strings.CutSuffix(host, suffix); found && tenant != "" && !strings.Contains(tenant, ".") {
+			return tenant
+		}
+	}
+	return ""
+}
+
+// tenantDatabase maps a tenant to its database name.
+func (s *Server) tenantDatabase(tenant string) string {
+	if db, ok := s.Cfg().TenantDBMap[tenant]; ok {
+		return db
+	}
+	if pattern := s.Cfg().TenantDBPattern; pattern != "" {
+		return fmt.Sprintf(pattern, tenant)
+	}
+	return tenant
+}
+
+// tenantRewriteHandler injects the tenant's database into /api paths before
+// routing. It wraps the router (rather than running as gin middleware)
+// because the path must change before route matching binds :database.
+func (s *Server) tenantRewriteHandler(next http.Handler) http.Handler {
+	if s.Cfg().TenantHeader == "" && s.Cfg().TenantHostSuffix == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rest, found := strings.CutPrefix(r.URL.Path, "/api/"); found {
+			if tenant := s.resolveTenant(r); tenant != "" {
+				if !tenantPattern.MatchString(tenant) {
+					http.Error(w, `{"error": "Invalid tenant"}`, http.StatusBadRequest)
+					return
+				}
+				r.URL.Path = "/api/" + s.tenantDatabase(tenant) + "/" + rest
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// certReloader serves the TLS certificate via tls.Config.GetCertificate and
+// reloads the key pair from disk when the files change, so certificates
+// renewed by e.g. certbot are picked up without a restart.
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	modTime time.Time
+
+	done chan struct{}
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	r := &certReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		done:     make(chan struct{}),
+	}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *certReloader) reload() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS key pair: %w", err)
+	}
+
+	info, err := os.Stat(r.certFile)
+	if err != nil {
+		return fmt.Errorf("failed to stat TLS certificate: %w", err)
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.modTime = info.ModTime()
+	r.mu.Unlock()
+	return nil
+}
+
+// GetCertificate is used as tls.Config.GetCertificate.
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// watch polls the certificate file mtime and reloads on change. Polling keeps
+// us dependency-free and is cheap at a 30s interval.
+func (r *certReloader) watch() {
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-ticker.C:
+			info, err := os.Stat(r.certFile)
+			if err != nil {
+				slog.Warn("Failed to stat TLS certificate", "file", r.certFile, "error", err)
+				continue
+			}
+			r.mu.RLock()
+			changed := info.ModTime().After(r.modTime)
+			r.mu.RUnlock()
+			if !changed {
+				continue
+			}
+			if err := r.reload(); err != nil {
+				slog.Error("Failed to reload TLS certificate", "file", r.certFile, "error", err)
+				continue
+			}
+			slog.Info("Reloaded TLS certificate", "file", r.certFile)
+		}
+	}
+}
+
+func (r *certReloader) stop(context.Context) error {
+	close(r.done)
+	return nil
+}
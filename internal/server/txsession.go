@@ -0,0 +1,265 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/pgarachne/internal/database"
+)
+
+// Multi-call transaction sessions: POST /api/:database/_tx/begin opens a
+// transaction and returns a token; function calls carrying that token in the
+// X-Transaction header run inside it, and _tx/:token/commit (or /rollback)
+// finishes it. Sessions are bound to the role that opened them and rolled
+// back by a janitor after txSessionIdleTimeout without activity, so an
+// abandoned client cannot hold locks forever.
+
+const (
+	txSessionHeader      = "X-Transaction"
+	txSessionIdleTimeout = 30 * time.Second
+)
+
+type txSession struct {
+	mu     sync.Mutex
+	tx     *sql.Tx
+	dbName string
+	role   string
+	// lastUsed holds the UnixNano of the last activity; atomic so the
+	// janitor can read it without taking the session mutex.
+	lastUsed atomic.Int64
+	finished bool
+}
+
+func (ts *txSession) touch() {
+	ts.lastUsed.Store(time.Now().UnixNano())
+}
+
+func (ts *txSession) idle() bool {
+	return time.Since(time.Unix(0, ts.lastUsed.Load())) > txSessionIdleTimeout
+}
+
+type txSessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]*txSession
+	done     chan struct{}
+}
+
+func newTxSessionStore() *txSessionStore {
+	store := &txSessionStore{sessions: make(map[string]*txSession), done: make(chan struct{})}
+	go store.janitor()
+	return store
+}
+
+// janitor rolls back sessions that have been idle past the timeout.
+func (st *txSessionStore) janitor() {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-st.done:
+			return
+		case <-ticker.C:
+		}
+		st.mu.Lock()
+		for token, session := range st.sessions {
+			if !session.idle() {
+				continue
+			}
+			// A held mutex means a call is executing right now; it will
+			// refresh lastUsed when it finishes, so leave the session alone.
+			if !session.mu.TryLock() {
+				continue
+			}
+			delete(st.sessions, token)
+			go func(session *txSession) {
+				defer session.mu.Unlock()
+				if !session.finished {
+					session.finished = true
+					session.tx.Rollback()
+				}
+			}(session)
+		}
+		st.mu.Unlock()
+	}
+}
+
+// stop ends the janitor goroutine; open sessions are left to their
+// databases' idle_in_transaction handling.
+func (st *txSessionStore) stop() {
+	close(st.done)
+}
+
+func (st *txSessionStore) get(token string) (*txSession, bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	session, ok := st.sessions[token]
+	return session, ok
+}
+
+func (st *txSessionStore) remove(token string) {
+	st.mu.Lock()
+	delete(st.sessions, token)
+	st.mu.Unlock()
+}
+
+// handleTxBegin opens a transaction as the caller's role and returns the
+// session token.
+func (s *Server) handleTxBegin(c *gin.Context) {
+	databaseName := c.Param("database")
+	dbRole := c.GetString("db_role")
+
+	if !s.checkAPIVersion(c, databaseName) {
+		return
+	}
+
+	db, err := database.GetConnection(s.Cfg(), databaseName)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, JSONRPCResponse{Error: &JSONRPCError{Message: "Database connection failed"}})
+		return
+	}
+
+	// The transaction outlives this request, so it must not be tied to the
+	// request context.
+	tx, err := db.BeginTx(context.Background(), nil)
+	if err != nil {
+		reqLogger(c).Error("Failed to begin transaction session", "error", err)
+		c.JSON(http.StatusServiceUnavailable, JSONRPCResponse{Error: &JSONRPCError{Message: "Database unavailable"}})
+		return
+	}
+
+	quotedRole := fmt.Sprintf(`"%s"`, strings.ReplaceAll(dbRole, `"`, `""`))
+	if _, err := tx.ExecContext(c.Request.Context(), fmt.Sprintf("SET LOCAL ROLE %s", quotedRole)); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusForbidden, JSONRPCResponse{Error: &JSONRPCError{Code: -32001, Message: "Permission denied for the specified role"}})
+		return
+	}
+	if err := s.applySearchPath(c, tx, databaseName); err != nil {
+		tx.Rollback()
+		reqLogger(c).Error("Failed to set search_path", "error", err)
+		c.JSON(http.StatusInternalServerError, JSONRPCResponse{Error: &JSONRPCError{Message: "Failed to apply search_path"}})
+		return
+	}
+	if err := setJWTClaimGUCs(c.Request.Context(), tx, c); err != nil {
+		tx.Rollback()
+		reqLogger(c).Error("Failed to set JWT claim GUCs", "error", err)
+		c.JSON(http.StatusInternalServerError, JSONRPCResponse{Error: &JSONRPCError{Message: "Failed to apply session claims"}})
+		return
+	}
+
+	raw := make([]byte, 16)
+	rand.Read(raw)
+	token := hex.EncodeToString(raw)
+
+	session := &txSession{tx: tx, dbName: databaseName, role: dbRole}
+	session.touch()
+	s.txSessions.mu.Lock()
+	s.txSessions.sessions[token] = session
+	s.txSessions.mu.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"transaction": token, "idle_timeout_seconds": int(txSessionIdleTimeout.Seconds())})
+}
+
+// sessionForRequest resolves and authorizes the caller's session token.
+func (s *Server) sessionForRequest(c *gin.Context, token string) *txSession {
+	session, ok := s.txSessions.get(token)
+	if !ok {
+		c.JSON(http.StatusNotFound, JSONRPCResponse{Error: &JSONRPCError{Message: "Unknown or expired transaction"}})
+		return nil
+	}
+	if session.dbName != c.Param("database") || session.role != c.GetString("db_role") {
+		c.JSON(http.StatusForbidden, JSONRPCResponse{Error: &JSONRPCError{Message: "Transaction belongs to a different role or database"}})
+		return nil
+	}
+	return session
+}
+
+// handleTxFinish commits or rolls back a session.
+func (s *Server) handleTxFinish(commit bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := c.Param("token")
+		session := s.sessionForRequest(c, token)
+		if session == nil {
+			return
+		}
+		s.txSessions.remove(token)
+
+		session.mu.Lock()
+		defer session.mu.Unlock()
+		if session.finished {
+			c.JSON(http.StatusNotFound, JSONRPCResponse{Error: &JSONRPCError{Message: "Unknown or expired transaction"}})
+			return
+		}
+		session.finished = true
+
+		var err error
+		action := "rolled_back"
+		if commit {
+			action = "committed"
+			err = session.tx.Commit()
+		} else {
+			err = session.tx.Rollback()
+		}
+		if err != nil {
+			reqLogger(c).Error("Transaction session finish failed", "commit", commit, "error", err)
+			c.JSON(http.StatusInternalServerError, JSONRPCResponse{Error: &JSONRPCError{Message: "Transaction finish failed"}})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"status": action})
+	}
+}
+
+// handleSessionCall runs one function call inside an open session.
+func (s *Server) handleSessionCall(c *gin.Context, functionName string, req *JSONRPCRequest) {
+	session := s.sessionForRequest(c, c.GetHeader(txSessionHeader))
+	if session == nil {
+		return
+	}
+
+	paramsJSON := []byte(req.RawParams)
+	if len(paramsJSON) == 0 {
+		var err error
+		if paramsJSON, err = jsonMarshal(req.Params); err != nil {
+			c.JSON(http.StatusInternalServerError, JSONRPCResponse{Error: &JSONRPCError{Message: "Failed to marshal params"}, ID: req.ID})
+			return
+		}
+	}
+
+	query := fmt.Sprintf("SELECT %s($1::jsonb)::json", functionName)
+	if functionName == "capabilities" {
+		query = `SELECT pgarachne.capabilities($1::jsonb)::json`
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if session.finished {
+		c.JSON(http.StatusNotFound, JSONRPCResponse{Error: &JSONRPCError{Message: "Unknown or expired transaction"}, ID: req.ID})
+		return
+	}
+	session.touch()
+	defer session.touch()
+
+	var resultJSON json.RawMessage
+	if err := session.tx.QueryRowContext(c.Request.Context(), query, paramsJSON).Scan(&resultJSON); err != nil {
+		reqLogger(c).Error("Function call failed in transaction session", "function", functionName, "error", err)
+		if status, rpcErr, ok := sqlErrorResponse(err); ok {
+			c.JSON(status, JSONRPCResponse{Error: rpcErr, ID: req.ID})
+		} else if strings.Contains(err.Error(), "does not exist") {
+			c.JSON(http.StatusNotFound, JSONRPCResponse{Error: &JSONRPCError{Message: "Function does not exist"}, ID: req.ID})
+		} else {
+			c.JSON(http.StatusInternalServerError, JSONRPCResponse{Error: &JSONRPCError{Message: fmt.Sprintf("Function call failed: %v", err)}, ID: req.ID})
+		}
+		return
+	}
+
+	writeRPCResponse(c, http.StatusOK, JSONRPCResponse{JSONRPC: "2.0", Result: resultJSON, ID: req.ID})
+}
@@ -7,6 +7,12 @@ type JSONRPCRequest struct {
 	Method  string                 `json:"method"`
 	Params  map[string]interface{} `json:"params"`
 	ID      interface{}            `json:"id"`
+
+	// RawParams holds the params object exactly as the client sent it,
+	// captured by bindJSONRPCRequest. The call path passes it to Postgres
+	// verbatim unless Params was rewritten (pagination, delta sync), which
+	// skips a full re-encode per request.
+	RawParams json.RawMessage `json:"-"`
 }
 
 type JSONRPCResponse struct {
@@ -17,8 +23,9 @@ type JSONRPCResponse struct {
 }
 
 type JSONRPCError struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
 }
 
 type LoginRequest struct {
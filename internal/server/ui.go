@@ -0,0 +1,35 @@
+package server
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Embedded console UI at /ui: browse a database's capabilities, log in for
+// a JWT and invoke functions interactively — the JSON-RPC equivalent of
+// GraphiQL. It is a static page that only talks to the public API, so it
+// ships enabled; disable it with UI_ENABLED=false.
+
+//go:embed ui
+var uiAssets embed.FS
+
+func (s *Server) registerUIEndpoints(router *gin.Engine) {
+	if !s.Cfg().UIEnabled {
+		return
+	}
+	assets, err := fs.Sub(uiAssets, "ui")
+	if err != nil {
+		// The embed is part of the binary; failure here is a build defect.
+		panic(err)
+	}
+	fileServer := http.StripPrefix("/ui", http.FileServer(http.FS(assets)))
+	router.GET("/ui", func(c *gin.Context) {
+		c.Redirect(http.StatusMovedPermanently, "/ui/")
+	})
+	router.GET("/ui/*filepath", func(c *gin.Context) {
+		fileServer.ServeHTTP(c.Writer, c.Request)
+	})
+}
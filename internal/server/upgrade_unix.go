@@ -0,0 +1,81 @@
+//go:build !windows
+
+package server
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"github.com/yourusername/pgarachne/internal/lifecycle"
+)
+
+// Zero-downtime restart: SIGUSR2 makes the running process spawn the (possibly
+// upgraded) binary with the TCP listener fd inherited, then drain and exit.
+// The successor finds the fd via PGARACHNE_GRACEFUL_FD and serves on it
+// immediately, so no connection is ever refused during the handover.
+
+const gracefulFDEnv = "PGARACHNE_GRACEFUL_FD"
+
+// inheritedListener returns the listener handed over by a predecessor during
+// a zero-downtime upgrade, or nil when starting fresh.
+func inheritedListener() (net.Listener, error) {
+	if os.Getenv(gracefulFDEnv) == "" {
+		return nil, nil
+	}
+	// The fd is always passed as ExtraFiles[0], i.e. fd 3.
+	file := os.NewFile(3, "graceful-listener")
+	listener, err := net.FileListener(file)
+	file.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to rebuild inherited listener: %w", err)
+	}
+	slog.Info("Inherited listener from predecessor process", "addr", listener.Addr().String())
+	return listener, nil
+}
+
+// watchUpgradeSignal handles SIGUSR2 for the lifetime of the process.
+func (s *Server) watchUpgradeSignal(manager *lifecycle.Manager, listener net.Listener) {
+	upgrade := make(chan os.Signal, 1)
+	signal.Notify(upgrade, syscall.SIGUSR2)
+	go func() {
+		for range upgrade {
+			slog.Info("SIGUSR2 received, starting zero-downtime upgrade")
+			if err := spawnSuccessor(listener); err != nil {
+				slog.Error("Zero-downtime upgrade failed, continuing to serve", "error", err)
+				continue
+			}
+			// The successor owns the listener now; drain and exit.
+			manager.RequestShutdown("upgrade handover")
+			return
+		}
+	}()
+}
+
+func spawnSuccessor(listener net.Listener) error {
+	tcpListener, ok := listener.(*net.TCPListener)
+	if !ok {
+		return fmt.Errorf("listener is not a TCP listener, cannot hand over")
+	}
+	file, err := tcpListener.File()
+	if err != nil {
+		return fmt.Errorf("failed to dup listener fd: %w", err)
+	}
+	defer file.Close()
+
+	cmd := exec.Command(os.Args[0], os.Args[1:]...)
+	cmd.Env = append(os.Environ(), gracefulFDEnv+"=3")
+	cmd.ExtraFiles = []*os.File{file}
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start successor process: %w", err)
+	}
+	slog.Info("Successor process started", "pid", cmd.Process.Pid)
+	return nil
+}
@@ -0,0 +1,18 @@
+//go:build windows
+
+package server
+
+import (
+	"net"
+
+	"github.com/yourusername/pgarachne/internal/lifecycle"
+)
+
+// Zero-downtime restart relies on SIGUSR2 and fd inheritance, neither of
+// which exists on Windows; upgrades there go through a normal restart.
+
+func inheritedListener() (net.Listener, error) {
+	return nil, nil
+}
+
+func (s *Server) watchUpgradeSignal(*lifecycle.Manager, net.Listener) {}
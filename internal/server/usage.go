@@ -0,0 +1,329 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/pgarachne/internal/database"
+	"github.com/yourusername/pgarachne/internal/lifecycle"
+)
+
+// Usage accounting: with USAGE_ACCOUNTING enabled, every function call adds
+// to an in-memory tally of calls, rows returned and execution time per role
+// (tokens map to roles, so a role is the billable identity), flushed to
+// pgarachne.usage in monthly buckets. Callers can inspect their own
+// consumption via GET /api/{database}/_usage, and a monthly_calls quota in
+// pgarachne.limits turns the accounting into enforcement: calls beyond the
+// budget get 429 until the month rolls over.
+
+// usageFlushInterval spaces the UPSERTs into pgarachne.usage; the tally
+// stays in memory between flushes, so a crash can lose at most this much.
+const usageFlushInterval = 30 * time.Second
+
+type usageKey struct {
+	db   string
+	role string
+}
+
+type usageDelta struct {
+	calls  int64
+	rows   int64
+	execMS int64
+}
+
+type usageRecorder struct {
+	mu     sync.Mutex
+	deltas map[usageKey]*usageDelta
+	// totals tracks this month's call counts (database value plus local
+	// deltas) for quota checks; reset on month rollover.
+	totals map[usageKey]int64
+	month  string
+}
+
+func newUsageRecorder() *usageRecorder {
+	return &usageRecorder{
+		deltas: make(map[usageKey]*usageDelta),
+		totals: make(map[usageKey]int64),
+		month:  time.Now().UTC().Format("2006-01"),
+	}
+}
+
+// rollover resets the quota totals when the month changes. Callers hold mu.
+func (u *usageRecorder) rollover() {
+	if month := time.Now().UTC().Format("2006-01"); month != u.month {
+		u.month = month
+		u.totals = make(map[usageKey]int64)
+	}
+}
+
+func (u *usageRecorder) record(dbName, role string, rows int64, elapsed time.Duration) {
+	key := usageKey{db: dbName, role: role}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.rollover()
+	delta, ok := u.deltas[key]
+	if !ok {
+		delta = &usageDelta{}
+		u.deltas[key] = delta
+	}
+	delta.calls++
+	delta.rows += rows
+	delta.execMS += elapsed.Milliseconds()
+	if _, seeded := u.totals[key]; seeded {
+		u.totals[key]++
+	}
+}
+
+// quotaExceeded reports whether the role has used up its monthly call
+// budget. The first check each month seeds the counter from the database so
+// restarts and other instances are accounted for.
+func (u *usageRecorder) quotaExceeded(s *Server, dbName, role string, quota int64) bool {
+	key := usageKey{db: dbName, role: role}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.rollover()
+	total, seeded := u.totals[key]
+	if !seeded {
+		total = loadUsageCalls(s, dbName, role)
+		if delta, ok := u.deltas[key]; ok {
+			total += delta.calls
+		}
+		u.totals[key] = total
+	}
+	return total >= quota
+}
+
+// monthStart returns the first day of the current UTC month, the bucket key
+// in pgarachne.usage.
+func monthStart() time.Time {
+	now := time.Now().UTC()
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+func loadUsageCalls(s *Server, dbName, role string) int64 {
+	db, err := database.GetConnection(s.Cfg(), dbName)
+	if err != nil {
+		return 0
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var calls int64
+	err = db.QueryRowContext(ctx,
+		`SELECT calls FROM pgarachne.usage WHERE month = $1 AND role = $2`,
+		monthStart(), role).Scan(&calls)
+	if err != nil && err != sql.ErrNoRows {
+		return 0
+	}
+	return calls
+}
+
+var ensuredUsageTables sync.Map
+
+func ensureUsageTable(db *sql.DB, dbName string) error {
+	if _, ok := ensuredUsageTables.Load(dbName); ok {
+		return nil
+	}
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS pgarachne.usage (
+			month DATE NOT NULL,
+			role TEXT NOT NULL,
+			calls BIGINT NOT NULL DEFAULT 0,
+			rows_returned BIGINT NOT NULL DEFAULT 0,
+			exec_ms BIGINT NOT NULL DEFAULT 0,
+			PRIMARY KEY (month, role)
+		)`)
+	if err != nil {
+		return err
+	}
+	ensuredUsageTables.Store(dbName, true)
+	return nil
+}
+
+// flush UPSERTs the accumulated deltas into each database's usage table.
+func (u *usageRecorder) flush(ctx context.Context, s *Server) {
+	u.mu.Lock()
+	pending := u.deltas
+	u.deltas = make(map[usageKey]*usageDelta)
+	u.mu.Unlock()
+
+	for key, delta := range pending {
+		db, err := database.GetConnection(s.Cfg(), key.db)
+		if err != nil {
+			slog.Error("Usage flush skipped, database unavailable", "database", key.db, "error", err)
+			continue
+		}
+		if err := ensureUsageTable(db, key.db); err != nil {
+			slog.Error("Failed to ensure usage table", "database", key.db, "error", err)
+			continue
+		}
+		var calls int64
+		err = db.QueryRowContext(ctx, `
+			INSERT INTO pgarachne.usage (month, role, calls, rows_returned, exec_ms)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (month, role) DO UPDATE SET
+				calls = pgarachne.usage.calls + EXCLUDED.calls,
+				rows_returned = pgarachne.usage.rows_returned + EXCLUDED.rows_returned,
+				exec_ms = pgarachne.usage.exec_ms + EXCLUDED.exec_ms
+			RETURNING calls`,
+			monthStart(), key.role, delta.calls, delta.rows, delta.execMS).Scan(&calls)
+		if err != nil {
+			slog.Error("Usage flush failed", "database", key.db, "role", key.role, "error", err)
+			continue
+		}
+		// The authoritative count folds in the other instances' flushes.
+		u.mu.Lock()
+		if _, seeded := u.totals[key]; seeded {
+			u.totals[key] = calls
+		}
+		u.mu.Unlock()
+	}
+}
+
+func (s *Server) registerUsageFlushHook(manager *lifecycle.Manager) {
+	if !s.Cfg().UsageAccounting {
+		return
+	}
+	done := make(chan struct{})
+	manager.Register(lifecycle.Hook{
+		Name: "usage-recorder",
+		Start: func() error {
+			go func() {
+				ticker := time.NewTicker(usageFlushInterval)
+				defer ticker.Stop()
+				for {
+					select {
+					case <-done:
+						return
+					case <-ticker.C:
+						s.usage.flush(context.Background(), s)
+					}
+				}
+			}()
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			close(done)
+			s.usage.flush(ctx, s)
+			return nil
+		},
+	})
+}
+
+// resultRows counts what a result is worth in rows: the element count for a
+// top-level array, one for anything else, zero for empty or null results.
+func resultRows(resultJSON json.RawMessage) int64 {
+	trimmed := bytes.TrimSpace(resultJSON)
+	if len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) {
+		return 0
+	}
+	if trimmed[0] != '[' {
+		return 1
+	}
+	decoder := json.NewDecoder(bytes.NewReader(trimmed))
+	decoder.UseNumber()
+	depth, rows := 0, int64(0)
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return rows
+		}
+		if delim, ok := token.(json.Delim); ok {
+			switch delim {
+			case '{', '[':
+				if depth == 1 {
+					rows++
+				}
+				depth++
+			case '}', ']':
+				depth--
+				if depth == 0 {
+					return rows
+				}
+			}
+		} else if depth == 1 {
+			rows++
+		}
+	}
+}
+
+// usageMiddleware enforces the monthly quota before the call and accounts
+// for it afterwards. The handler reports the row count via the usage_rows
+// context key; endpoints that never set it count as zero rows.
+func (s *Server) usageMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		dbName := c.Param("database")
+		role := c.GetString("db_role")
+
+		if limit, ok := s.roleLimits.limits(s, dbName)[role]; ok && limit.monthlyCalls > 0 {
+			if s.usage.quotaExceeded(s, dbName, role, limit.monthlyCalls) {
+				rateLimitHitsTotal.WithLabelValues(dbName, "quota").Inc()
+				c.AbortWithStatusJSON(http.StatusTooManyRequests, JSONRPCResponse{Error: &JSONRPCError{
+					Code:    -32004,
+					Message: "Monthly call quota exceeded",
+				}})
+				return
+			}
+		}
+
+		started := time.Now()
+		c.Next()
+		if c.Writer.Status() < http.StatusBadRequest {
+			s.usage.record(dbName, role, c.GetInt64("usage_rows"), time.Since(started))
+		}
+	}
+}
+
+// handleUsage serves the caller's own consumption, most recent months first.
+func (s *Server) handleUsage(c *gin.Context) {
+	databaseName := c.Param("database")
+	role := c.GetString("db_role")
+	db, err := database.GetConnection(s.Cfg(), databaseName)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Database connection failed"})
+		return
+	}
+	if err := ensureUsageTable(db, databaseName); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Usage storage unavailable"})
+		return
+	}
+
+	rows, err := db.QueryContext(c.Request.Context(), `
+		SELECT month, calls, rows_returned, exec_ms FROM pgarachne.usage
+		WHERE role = $1 ORDER BY month DESC LIMIT 12`, role)
+	if err != nil {
+		reqLogger(c).Error("Failed to read usage", "database", databaseName, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read usage"})
+		return
+	}
+	defer rows.Close()
+
+	type usageMonth struct {
+		Month        string `json:"month"`
+		Calls        int64  `json:"calls"`
+		RowsReturned int64  `json:"rows_returned"`
+		ExecMS       int64  `json:"exec_ms"`
+	}
+	months := []usageMonth{}
+	for rows.Next() {
+		var m usageMonth
+		var month time.Time
+		if err := rows.Scan(&month, &m.Calls, &m.RowsReturned, &m.ExecMS); err != nil {
+			continue
+		}
+		m.Month = month.Format("2006-01")
+		months = append(months, m)
+	}
+
+	result := gin.H{"role": role, "months": months}
+	if limit, ok := s.roleLimits.limits(s, databaseName)[role]; ok && limit.monthlyCalls > 0 {
+		result["monthly_quota"] = limit.monthlyCalls
+	}
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/pgarachne/internal/config"
+)
+
+// API versioning: /api/v2/{database}/{function} maps the version segment to
+// a schema (API_VERSIONS="v1=api,v2=api_v2", overridable per database), and
+// the call runs with SET LOCAL search_path pointing at it. SQL authors
+// evolve function signatures by creating api_v2.* next to api.* — existing
+// clients keep calling the old paths untouched.
+
+var apiVersionPattern = regexp.MustCompile(`^v[0-9]+$`)
+
+type apiVersionContextKey struct{}
+
+// apiVersion returns the version segment stripped from the request path, or
+// "" for unversioned calls.
+func apiVersion(c *gin.Context) string {
+	version, _ := c.Request.Context().Value(apiVersionContextKey{}).(string)
+	return version
+}
+
+// versionSchema resolves a version to its schema for the database; the
+// per-database map wins over the global one.
+func versionSchema(cfg *config.Config, dbName, version string) string {
+	if overrides, ok := cfg.APIVersionsDB[dbName]; ok {
+		if schema, ok := overrides[version]; ok {
+			return schema
+		}
+	}
+	return cfg.APIVersions[version]
+}
+
+// versionKnown reports whether any map — global or per-database — defines
+// the version. The handler runs before tenant rewriting has settled the
+// database name, so this is the strongest existence check it can make; the
+// per-call resolution against the final name happens in callSearchPath.
+func versionKnown(cfg *config.Config, version string) bool {
+	if cfg.APIVersions[version] != "" {
+		return true
+	}
+	for _, overrides := range cfg.APIVersionsDB {
+		if overrides[version] != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// apiVersionHandler strips a leading version segment from /api paths before
+// the router matches them, remembering it in the request context. The
+// wrapper is a no-op until versions are configured.
+func (s *Server) apiVersionHandler(next http.Handler) http.Handler {
+	if len(s.Cfg().APIVersions) == 0 && len(s.Cfg().APIVersionsDB) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rest, ok := strings.CutPrefix(r.URL.Path, "/api/")
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		version, remainder, _ := strings.Cut(rest, "/")
+		if !apiVersionPattern.MatchString(version) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !versionKnown(s.Cfg(), version) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": "Unknown API version"}`))
+			return
+		}
+		r = r.WithContext(context.WithValue(r.Context(), apiVersionContextKey{}, version))
+		r.URL.Path = "/api/" + remainder
+		next.ServeHTTP(w, r)
+	})
+}
+
+// checkAPIVersion rejects a versioned call whose version resolves to no
+// schema for this database; it runs after tenant rewriting, against the
+// final database name. It returns true when the call may proceed.
+func (s *Server) checkAPIVersion(c *gin.Context, dbName string) bool {
+	version := apiVersion(c)
+	if version == "" || versionSchema(s.Cfg(), dbName, version) != "" {
+		return true
+	}
+	c.JSON(http.StatusNotFound, JSONRPCResponse{Error: &JSONRPCError{Message: "Unknown API version"}})
+	return false
+}
+
+// callSearchPath returns the search_path for the call: the version's schema
+// for versioned requests, the database's default schema (DEFAULT_SCHEMA /
+// DEFAULT_SCHEMA_DB) otherwise, "" when neither is configured.
+func (s *Server) callSearchPath(c *gin.Context, dbName string) string {
+	if version := apiVersion(c); version != "" {
+		return versionSchema(s.Cfg(), dbName, version)
+	}
+	if schema, ok := s.Cfg().DefaultSchemaDB[dbName]; ok {
+		return schema
+	}
+	return s.Cfg().DefaultSchema
+}
+
+// applySearchPath sets the transaction-local search_path when one applies;
+// used by the paths that build their transactions outside runOnce.
+func (s *Server) applySearchPath(c *gin.Context, tx *sql.Tx, dbName string) error {
+	searchPath := s.callSearchPath(c, dbName)
+	if searchPath == "" {
+		return nil
+	}
+	_, err := tx.ExecContext(c.Request.Context(), `SELECT set_config('search_path', $1, true)`, searchPath)
+	return err
+}
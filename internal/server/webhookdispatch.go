@@ -0,0 +1,254 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/yourusername/pgarachne/internal/database"
+	"github.com/yourusername/pgarachne/internal/leader"
+	"github.com/yourusername/pgarachne/internal/lifecycle"
+)
+
+// Outgoing webhook dispatcher: for every database listed in
+// WEBHOOK_DATABASES it LISTENs on the channels found in
+// pgarachne.webhook_subscriptions and POSTs each NOTIFY payload to the
+// subscribed URLs. Deliveries are HMAC-signed with the subscription secret
+// and retried with backoff; exhausted deliveries are dead-letter logged.
+
+// webhookRetrySchedule spaces the delivery attempts; after the last one the
+// payload is dead-lettered.
+var webhookRetrySchedule = []time.Duration{0, 1 * time.Second, 5 * time.Second, 25 * time.Second}
+
+// validateWebhookURL rejects subscription targets the dispatcher would never
+// deliver to: only http/https URLs with a host are accepted. The private
+// address ranges are additionally enforced at delivery time, where the name
+// actually resolves.
+func validateWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("not a valid URL")
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("scheme must be http or https")
+	}
+	if u.Hostname() == "" {
+		return fmt.Errorf("missing host")
+	}
+	return nil
+}
+
+// privateWebhookIP reports addresses deliveries must never reach: loopback,
+// RFC 1918, link-local (cloud metadata lives at 169.254.169.254) and
+// unspecified.
+func privateWebhookIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsUnspecified()
+}
+
+// webhookDialContext resolves the target itself and dials the vetted IP
+// directly, so stored URLs cannot be used for SSRF into internal networks —
+// including via DNS answers that change between check and dial. Operators
+// whose consumers genuinely live on private addresses opt out with
+// WEBHOOK_ALLOW_PRIVATE.
+func webhookDialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	for _, ip := range ips {
+		if privateWebhookIP(ip.IP) {
+			return nil, fmt.Errorf("refusing webhook delivery to private address %s", ip.IP)
+		}
+	}
+	return dialer.DialContext(ctx, network, net.JoinHostPort(ips[0].IP.String(), port))
+}
+
+// webhookClient builds the delivery client, with the private-range guard
+// unless the operator allows internal targets.
+func webhookClient(allowPrivate bool) *http.Client {
+	client := &http.Client{Timeout: 10 * time.Second}
+	if !allowPrivate {
+		client.Transport = &http.Transport{DialContext: webhookDialContext}
+	}
+	return client
+}
+
+type webhookDispatcher struct {
+	dbName   string
+	listener *pq.Listener
+	client   *http.Client
+	done     chan struct{}
+	// leader, when set, restricts delivery to the elected instance so a
+	// multi-instance cluster does not POST every event once per instance.
+	leader func() bool
+}
+
+func (s *Server) registerWebhookDispatchHooks(manager *lifecycle.Manager) {
+	for _, dbName := range s.Cfg().WebhookDatabases {
+		dbName := dbName
+		dispatcher := &webhookDispatcher{
+			dbName: dbName,
+			client: webhookClient(s.Cfg().WebhookAllowPrivate),
+			done:   make(chan struct{}),
+		}
+		var elector *leader.Elector
+		manager.Register(lifecycle.Hook{
+			Name: "webhook-dispatcher:" + dbName,
+			Start: func() error {
+				db, err := database.GetConnection(s.Cfg(), dbName)
+				if err != nil {
+					return fmt.Errorf("webhook dispatcher for %s: %w", dbName, err)
+				}
+				if err := ensureWebhookTable(db, dbName); err != nil {
+					return fmt.Errorf("webhook dispatcher for %s: %w", dbName, err)
+				}
+				if s.Cfg().LeaderElection {
+					elector = leader.New(db, "webhooks:"+dbName)
+					go elector.Run()
+					dispatcher.leader = elector.IsLeader
+				}
+				host, port := database.PrimaryAddr(s.Cfg())
+				connStr := database.ConnString(s.Cfg(), dbName, host, port)
+				dispatcher.listener = pq.NewListener(connStr, time.Second, time.Minute, nil)
+				go dispatcher.run(db)
+				return nil
+			},
+			Stop: func(ctx context.Context) error {
+				close(dispatcher.done)
+				if elector != nil {
+					elector.Stop()
+				}
+				return dispatcher.listener.Close()
+			},
+		})
+	}
+}
+
+// run keeps the LISTEN set in sync with the subscriptions table (refreshed
+// every minute) and fans incoming notifications out to deliver.
+func (d *webhookDispatcher) run(db *sql.DB) {
+	listening := make(map[string]bool)
+	refresh := func() {
+		rows, err := db.Query(`SELECT DISTINCT channel FROM pgarachne.webhook_subscriptions`)
+		if err != nil {
+			slog.Error("Webhook dispatcher failed to read channels", "database", d.dbName, "error", err)
+			return
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var channel string
+			if err := rows.Scan(&channel); err != nil {
+				continue
+			}
+			if !listening[channel] {
+				if err := d.listener.Listen(channel); err != nil {
+					slog.Error("Webhook dispatcher LISTEN failed", "database", d.dbName, "channel", channel, "error", err)
+					continue
+				}
+				listening[channel] = true
+				slog.Info("Webhook dispatcher listening", "database", d.dbName, "channel", channel)
+			}
+		}
+	}
+	refresh()
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-d.done:
+			return
+		case <-ticker.C:
+			refresh()
+		case notification := <-d.listener.Notify:
+			if notification == nil {
+				// Connection loss; pq reconnects, but LISTENs must be redone.
+				listening = make(map[string]bool)
+				refresh()
+				continue
+			}
+			if d.leader != nil && !d.leader() {
+				continue
+			}
+			go d.dispatch(db, notification.Channel, notification.Extra)
+		}
+	}
+}
+
+// dispatch delivers one notification payload to every subscription on the
+// channel.
+func (d *webhookDispatcher) dispatch(db *sql.DB, channel, payload string) {
+	rows, err := db.Query(`SELECT id, url, secret FROM pgarachne.webhook_subscriptions WHERE channel = $1`, channel)
+	if err != nil {
+		slog.Error("Webhook dispatcher failed to read subscriptions", "database", d.dbName, "channel", channel, "error", err)
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		var url, secret string
+		if err := rows.Scan(&id, &url, &secret); err != nil {
+			continue
+		}
+		go d.deliver(id, channel, url, secret, payload)
+	}
+}
+
+// deliver POSTs the payload with an HMAC-SHA256 signature, retrying on the
+// schedule; permanent failures are dead-letter logged with the full payload
+// so they can be replayed by hand.
+func (d *webhookDispatcher) deliver(id int64, channel, url, secret, payload string) {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	var lastErr error
+	for attempt, delay := range webhookRetrySchedule {
+		if delay > 0 {
+			select {
+			case <-d.done:
+				return
+			case <-time.After(delay):
+			}
+		}
+		req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader([]byte(payload)))
+		if err != nil {
+			lastErr = err
+			break
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-PgArachne-Channel", channel)
+		req.Header.Set("X-PgArachne-Signature", signature)
+
+		resp, err := d.client.Do(req)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode < http.StatusMultipleChoices {
+				return
+			}
+			err = fmt.Errorf("endpoint returned %s", resp.Status)
+		}
+		lastErr = err
+		slog.Warn("Webhook delivery attempt failed",
+			"database", d.dbName, "subscription", id, "channel", channel, "attempt", attempt+1, "error", err)
+	}
+
+	slog.Error("Webhook delivery dead-lettered",
+		"database", d.dbName, "subscription", id, "channel", channel, "url", url, "payload", payload, "error", lastErr)
+}
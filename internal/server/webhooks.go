@@ -0,0 +1,176 @@
+package server
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/pgarachne/internal/database"
+)
+
+// Webhook subscriptions let authenticated clients self-serve event
+// subscriptions: POST/GET/DELETE on /api/:database/_webhooks. Rows live in
+// pgarachne.webhook_subscriptions (see sql/schema.sql) and are always scoped
+// to the caller's role, so one role cannot see or delete another's hooks.
+
+type webhookSubscription struct {
+	ID        int64     `json:"id"`
+	Channel   string    `json:"channel"`
+	URL       string    `json:"url"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type webhookCreateRequest struct {
+	Channel string `json:"channel" binding:"required"`
+	URL     string `json:"url" binding:"required"`
+	Secret  string `json:"secret"`
+}
+
+// ensuredWebhookTables tracks databases where the subscriptions table has
+// been verified, so upgrades of existing deployments need no manual DDL.
+var ensuredWebhookTables sync.Map
+
+func ensureWebhookTable(db *sql.DB, dbName string) error {
+	if _, ok := ensuredWebhookTables.Load(dbName); ok {
+		return nil
+	}
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS pgarachne.webhook_subscriptions (
+			id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+			role TEXT NOT NULL,
+			channel TEXT NOT NULL,
+			url TEXT NOT NULL,
+			secret TEXT NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			UNIQUE (role, channel, url)
+		)`)
+	if err != nil {
+		return err
+	}
+	ensuredWebhookTables.Store(dbName, true)
+	return nil
+}
+
+func (s *Server) webhookDB(c *gin.Context) *sql.DB {
+	databaseName := c.Param("database")
+	db, err := database.GetConnection(s.Cfg(), databaseName)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, JSONRPCResponse{Error: &JSONRPCError{Message: "Database connection failed"}})
+		return nil
+	}
+	if err := ensureWebhookTable(db, databaseName); err != nil {
+		slog.Error("Failed to ensure webhook subscriptions table", "database", databaseName, "error", err)
+		c.JSON(http.StatusInternalServerError, JSONRPCResponse{Error: &JSONRPCError{Message: "Webhook storage unavailable"}})
+		return nil
+	}
+	return db
+}
+
+func (s *Server) handleWebhookCreate(c *gin.Context) {
+	db := s.webhookDB(c)
+	if db == nil {
+		return
+	}
+
+	var req webhookCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, JSONRPCResponse{Error: &JSONRPCError{Message: "Invalid request body: channel and url are required"}})
+		return
+	}
+	if err := validateWebhookURL(req.URL); err != nil {
+		c.JSON(http.StatusBadRequest, JSONRPCResponse{Error: &JSONRPCError{Message: fmt.Sprintf("Invalid webhook URL: %v", err)}})
+		return
+	}
+
+	// A per-subscription secret signs outgoing deliveries; generate one when
+	// the client doesn't bring its own.
+	secret := req.Secret
+	generated := false
+	if secret == "" {
+		raw := make([]byte, 32)
+		if _, err := rand.Read(raw); err != nil {
+			c.JSON(http.StatusInternalServerError, JSONRPCResponse{Error: &JSONRPCError{Message: "Failed to generate webhook secret"}})
+			return
+		}
+		secret = hex.EncodeToString(raw)
+		generated = true
+	}
+
+	var id int64
+	err := db.QueryRowContext(c.Request.Context(), `
+		INSERT INTO pgarachne.webhook_subscriptions (role, channel, url, secret)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (role, channel, url) DO UPDATE SET secret = EXCLUDED.secret
+		RETURNING id`,
+		c.GetString("db_role"), req.Channel, req.URL, secret).Scan(&id)
+	if err != nil {
+		slog.Error("Failed to create webhook subscription", "error", err)
+		c.JSON(http.StatusInternalServerError, JSONRPCResponse{Error: &JSONRPCError{Message: "Failed to create webhook subscription"}})
+		return
+	}
+
+	resp := gin.H{"id": id, "channel": req.Channel, "url": req.URL}
+	if generated {
+		// The generated secret is shown exactly once, like API tokens.
+		resp["secret"] = secret
+	}
+	c.JSON(http.StatusCreated, resp)
+}
+
+func (s *Server) handleWebhookList(c *gin.Context) {
+	db := s.webhookDB(c)
+	if db == nil {
+		return
+	}
+
+	rows, err := db.QueryContext(c.Request.Context(), `
+		SELECT id, channel, url, created_at
+		FROM pgarachne.webhook_subscriptions
+		WHERE role = $1
+		ORDER BY id`,
+		c.GetString("db_role"))
+	if err != nil {
+		slog.Error("Failed to list webhook subscriptions", "error", err)
+		c.JSON(http.StatusInternalServerError, JSONRPCResponse{Error: &JSONRPCError{Message: "Failed to list webhook subscriptions"}})
+		return
+	}
+	defer rows.Close()
+
+	subscriptions := []webhookSubscription{}
+	for rows.Next() {
+		var sub webhookSubscription
+		if err := rows.Scan(&sub.ID, &sub.Channel, &sub.URL, &sub.CreatedAt); err != nil {
+			c.JSON(http.StatusInternalServerError, JSONRPCResponse{Error: &JSONRPCError{Message: "Failed to read webhook subscriptions"}})
+			return
+		}
+		subscriptions = append(subscriptions, sub)
+	}
+	c.JSON(http.StatusOK, gin.H{"webhooks": subscriptions})
+}
+
+func (s *Server) handleWebhookDelete(c *gin.Context) {
+	db := s.webhookDB(c)
+	if db == nil {
+		return
+	}
+
+	result, err := db.ExecContext(c.Request.Context(), `
+		DELETE FROM pgarachne.webhook_subscriptions WHERE id = $1 AND role = $2`,
+		c.Param("id"), c.GetString("db_role"))
+	if err != nil {
+		slog.Error("Failed to delete webhook subscription", "error", err)
+		c.JSON(http.StatusInternalServerError, JSONRPCResponse{Error: &JSONRPCError{Message: "Failed to delete webhook subscription"}})
+		return
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		c.JSON(http.StatusNotFound, JSONRPCResponse{Error: &JSONRPCError{Message: "Webhook subscription not found"}})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
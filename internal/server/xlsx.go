@@ -0,0 +1,178 @@
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Minimal streaming XLSX writer for format=xlsx downloads. An .xlsx file is
+// a zip of OOXML parts; the workbook here is the smallest set Excel and
+// LibreOffice accept (one sheet, one style sheet) and the sheet rows are
+// written straight into the zip stream, so large results never have to be
+// buffered. Numbers and booleans keep their native cell type and ISO-8601
+// strings become real date cells, so spreadsheets sort and compute on them.
+
+// Cell style indexes into the cellXfs of xlsxStyles.
+const (
+	xlsxStyleDefault  = 0
+	xlsxStyleDateTime = 1
+	xlsxStyleDate     = 2
+)
+
+const xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>
+</Types>`
+
+const xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`
+
+const xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="Data" sheetId="1" r:id="rId1"/></sheets>
+</workbook>`
+
+const xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>
+</Relationships>`
+
+// numFmtId 22 is the builtin datetime format, 14 the builtin date format.
+const xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<fonts count="1"><font/></fonts>
+<fills count="1"><fill><patternFill patternType="none"/></fill></fills>
+<borders count="1"><border/></borders>
+<cellStyleXfs count="1"><xf/></cellStyleXfs>
+<cellXfs count="3"><xf numFmtId="0" xfId="0"/><xf numFmtId="22" applyNumberFormat="1" xfId="0"/><xf numFmtId="14" applyNumberFormat="1" xfId="0"/></cellXfs>
+</styleSheet>`
+
+// xlsxColumnRef converts a zero-based column index to the A, B, ..., AA
+// letter scheme.
+func xlsxColumnRef(index int) string {
+	ref := ""
+	for index >= 0 {
+		ref = string(rune('A'+index%26)) + ref
+		index = index/26 - 1
+	}
+	return ref
+}
+
+// xlsxSerial converts a timestamp to Excel's serial date number (days since
+// 1899-12-30).
+func xlsxSerial(t time.Time) float64 {
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	return t.UTC().Sub(epoch).Hours() / 24
+}
+
+// xlsxTimeValue recognizes the timestamp shapes Postgres emits in JSON and
+// reports whether the cell should carry the date or datetime style.
+func xlsxTimeValue(s string) (serial float64, style int, ok bool) {
+	for _, layout := range []string{time.RFC3339Nano, time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return xlsxSerial(t), xlsxStyleDateTime, true
+		}
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return xlsxSerial(t), xlsxStyleDate, true
+	}
+	return 0, 0, false
+}
+
+func xlsxEscape(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
+
+// xlsxCell writes one typed cell.
+func xlsxCell(w io.Writer, ref string, value interface{}) {
+	switch v := value.(type) {
+	case nil:
+		return
+	case float64:
+		fmt.Fprintf(w, `<c r="%s" t="n"><v>%s</v></c>`, ref, strconv.FormatFloat(v, 'f', -1, 64))
+	case bool:
+		cell := 0
+		if v {
+			cell = 1
+		}
+		fmt.Fprintf(w, `<c r="%s" t="b"><v>%d</v></c>`, ref, cell)
+	case string:
+		if serial, style, ok := xlsxTimeValue(v); ok {
+			fmt.Fprintf(w, `<c r="%s" s="%d" t="n"><v>%s</v></c>`, ref, style, strconv.FormatFloat(serial, 'f', -1, 64))
+			return
+		}
+		fmt.Fprintf(w, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, xlsxEscape(v))
+	default:
+		// Nested objects and arrays are kept as their JSON text.
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return
+		}
+		fmt.Fprintf(w, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, xlsxEscape(string(encoded)))
+	}
+}
+
+// writeXLSX streams rows as a one-sheet workbook download.
+func writeXLSX(c *gin.Context, functionName string, rows []map[string]interface{}, columns []string) {
+	c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.xlsx"`, functionName))
+	c.Status(200)
+
+	zw := zip.NewWriter(c.Writer)
+	defer zw.Close()
+
+	static := []struct{ name, body string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", xlsxWorkbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+		{"xl/styles.xml", xlsxStyles},
+	}
+	for _, part := range static {
+		w, err := zw.Create(part.name)
+		if err != nil {
+			return
+		}
+		io.WriteString(w, part.body)
+	}
+
+	sheet, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return
+	}
+	io.WriteString(sheet, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`+"\n")
+	io.WriteString(sheet, `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+
+	io.WriteString(sheet, `<row r="1">`)
+	for i, column := range columns {
+		xlsxCell(sheet, xlsxColumnRef(i)+"1", column)
+	}
+	io.WriteString(sheet, `</row>`)
+
+	for rowIdx, row := range rows {
+		rowRef := strconv.Itoa(rowIdx + 2)
+		fmt.Fprintf(sheet, `<row r="%s">`, rowRef)
+		for i, column := range columns {
+			xlsxCell(sheet, xlsxColumnRef(i)+rowRef, row[column])
+		}
+		io.WriteString(sheet, `</row>`)
+	}
+	io.WriteString(sheet, `</sheetData></worksheet>`)
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestXLSXColumnRef(t *testing.T) {
+	tests := []struct {
+		index int
+		want  string
+	}{
+		{0, "A"},
+		{25, "Z"},
+		{26, "AA"},
+		{51, "AZ"},
+		{52, "BA"},
+		{701, "ZZ"},
+		{702, "AAA"},
+	}
+	for _, tt := range tests {
+		if got := xlsxColumnRef(tt.index); got != tt.want {
+			t.Errorf("xlsxColumnRef(%d) = %s; want %s", tt.index, got, tt.want)
+		}
+	}
+}
+
+func TestXLSXTimeValue(t *testing.T) {
+	tests := []struct {
+		input  string
+		serial float64
+		style  int
+		ok     bool
+	}{
+		// Excel serials: days since 1899-12-30.
+		{"1900-01-01", 2, xlsxStyleDate, true},
+		{"2024-03-05", 45356, xlsxStyleDate, true},
+		{"2024-03-05T12:00:00Z", 45356.5, xlsxStyleDateTime, true},
+		{"2024-03-05 06:00:00", 45356.25, xlsxStyleDateTime, true},
+		{"hello", 0, 0, false},
+		{"2024-13-40", 0, 0, false},
+	}
+	for _, tt := range tests {
+		serial, style, ok := xlsxTimeValue(tt.input)
+		if ok != tt.ok || serial != tt.serial || style != tt.style {
+			t.Errorf("xlsxTimeValue(%q) = (%v, %d, %v); want (%v, %d, %v)",
+				tt.input, serial, style, ok, tt.serial, tt.style, tt.ok)
+		}
+	}
+}
+
+func TestXLSXCell(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string
+	}{
+		{"nil is omitted", nil, ""},
+		{"number", 1.5, `<c r="A1" t="n"><v>1.5</v></c>`},
+		{"bool", true, `<c r="A1" t="b"><v>1</v></c>`},
+		{"string", "hi", `<c r="A1" t="inlineStr"><is><t xml:space="preserve">hi</t></is></c>`},
+		{"string needing XML escapes", "a<b&c", `<c r="A1" t="inlineStr"><is><t xml:space="preserve">a&lt;b&amp;c</t></is></c>`},
+		{"date string becomes a styled serial", "1900-01-01", `<c r="A1" s="2" t="n"><v>2</v></c>`},
+		{"nested value keeps its JSON text", map[string]interface{}{"k": true}, `<c r="A1" t="inlineStr"><is><t xml:space="preserve">{&#34;k&#34;:true}</t></is></c>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			xlsxCell(&buf, "A1", tt.value)
+			if buf.String() != tt.want {
+				t.Errorf("xlsxCell = %s; want %s", buf.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package state
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// CachedStore puts a small in-process read cache in front of the Postgres
+// store so hot lookups (revocation checks, lockout state) do not hit the
+// cluster on every request. Writes NOTIFY on the pgarachne_state channel
+// and every instance LISTENs, evicting the touched key — so a token revoked
+// through one instance stops working on all of them within the notification
+// round-trip rather than a cache TTL. The counters (Incr) and SetNX stay
+// uncached: they must be authoritative.
+type CachedStore struct {
+	*PostgresStore
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	value   []byte
+	ok      bool
+	expires time.Time
+}
+
+// cacheMaxAge bounds staleness should a notification be missed entirely.
+const cacheMaxAge = 30 * time.Second
+
+const notifyChannel = "pgarachne_state"
+
+// NewCachedStore wraps the Postgres store and starts the LISTEN loop on a
+// dedicated connection.
+func NewCachedStore(store *PostgresStore, conninfo string) *CachedStore {
+	c := &CachedStore{
+		PostgresStore: store,
+		entries:       make(map[string]cacheEntry),
+	}
+	listener := pq.NewListener(conninfo, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		// Any reconnect may have missed notifications; drop everything.
+		if event == pq.ListenerEventReconnected || event == pq.ListenerEventConnected {
+			c.flush()
+		}
+		if err != nil {
+			slog.Warn("State cache listener event", "event", int(event), "error", err)
+		}
+	})
+	if err := listener.Listen(notifyChannel); err != nil {
+		slog.Warn("State cache LISTEN failed; falling back to TTL-only invalidation", "error", err)
+		return c
+	}
+	go func() {
+		for notification := range listener.Notify {
+			if notification == nil {
+				// Connection loss marker; the reconnect callback flushes.
+				continue
+			}
+			c.evict(notification.Extra)
+		}
+	}()
+	return c
+}
+
+func (c *CachedStore) flush() {
+	c.mu.Lock()
+	c.entries = make(map[string]cacheEntry)
+	c.mu.Unlock()
+}
+
+func (c *CachedStore) evict(cacheKey string) {
+	c.mu.Lock()
+	delete(c.entries, cacheKey)
+	c.mu.Unlock()
+}
+
+func cacheKey(bucket, key string) string {
+	return bucket + "/" + key
+}
+
+func (c *CachedStore) Get(ctx context.Context, bucket, key string) ([]byte, bool, error) {
+	ck := cacheKey(bucket, key)
+	c.mu.Lock()
+	entry, cached := c.entries[ck]
+	c.mu.Unlock()
+	if cached && time.Now().Before(entry.expires) {
+		return entry.value, entry.ok, nil
+	}
+
+	value, ok, err := c.PostgresStore.Get(ctx, bucket, key)
+	if err != nil {
+		return nil, false, err
+	}
+	c.mu.Lock()
+	c.entries[ck] = cacheEntry{value: value, ok: ok, expires: time.Now().Add(cacheMaxAge)}
+	c.mu.Unlock()
+	return value, ok, nil
+}
+
+func (c *CachedStore) Set(ctx context.Context, bucket, key string, value []byte, ttl time.Duration) error {
+	if err := c.PostgresStore.Set(ctx, bucket, key, value, ttl); err != nil {
+		return err
+	}
+	c.evict(cacheKey(bucket, key))
+	c.notify(ctx, bucket, key)
+	return nil
+}
+
+func (c *CachedStore) Delete(ctx context.Context, bucket, key string) error {
+	if err := c.PostgresStore.Delete(ctx, bucket, key); err != nil {
+		return err
+	}
+	c.evict(cacheKey(bucket, key))
+	c.notify(ctx, bucket, key)
+	return nil
+}
+
+// notify tells the other instances to drop their cached copy. Best effort:
+// a failed NOTIFY only delays them until cacheMaxAge.
+func (c *CachedStore) notify(ctx context.Context, bucket, key string) {
+	if _, err := c.db.ExecContext(ctx, `SELECT pg_notify($1, $2)`, notifyChannel, cacheKey(bucket, key)); err != nil {
+		slog.Warn("State cache NOTIFY failed", "error", err)
+	}
+}
@@ -0,0 +1,115 @@
+package state
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps state in a process-local map. Fast and dependency-free,
+// but limits and idempotency are only enforced per instance.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]*memoryEntry
+}
+
+type memoryEntry struct {
+	value     []byte
+	counter   int64
+	expiresAt time.Time // zero = no expiry
+}
+
+func NewMemoryStore() *MemoryStore {
+	s := &MemoryStore{entries: make(map[string]*memoryEntry)}
+	// Janitor removes expired entries so long-running servers don't leak.
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for range ticker.C {
+			s.sweep()
+		}
+	}()
+	return s
+}
+
+func (s *MemoryStore) sweep() {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, e := range s.entries {
+		if !e.expiresAt.IsZero() && now.After(e.expiresAt) {
+			delete(s.entries, k)
+		}
+	}
+}
+
+func memKey(bucket, key string) string { return bucket + "\x00" + key }
+
+// live returns the entry if present and unexpired, dropping expired ones.
+// Callers must hold s.mu.
+func (s *MemoryStore) live(k string) *memoryEntry {
+	e, ok := s.entries[k]
+	if !ok {
+		return nil
+	}
+	if !e.expiresAt.IsZero() && time.Now().After(e.expiresAt) {
+		delete(s.entries, k)
+		return nil
+	}
+	return e
+}
+
+func expiry(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(ttl)
+}
+
+func (s *MemoryStore) Get(_ context.Context, bucket, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.live(memKey(bucket, key))
+	if e == nil {
+		return nil, false, nil
+	}
+	return e.value, true, nil
+}
+
+func (s *MemoryStore) Set(_ context.Context, bucket, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[memKey(bucket, key)] = &memoryEntry{value: value, expiresAt: expiry(ttl)}
+	return nil
+}
+
+func (s *MemoryStore) SetNX(_ context.Context, bucket, key string, value []byte, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := memKey(bucket, key)
+	if s.live(k) != nil {
+		return false, nil
+	}
+	s.entries[k] = &memoryEntry{value: value, expiresAt: expiry(ttl)}
+	return true, nil
+}
+
+func (s *MemoryStore) Incr(_ context.Context, bucket, key string, ttl time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := memKey(bucket, key)
+	e := s.live(k)
+	if e == nil {
+		e = &memoryEntry{expiresAt: expiry(ttl)}
+		s.entries[k] = e
+	}
+	e.counter++
+	return e.counter, nil
+}
+
+func (s *MemoryStore) Delete(_ context.Context, bucket, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, memKey(bucket, key))
+	return nil
+}
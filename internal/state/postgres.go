@@ -0,0 +1,123 @@
+package state
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/yourusername/pgarachne/internal/config"
+	"github.com/yourusername/pgarachne/internal/database"
+)
+
+// PostgresStore keeps state in the pgarachne.kv_state table (see
+// sql/schema.sql), so every PgArachne instance pointing at the same cluster
+// shares limits and idempotency decisions. STATE_STORAGE_DATABASE selects
+// which database holds the table.
+type PostgresStore struct {
+	db *sql.DB
+}
+
+func NewPostgresStore(cfg *config.Config) (*PostgresStore, error) {
+	if cfg.StateStorageDatabase == "" {
+		return nil, fmt.Errorf("STATE_STORAGE=postgres requires STATE_STORAGE_DATABASE")
+	}
+	db, err := database.GetConnection(cfg, cfg.StateStorageDatabase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect state storage: %w", err)
+	}
+	// The table normally comes from sql/schema.sql; creating it lazily keeps
+	// upgrades of existing deployments painless. UNLOGGED: the state is
+	// ephemeral by nature (TTLs, counters), so skipping WAL is a free win.
+	_, err = db.Exec(`
+		CREATE UNLOGGED TABLE IF NOT EXISTS pgarachne.kv_state (
+			bucket TEXT NOT NULL,
+			key TEXT NOT NULL,
+			value BYTEA,
+			counter BIGINT NOT NULL DEFAULT 0,
+			expires_at TIMESTAMPTZ,
+			PRIMARY KEY (bucket, key)
+		)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to ensure pgarachne.kv_state: %w", err)
+	}
+	return &PostgresStore{db: db}, nil
+}
+
+// expiresArg converts a TTL to an expires_at value (NULL = no expiry).
+func expiresArg(ttl time.Duration) interface{} {
+	if ttl <= 0 {
+		return nil
+	}
+	return time.Now().Add(ttl)
+}
+
+func (s *PostgresStore) Get(ctx context.Context, bucket, key string) ([]byte, bool, error) {
+	var value []byte
+	err := s.db.QueryRowContext(ctx, `
+		SELECT value FROM pgarachne.kv_state
+		WHERE bucket = $1 AND key = $2 AND (expires_at IS NULL OR expires_at > NOW())`,
+		bucket, key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+func (s *PostgresStore) Set(ctx context.Context, bucket, key string, value []byte, ttl time.Duration) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO pgarachne.kv_state (bucket, key, value, expires_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (bucket, key)
+		DO UPDATE SET value = EXCLUDED.value, counter = 0, expires_at = EXCLUDED.expires_at`,
+		bucket, key, value, expiresArg(ttl))
+	return err
+}
+
+func (s *PostgresStore) SetNX(ctx context.Context, bucket, key string, value []byte, ttl time.Duration) (bool, error) {
+	// An expired row counts as absent, so the upsert may also take over a
+	// row whose expires_at has passed.
+	result, err := s.db.ExecContext(ctx, `
+		INSERT INTO pgarachne.kv_state (bucket, key, value, expires_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (bucket, key)
+		DO UPDATE SET value = EXCLUDED.value, counter = 0, expires_at = EXCLUDED.expires_at
+		WHERE pgarachne.kv_state.expires_at IS NOT NULL AND pgarachne.kv_state.expires_at <= NOW()`,
+		bucket, key, value, expiresArg(ttl))
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+func (s *PostgresStore) Incr(ctx context.Context, bucket, key string, ttl time.Duration) (int64, error) {
+	var counter int64
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO pgarachne.kv_state (bucket, key, counter, expires_at)
+		VALUES ($1, $2, 1, $3)
+		ON CONFLICT (bucket, key)
+		DO UPDATE SET
+			counter = CASE
+				WHEN pgarachne.kv_state.expires_at IS NOT NULL AND pgarachne.kv_state.expires_at <= NOW() THEN 1
+				ELSE pgarachne.kv_state.counter + 1
+			END,
+			expires_at = CASE
+				WHEN pgarachne.kv_state.expires_at IS NOT NULL AND pgarachne.kv_state.expires_at <= NOW() THEN EXCLUDED.expires_at
+				ELSE pgarachne.kv_state.expires_at
+			END
+		RETURNING counter`,
+		bucket, key, expiresArg(ttl)).Scan(&counter)
+	return counter, err
+}
+
+func (s *PostgresStore) Delete(ctx context.Context, bucket, key string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM pgarachne.kv_state WHERE bucket = $1 AND key = $2`, bucket, key)
+	return err
+}
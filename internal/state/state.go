@@ -0,0 +1,56 @@
+// Package state provides a small key/value store with TTLs, shared by
+// features that need cross-request bookkeeping: rate limiting, idempotency
+// keys, token revocation lists. The backend is selected with STATE_STORAGE:
+// "memory" (default, single-node) or "postgres" (correct across multiple
+// PgArachne instances pointing at the same cluster).
+package state
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/yourusername/pgarachne/internal/config"
+	"github.com/yourusername/pgarachne/internal/database"
+)
+
+// Store is the backend interface. Keys are namespaced by bucket so the
+// individual features cannot collide ("ratelimit", "idempotency", ...).
+type Store interface {
+	// Get returns the stored value and whether the key exists (expired keys
+	// count as absent).
+	Get(ctx context.Context, bucket, key string) ([]byte, bool, error)
+	// Set stores a value with a TTL; ttl <= 0 means no expiry.
+	Set(ctx context.Context, bucket, key string, value []byte, ttl time.Duration) error
+	// SetNX stores the value only if the key is absent and reports whether
+	// it was stored. This is the primitive behind idempotency keys.
+	SetNX(ctx context.Context, bucket, key string, value []byte, ttl time.Duration) (bool, error)
+	// Incr atomically increments a counter, creating it with the given TTL,
+	// and returns the new value. This is the primitive behind rate limiting.
+	Incr(ctx context.Context, bucket, key string, ttl time.Duration) (int64, error)
+	// Delete removes a key; deleting an absent key is not an error.
+	Delete(ctx context.Context, bucket, key string) error
+}
+
+// New builds the configured Store.
+func New(cfg *config.Config) (Store, error) {
+	switch cfg.StateStorage {
+	case "", "memory":
+		return NewMemoryStore(), nil
+	case "postgres":
+		store, err := NewPostgresStore(cfg)
+		if err != nil {
+			return nil, err
+		}
+		// Developer mode skips the read cache so revocations and state
+		// edits apply on the next request instead of after a notification.
+		if cfg.DevMode {
+			return store, nil
+		}
+		// The read cache LISTENs on its own connection to the primary.
+		host, port := database.PrimaryAddr(cfg)
+		return NewCachedStore(store, database.ConnString(cfg, cfg.StateStorageDatabase, host, port)), nil
+	default:
+		return nil, fmt.Errorf("unsupported STATE_STORAGE backend: '%s' (supported: memory, postgres)", cfg.StateStorage)
+	}
+}
@@ -0,0 +1,246 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yourusername/pgarachne/internal/config"
+)
+
+// S3-compatible backend with a minimal SigV4 signer (same approach as the
+// secrets providers — no SDK). Uploads are signed with UNSIGNED-PAYLOAD so
+// the body can stream straight through; MinIO and other self-hosted
+// services are reached through S3_ENDPOINT with path-style addressing.
+type s3Backend struct {
+	endpoint  string // scheme://host[:port], no trailing slash
+	bucket    string
+	region    string
+	accessKey string
+	secretKey string
+	pathStyle bool
+}
+
+func newS3Backend(cfg *config.Config) (*s3Backend, error) {
+	b := &s3Backend{
+		endpoint:  strings.TrimSuffix(cfg.S3Endpoint, "/"),
+		bucket:    cfg.S3Bucket,
+		region:    cfg.S3Region,
+		accessKey: cfg.S3AccessKey,
+		secretKey: cfg.S3SecretKey,
+		pathStyle: cfg.S3ForcePathStyle,
+	}
+	if b.bucket == "" {
+		return nil, fmt.Errorf("STORAGE_BACKEND=s3 requires S3_BUCKET")
+	}
+	if b.accessKey == "" || b.secretKey == "" {
+		return nil, fmt.Errorf("STORAGE_BACKEND=s3 requires S3_ACCESS_KEY and S3_SECRET_KEY")
+	}
+	if b.region == "" {
+		b.region = "us-east-1"
+	}
+	if b.endpoint == "" {
+		b.endpoint = "https://s3." + b.region + ".amazonaws.com"
+		// Virtual-hosted addressing is the AWS default; custom endpoints
+		// (MinIO) usually need path style.
+	} else {
+		b.pathStyle = true
+	}
+	return b, nil
+}
+
+// objectURL returns the full URL and the canonical path for a key.
+func (b *s3Backend) objectURL(key string) (fullURL, canonicalPath string) {
+	escaped := escapeS3Path(key)
+	if b.pathStyle {
+		return b.endpoint + "/" + b.bucket + "/" + escaped, "/" + b.bucket + "/" + escaped
+	}
+	u, _ := url.Parse(b.endpoint)
+	return u.Scheme + "://" + b.bucket + "." + u.Host + "/" + escaped, "/" + escaped
+}
+
+// escapeS3Path applies the S3 flavour of URI encoding: every byte outside
+// the RFC 3986 unreserved set is percent-encoded, with slashes kept as
+// segment separators. url.PathEscape is deliberately not used here — it
+// leaves sub-delims like '+' and '=' bare, which SigV4 canonical URIs
+// reject with SignatureDoesNotMatch.
+func escapeS3Path(key string) string {
+	var b strings.Builder
+	for i := 0; i < len(key); i++ {
+		ch := key[i]
+		switch {
+		case ch >= 'A' && ch <= 'Z', ch >= 'a' && ch <= 'z', ch >= '0' && ch <= '9',
+			ch == '-', ch == '.', ch == '_', ch == '~', ch == '/':
+			b.WriteByte(ch)
+		default:
+			b.WriteString(fmt.Sprintf("%%%02X", ch))
+		}
+	}
+	return b.String()
+}
+
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// sign adds the SigV4 Authorization header. Only host, x-amz-date and
+// x-amz-content-sha256 are signed, which every S3 implementation accepts.
+func (b *s3Backend) sign(req *http.Request, canonicalPath string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	dateStamp := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", unsignedPayload)
+
+	canonicalHeaders := "host:" + req.URL.Host + "\n" +
+		"x-amz-content-sha256:" + unsignedPayload + "\n" +
+		"x-amz-date:" + amzDate + "\n"
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalPath,
+		req.URL.RawQuery,
+		canonicalHeaders,
+		signedHeaders,
+		unsignedPayload,
+	}, "\n")
+
+	scope := strings.Join([]string{dateStamp, b.region, "s3", "aws4_request"}, "/")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(requestHash[:]),
+	}, "\n")
+
+	signature := hex.EncodeToString(hmacSHA256(b.signingKey(dateStamp), stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		b.accessKey, scope, signedHeaders, signature))
+}
+
+func (b *s3Backend) signingKey(dateStamp string) []byte {
+	key := hmacSHA256([]byte("AWS4"+b.secretKey), dateStamp)
+	key = hmacSHA256(key, b.region)
+	key = hmacSHA256(key, "s3")
+	return hmacSHA256(key, "aws4_request")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func (b *s3Backend) Put(ctx context.Context, key, contentType string, body io.Reader, size int64) error {
+	fullURL, canonicalPath := b.objectURL(key)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, fullURL, body)
+	if err != nil {
+		return err
+	}
+	if size >= 0 {
+		req.ContentLength = size
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	b.sign(req, canonicalPath, time.Now().UTC())
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("object storage PUT returned %s", resp.Status)
+	}
+	return nil
+}
+
+func (b *s3Backend) Get(ctx context.Context, key string) (*Object, error) {
+	fullURL, canonicalPath := b.objectURL(key)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fullURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	b.sign(req, canonicalPath, time.Now().UTC())
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("object storage GET returned %s", resp.Status)
+	}
+	return &Object{
+		Body:          resp.Body,
+		ContentType:   resp.Header.Get("Content-Type"),
+		ContentLength: resp.ContentLength,
+	}, nil
+}
+
+func (b *s3Backend) Delete(ctx context.Context, key string) error {
+	fullURL, canonicalPath := b.objectURL(key)
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, fullURL, nil)
+	if err != nil {
+		return err
+	}
+	b.sign(req, canonicalPath, time.Now().UTC())
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("object storage DELETE returned %s", resp.Status)
+	}
+	return nil
+}
+
+// PresignGet builds a query-signed download URL valid for ttl.
+func (b *s3Backend) PresignGet(key string, ttl time.Duration) string {
+	fullURL, canonicalPath := b.objectURL(key)
+	now := time.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	dateStamp := now.Format("20060102")
+	scope := strings.Join([]string{dateStamp, b.region, "s3", "aws4_request"}, "/")
+
+	query := url.Values{}
+	query.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	query.Set("X-Amz-Credential", b.accessKey+"/"+scope)
+	query.Set("X-Amz-Date", amzDate)
+	query.Set("X-Amz-Expires", strconv.Itoa(int(ttl.Seconds())))
+	query.Set("X-Amz-SignedHeaders", "host")
+
+	u, _ := url.Parse(fullURL)
+	canonicalRequest := strings.Join([]string{
+		http.MethodGet,
+		canonicalPath,
+		strings.ReplaceAll(query.Encode(), "+", "%20"),
+		"host:" + u.Host + "\n",
+		"host",
+		unsignedPayload,
+	}, "\n")
+
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(requestHash[:]),
+	}, "\n")
+	signature := hex.EncodeToString(hmacSHA256(b.signingKey(dateStamp), stringToSign))
+
+	query.Set("X-Amz-Signature", signature)
+	return fullURL + "?" + strings.ReplaceAll(query.Encode(), "+", "%20")
+}
@@ -0,0 +1,52 @@
+// Package storage provides the optional object storage backend that keeps
+// large binary payloads out of Postgres: uploads are streamed to an
+// S3-compatible service (AWS S3, MinIO, ...) and only the object key plus
+// metadata travels through the SQL layer; downloads are served as presigned
+// URLs or proxied through the gateway.
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/yourusername/pgarachne/internal/config"
+)
+
+// Object describes a stored object as returned by Get.
+type Object struct {
+	Body          io.ReadCloser
+	ContentType   string
+	ContentLength int64
+}
+
+// Backend is the object storage interface.
+type Backend interface {
+	// Put streams body to the given key. size < 0 means unknown, in which
+	// case the backend may buffer.
+	Put(ctx context.Context, key, contentType string, body io.Reader, size int64) error
+	// Get opens the object for reading (proxy mode).
+	Get(ctx context.Context, key string) (*Object, error)
+	// Delete removes the object.
+	Delete(ctx context.Context, key string) error
+	// PresignGet returns a time-limited download URL, or "" when the
+	// backend cannot presign.
+	PresignGet(key string, ttl time.Duration) string
+}
+
+var httpClient = &http.Client{Timeout: 5 * time.Minute}
+
+// New builds the configured backend; cfg.StorageBackend "" means object
+// storage is disabled and New returns nil.
+func New(cfg *config.Config) (Backend, error) {
+	switch cfg.StorageBackend {
+	case "":
+		return nil, nil
+	case "s3":
+		return newS3Backend(cfg)
+	default:
+		return nil, fmt.Errorf("unsupported STORAGE_BACKEND: '%s' (supported: s3)", cfg.StorageBackend)
+	}
+}
@@ -0,0 +1,299 @@
+// Package pgarachnetest is an integration test harness for SQL APIs served
+// through PgArachne. It boots the full server in-process against a real
+// Postgres — an ephemeral Docker container or whatever PGARACHNE_TEST_DSN
+// points at — so end-to-end tests of SQL functions can run in ordinary Go
+// CI pipelines:
+//
+//	func TestOrders(t *testing.T) {
+//		host, port := pgarachnetest.StartPostgres(t)
+//		h := pgarachnetest.Start(t, pgarachnetest.Options{
+//			Database: "postgres", DBHost: host, DBPort: port,
+//		})
+//		h.ExecSQL(t, `CREATE ROLE api_user LOGIN; CREATE FUNCTION ...`)
+//		token := h.Token(t, "api_user", nil)
+//		result := h.Call(t, token, "api.get_orders", map[string]any{"status": "open"})
+//		...
+//	}
+package pgarachnetest
+
+import (
+	"bytes"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/yourusername/pgarachne/internal/config"
+	"github.com/yourusername/pgarachne/internal/database"
+	"github.com/yourusername/pgarachne/internal/server"
+)
+
+// Options configures the harness; zero values get working defaults.
+type Options struct {
+	// Database is the Postgres catalog the API serves; default "postgres".
+	Database string
+	// DBHost/DBPort point at the cluster, typically from StartPostgres.
+	DBHost string
+	DBPort string
+	// Env sets or overrides any PgArachne configuration variable (applied
+	// with t.Setenv, so it is scoped to the test).
+	Env map[string]string
+}
+
+// Harness is one running PgArachne instance plus its database handle.
+type Harness struct {
+	// BaseURL is the loopback address of the running server.
+	BaseURL string
+	// Database is the catalog under /api/.
+	Database string
+	// JWTSecret signs the tokens Token issues.
+	JWTSecret string
+	// DB is a direct connection for installing schemas and fixtures.
+	DB *sql.DB
+
+	client *http.Client
+}
+
+// StartPostgres provides a Postgres to test against: PGARACHNE_TEST_DSN
+// ("host=... port=...") wins when set; otherwise an ephemeral
+// postgres:16-alpine container is started through the docker CLI and removed
+// when the test ends. Tests skip when neither is available.
+func StartPostgres(t *testing.T) (host, port string) {
+	t.Helper()
+	if dsn := os.Getenv("PGARACHNE_TEST_DSN"); dsn != "" {
+		host, port = "127.0.0.1", "5432"
+		for _, field := range strings.Fields(dsn) {
+			if v, ok := strings.CutPrefix(field, "host="); ok {
+				host = v
+			}
+			if v, ok := strings.CutPrefix(field, "port="); ok {
+				port = v
+			}
+		}
+		return host, port
+	}
+
+	if _, err := exec.LookPath("docker"); err != nil {
+		t.Skip("no PGARACHNE_TEST_DSN and no docker binary; skipping integration test")
+	}
+	out, err := exec.Command("docker", "run", "-d", "--rm",
+		"-e", "POSTGRES_PASSWORD=postgres",
+		"-e", "POSTGRES_HOST_AUTH_METHOD=trust",
+		"-p", "127.0.0.1:0:5432",
+		"postgres:16-alpine").Output()
+	if err != nil {
+		t.Skipf("failed to start postgres container: %v", err)
+	}
+	containerID := strings.TrimSpace(string(out))
+	t.Cleanup(func() {
+		exec.Command("docker", "rm", "-f", containerID).Run()
+	})
+
+	mapped, err := exec.Command("docker", "port", containerID, "5432/tcp").Output()
+	if err != nil {
+		t.Fatalf("failed to resolve container port: %v", err)
+	}
+	addr := strings.TrimSpace(strings.Split(string(mapped), "\n")[0])
+	host, port, err = net.SplitHostPort(addr)
+	if err != nil {
+		t.Fatalf("unexpected docker port output %q: %v", addr, err)
+	}
+
+	// Wait for the server inside the container to accept connections.
+	dsn := fmt.Sprintf("host=%s port=%s user=postgres password=postgres dbname=postgres sslmode=disable", host, port)
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("failed to open postgres: %v", err)
+	}
+	defer db.Close()
+	deadline := time.Now().Add(60 * time.Second)
+	for db.Ping() != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("postgres container did not become ready within 60s")
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+	return host, port
+}
+
+// Start boots PgArachne against the given cluster and serves it on an
+// ephemeral loopback port; the listener closes with the test.
+func Start(t *testing.T, opts Options) *Harness {
+	t.Helper()
+	if opts.Database == "" {
+		opts.Database = "postgres"
+	}
+	if opts.DBHost == "" {
+		opts.DBHost = "127.0.0.1"
+	}
+	if opts.DBPort == "" {
+		opts.DBPort = "5432"
+	}
+
+	secretBytes := make([]byte, 32)
+	rand.Read(secretBytes)
+	secret := hex.EncodeToString(secretBytes)
+
+	env := map[string]string{
+		"DB_HOST":    opts.DBHost,
+		"DB_PORT":    opts.DBPort,
+		"DB_USER":    "postgres",
+		"DB_DSN":     "password=postgres sslmode=disable",
+		"JWT_SECRET": secret,
+		"DATABASES":  opts.Database,
+		"LOG_LEVEL":  "ERROR",
+	}
+	for name, value := range opts.Env {
+		env[name] = value
+	}
+	for name, value := range env {
+		t.Setenv(name, value)
+	}
+
+	cfg, err := config.Load(os.DevNull)
+	if err != nil {
+		t.Fatalf("failed to load configuration: %v", err)
+	}
+	srv, err := server.New(cfg)
+	if err != nil {
+		t.Fatalf("failed to initialize server: %v", err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to open listener: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go http.Serve(listener, srv.Router())
+
+	db, err := database.GetConnection(cfg, opts.Database)
+	if err != nil {
+		t.Fatalf("failed to connect to %s: %v", opts.Database, err)
+	}
+
+	return &Harness{
+		BaseURL:   "http://" + listener.Addr().String(),
+		Database:  opts.Database,
+		JWTSecret: secret,
+		DB:        db,
+		client:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// ExecSQL runs a statement batch (schema, roles, fixtures) and fails the
+// test on error.
+func (h *Harness) ExecSQL(t *testing.T, statements string) {
+	t.Helper()
+	if _, err := h.DB.Exec(statements); err != nil {
+		t.Fatalf("SQL failed: %v", err)
+	}
+}
+
+// ApplySQLFile installs a schema file, e.g. the application's migrations or
+// PgArachne's own sql/schema.sql.
+func (h *Harness) ApplySQLFile(t *testing.T, path string) {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", path, err)
+	}
+	h.ExecSQL(t, string(content))
+}
+
+// Token issues a JWT for the role, valid for an hour, with any extra claims
+// merged in — the same shape the login endpoint produces.
+func (h *Harness) Token(t *testing.T, role string, extraClaims map[string]interface{}) string {
+	t.Helper()
+	claims := jwt.MapClaims{
+		"db_role": role,
+		"db_name": h.Database,
+		"exp":     time.Now().Add(time.Hour).Unix(),
+	}
+	for name, value := range extraClaims {
+		claims[name] = value
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(h.JWTSecret))
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return token
+}
+
+// CallError is a JSON-RPC error returned by CallRaw.
+type CallError struct {
+	Status  int
+	Code    int
+	Message string
+	Data    json.RawMessage
+}
+
+func (e *CallError) Error() string {
+	return fmt.Sprintf("HTTP %d, code %d: %s", e.Status, e.Code, e.Message)
+}
+
+// Call invokes a function and fails the test on any error; use CallRaw to
+// assert on errors.
+func (h *Harness) Call(t *testing.T, token, function string, params interface{}) json.RawMessage {
+	t.Helper()
+	result, err := h.CallRaw(token, function, params)
+	if err != nil {
+		t.Fatalf("call to %s failed: %v", function, err)
+	}
+	return result
+}
+
+// CallRaw invokes a function through the full HTTP stack and returns the
+// JSON-RPC result, or a *CallError carrying the response's error object.
+func (h *Harness) CallRaw(token, function string, params interface{}) (json.RawMessage, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"params":  params,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost,
+		h.BaseURL+"/api/"+h.Database+"/"+function, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var envelope struct {
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Code    int             `json:"code"`
+			Message string          `json:"message"`
+			Data    json.RawMessage `json:"data"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return nil, fmt.Errorf("invalid response (HTTP %d): %w", resp.StatusCode, err)
+	}
+	if envelope.Error != nil {
+		return nil, &CallError{
+			Status:  resp.StatusCode,
+			Code:    envelope.Error.Code,
+			Message: envelope.Error.Message,
+			Data:    envelope.Error.Data,
+		}
+	}
+	return envelope.Result, nil
+}
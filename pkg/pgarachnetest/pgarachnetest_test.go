@@ -0,0 +1,55 @@
+package pgarachnetest_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/yourusername/pgarachne/pkg/pgarachnetest"
+)
+
+// TestHarnessEndToEnd drives the harness exactly the way a consuming project
+// would: boot Postgres and PgArachne, install a schema, mint a token and
+// call a function through the full HTTP stack. It skips when neither
+// PGARACHNE_TEST_DSN nor docker is available.
+func TestHarnessEndToEnd(t *testing.T) {
+	host, port := pgarachnetest.StartPostgres(t)
+	h := pgarachnetest.Start(t, pgarachnetest.Options{
+		DBHost: host, DBPort: port,
+	})
+
+	h.ExecSQL(t, `
+		DROP ROLE IF EXISTS pgarachnetest_user;
+		CREATE ROLE pgarachnetest_user;
+		CREATE SCHEMA IF NOT EXISTS api;
+		CREATE OR REPLACE FUNCTION api.echo(params jsonb) RETURNS jsonb
+			LANGUAGE sql AS $$ SELECT params $$;
+		GRANT USAGE ON SCHEMA api TO pgarachnetest_user;
+		GRANT EXECUTE ON FUNCTION api.echo(jsonb) TO pgarachnetest_user;
+	`)
+
+	token := h.Token(t, "pgarachnetest_user", nil)
+
+	result := h.Call(t, token, "api.echo", map[string]interface{}{"status": "open"})
+	var echoed map[string]interface{}
+	if err := json.Unmarshal(result, &echoed); err != nil {
+		t.Fatalf("result is not a JSON object: %v", err)
+	}
+	if echoed["status"] != "open" {
+		t.Errorf("api.echo returned %s; want the params back", result)
+	}
+
+	// Unauthenticated calls must be rejected before reaching the function.
+	if _, err := h.CallRaw("", "api.echo", nil); err == nil {
+		t.Error("call without a token succeeded")
+	}
+
+	// Unknown functions surface as a CallError, not a test failure.
+	_, err := h.CallRaw(token, "api.does_not_exist", nil)
+	callErr, ok := err.(*pgarachnetest.CallError)
+	if !ok {
+		t.Fatalf("call to missing function returned %v; want *CallError", err)
+	}
+	if callErr.Status != 404 {
+		t.Errorf("call to missing function returned HTTP %d; want 404", callErr.Status)
+	}
+}
@@ -0,0 +1,12 @@
+// Package sql embeds the bundled pgarachne schema so the binary can install
+// it (`pgarachne init-db`) without the source tree present.
+package sql
+
+import _ "embed"
+
+// SchemaVersion identifies the bundled schema; init-db records it in
+// pgarachne.schema_info and skips reapplying when already current.
+const SchemaVersion = 1
+
+//go:embed schema.sql
+var Schema string
@@ -279,3 +279,201 @@ BEGIN
     );
 END;
 $$;
+
+
+-- =============================================================================
+-- Table: pgarachne.kv_state
+-- Description: Shared key/value state (rate limits, idempotency keys,
+--              revocation lists) when STATE_STORAGE=postgres. Unlogged:
+--              the rows are ephemeral, so WAL would be pure overhead.
+-- =============================================================================
+CREATE UNLOGGED TABLE IF NOT EXISTS pgarachne.kv_state (
+    bucket TEXT NOT NULL,
+    key TEXT NOT NULL,
+    value BYTEA,
+    counter BIGINT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMPTZ,
+    PRIMARY KEY (bucket, key)
+);
+
+COMMENT ON TABLE pgarachne.kv_state IS 'Shared state for rate limiting, idempotency and revocation across PgArachne instances.';
+
+
+-- =============================================================================
+-- Table: pgarachne.limits
+-- Description: Per-role rate limits and concurrency caps, enforced by the
+--              server when ROLE_LIMITS=true. Manage API tiers with plain
+--              SQL; the trigger notifies running instances so changes apply
+--              within a request or two. Zero disables the respective limit.
+-- =============================================================================
+CREATE TABLE IF NOT EXISTS pgarachne.limits (
+    role_name TEXT PRIMARY KEY,
+    requests_per_minute INTEGER NOT NULL DEFAULT 0,
+    max_concurrent INTEGER NOT NULL DEFAULT 0,
+    monthly_calls BIGINT NOT NULL DEFAULT 0
+);
+
+COMMENT ON TABLE pgarachne.limits IS 'Per-role API rate limits and concurrency caps, cached by PgArachne and refreshed on NOTIFY pgarachne_limits.';
+
+-- Trigger so edits take effect without waiting for the periodic reload
+CREATE OR REPLACE FUNCTION pgarachne.limits_changed()
+RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM pg_notify('pgarachne_limits', '');
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER limits_changed
+AFTER INSERT OR UPDATE OR DELETE ON pgarachne.limits
+FOR EACH STATEMENT
+EXECUTE FUNCTION pgarachne.limits_changed();
+
+
+-- =============================================================================
+-- Table: pgarachne.usage
+-- Description: Monthly usage accounting per role (calls, rows returned,
+--              execution time), flushed by the server every 30s when
+--              USAGE_ACCOUNTING=true and served via /api/<database>/_usage.
+--              The monthly_calls column of pgarachne.limits caps the calls
+--              counted here.
+-- =============================================================================
+CREATE TABLE IF NOT EXISTS pgarachne.usage (
+    month DATE NOT NULL,
+    role TEXT NOT NULL,
+    calls BIGINT NOT NULL DEFAULT 0,
+    rows_returned BIGINT NOT NULL DEFAULT 0,
+    exec_ms BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (month, role)
+);
+
+COMMENT ON TABLE pgarachne.usage IS 'Aggregated API usage per role and month, maintained by PgArachne for accounting and quota enforcement.';
+
+
+-- =============================================================================
+-- Table: pgarachne.webhook_subscriptions
+-- Description: Self-service webhook subscriptions managed over the REST API
+--              (/api/<database>/_webhooks), scoped per role.
+-- =============================================================================
+CREATE TABLE IF NOT EXISTS pgarachne.webhook_subscriptions (
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    role TEXT NOT NULL,
+    channel TEXT NOT NULL,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (role, channel, url)
+);
+
+COMMENT ON TABLE pgarachne.webhook_subscriptions IS 'Webhook subscriptions registered by API clients, delivered on matching NOTIFY channels.';
+
+
+-- =============================================================================
+-- Table: pgarachne.audit_log
+-- Description: Audit trail of API function calls, populated through
+--              pgarachne.audit() when AUDIT_LOG=db.
+-- =============================================================================
+CREATE TABLE IF NOT EXISTS pgarachne.audit_log (
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    time TIMESTAMPTZ NOT NULL,
+    request_id TEXT,
+    client_ip TEXT,
+    role TEXT NOT NULL,
+    function TEXT NOT NULL,
+    params JSONB,
+    status TEXT NOT NULL,
+    latency_ms BIGINT NOT NULL
+);
+
+COMMENT ON TABLE pgarachne.audit_log IS 'Who called which API function with which parameters, result status and latency.';
+
+-- =============================================================================
+-- Function: pgarachne.audit
+-- Description: Inserts one audit entry; called by the server for every
+--              function call when AUDIT_LOG=db. Parameters arrive already
+--              redacted according to AUDIT_REDACT_KEYS.
+-- =============================================================================
+CREATE OR REPLACE FUNCTION pgarachne.audit(params JSONB)
+RETURNS VOID
+LANGUAGE sql
+SECURITY DEFINER
+AS $$
+    INSERT INTO pgarachne.audit_log (time, request_id, client_ip, role, function, params, status, latency_ms)
+    VALUES (
+        (params->>'time')::timestamptz,
+        params->>'request_id',
+        params->>'client_ip',
+        params->>'role',
+        params->>'function',
+        params->'params',
+        params->>'status',
+        (params->>'latency_ms')::bigint
+    );
+$$;
+
+
+-- =============================================================================
+-- Table: pgarachne.reset_tokens
+-- Description: One-time password reset tokens (hashes only), issued through
+--              the /password/reset endpoints.
+-- =============================================================================
+CREATE TABLE IF NOT EXISTS pgarachne.reset_tokens (
+    token_hash TEXT PRIMARY KEY,
+    role TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    valid_to TIMESTAMPTZ NOT NULL
+);
+
+COMMENT ON TABLE pgarachne.reset_tokens IS 'One-time password reset tokens; only SHA-256 hashes are stored.';
+
+-- =============================================================================
+-- Function: pgarachne.create_reset_token
+-- Description: Stores a reset token hash for an existing role. Raises for
+--              unknown roles so the server can stay silent about them.
+-- =============================================================================
+CREATE OR REPLACE FUNCTION pgarachne.create_reset_token(
+    target_role TEXT,
+    input_token_hash TEXT,
+    validity INTERVAL DEFAULT '1 hour'
+)
+RETURNS VOID
+LANGUAGE plpgsql
+SECURITY DEFINER
+AS $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = target_role AND rolcanlogin) THEN
+        RAISE EXCEPTION 'unknown role';
+    END IF;
+    INSERT INTO pgarachne.reset_tokens (token_hash, role, valid_to)
+    VALUES (input_token_hash, target_role, NOW() + validity);
+END;
+$$;
+
+-- =============================================================================
+-- Function: pgarachne.consume_reset_token
+-- Description: Validates a reset token hash and, when valid, deletes it and
+--              applies the new password. Returns TRUE on success.
+-- =============================================================================
+CREATE OR REPLACE FUNCTION pgarachne.consume_reset_token(
+    input_token_hash TEXT,
+    new_password TEXT
+)
+RETURNS BOOLEAN
+LANGUAGE plpgsql
+SECURITY DEFINER
+AS $$
+DECLARE
+    target_role TEXT;
+BEGIN
+    DELETE FROM pgarachne.reset_tokens
+    WHERE token_hash = input_token_hash AND valid_to > NOW()
+    RETURNING role INTO target_role;
+
+    IF target_role IS NULL THEN
+        RETURN FALSE;
+    END IF;
+
+    EXECUTE format('ALTER ROLE %I PASSWORD %L', target_role, new_password);
+    RETURN TRUE;
+END;
+$$;